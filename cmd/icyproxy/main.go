@@ -10,12 +10,14 @@ import (
 	nethttp "net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/harper/radio-metadata-proxy/internal/application/config"
 	"github.com/harper/radio-metadata-proxy/internal/application/manager"
 	"github.com/harper/radio-metadata-proxy/internal/infrastructure/http"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/transcode"
 )
 
 func main() {
@@ -47,45 +49,58 @@ func run() error {
 		return fmt.Errorf("start stations: %w", err)
 	}
 
-	// Setup HTTP routes
-	mux := nethttp.NewServeMux()
-	mux.Handle("/stations", http.NewStationsHandler(mgr))
-	mux.HandleFunc("/healthz", http.HealthzHandler)
-
-	// Station-specific routes
-	streamHandler := http.NewStreamHandler(mgr)
-	metaHandler := http.NewMetaHandler(mgr)
-	coverHandler := http.NewCoverHandler(mgr)
-
-	mux.HandleFunc("/", func(w nethttp.ResponseWriter, r *nethttp.Request) {
-		if len(r.URL.Path) > 7 && r.URL.Path[len(r.URL.Path)-7:] == "/stream" {
-			streamHandler.ServeHTTP(w, r)
-			return
-		}
-		if len(r.URL.Path) > 5 && r.URL.Path[len(r.URL.Path)-5:] == "/meta" {
-			metaHandler.ServeHTTP(w, r)
-			return
-		}
-		if len(r.URL.Path) > 6 && r.URL.Path[len(r.URL.Path)-6:] == "/cover" {
-			coverHandler.ServeHTTP(w, r)
-			return
-		}
-		nethttp.NotFound(w, r)
-	})
+	handler, err := newHandler(cfg, mgr)
+	if err != nil {
+		return err
+	}
 
 	// Create HTTP server
+	// Streams need the server-wide write timeout off; every other route gets
+	// request_timeout_ms per request so slow clients can't pin connections
 	addr := fmt.Sprintf("%s:%d", cfg.Listen.Host, cfg.Listen.Port)
+	requestTimeout := time.Duration(mgr.Config().Server.RequestTimeoutMs) * time.Millisecond
 	srv := &nethttp.Server{
-		Addr:         addr,
-		Handler:      mux,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 0, // Streaming
-		IdleTimeout:  0, // Streaming
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: requestTimeout,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      0, // Streaming
+		IdleTimeout:       requestTimeout,
 		BaseContext: func(_ net.Listener) context.Context {
 			return context.Background()
 		},
 	}
 
+	// Reload the station list on SIGHUP
+	go func() {
+		sighup := make(chan os.Signal, 1)
+		signal.Notify(sighup, syscall.SIGHUP)
+		for range sighup {
+			next, err := config.Load(cfgPath)
+			if err == nil {
+				err = mgr.Reload(next)
+			}
+			if err != nil {
+				log.Printf("reload %s: %v", cfgPath, err)
+				continue
+			}
+			log.Printf("reloaded stations from %s", cfgPath)
+		}
+	}()
+
+	// Log a stats snapshot on SIGUSR1, for when the HTTP API isn't reachable
+	go func() {
+		sigusr1 := make(chan os.Signal, 1)
+		signal.Notify(sigusr1, syscall.SIGUSR1)
+		for range sigusr1 {
+			var buf strings.Builder
+			mgr.DumpStats(&buf)
+			for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+				log.Printf("stats: %s", line)
+			}
+		}
+	}()
+
 	// Graceful shutdown
 	shutdown := make(chan error, 1)
 	go func() {
@@ -95,15 +110,33 @@ func run() error {
 
 		log.Println("shutting down...")
 
+		// Refuse new listeners and fail /healthz, letting existing streams
+		// play until they leave or the drain timeout passes
+		if drain := time.Duration(cfg.Server.DrainTimeoutMs) * time.Millisecond; drain > 0 {
+			mgr.BeginDrain()
+			log.Printf("draining for up to %s", drain)
+			waitForDrain(mgr, drain)
+		}
+
+		// Tell stream handlers to finish so Shutdown doesn't wait out the timeout
+		mgr.BeginShutdown()
+
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
 		shutdown <- srv.Shutdown(ctx)
 	}()
 
-	// Start server
-	log.Printf("listening on http://%s (try /stations)", addr)
-	if err := srv.ListenAndServe(); err != nil && err != nethttp.ErrServerClosed {
+	// Start server; with TLS, Go negotiates HTTP/2 so JSON and metadata
+	// requests multiplex over one connection alongside the stream
+	if cfg.Listen.TLSCertFile != "" || cfg.Listen.TLSKeyFile != "" {
+		log.Printf("listening on https://%s with HTTP/2 (try %s/stations)", addr, mgr.Config().Server.BasePath)
+		err = srv.ListenAndServeTLS(cfg.Listen.TLSCertFile, cfg.Listen.TLSKeyFile)
+	} else {
+		log.Printf("listening on http://%s (try %s/stations)", addr, mgr.Config().Server.BasePath)
+		err = srv.ListenAndServe()
+	}
+	if err != nil && err != nethttp.ErrServerClosed {
 		return fmt.Errorf("http server: %w", err)
 	}
 
@@ -120,3 +153,85 @@ func run() error {
 	log.Println("shutdown complete")
 	return nil
 }
+
+// newHandler builds the HTTP routes for mgr's stations, wrapped in the
+// server-wide path cleaning, base path, request timeout and gzip handling
+func newHandler(cfg *config.Config, mgr *manager.Manager) (nethttp.Handler, error) {
+	mux := nethttp.NewServeMux()
+	cached := func(route string, h nethttp.Handler) nethttp.Handler {
+		return http.WithCacheControl(http.CacheControl(route, cfg.Server.CacheControl), h)
+	}
+
+	mux.Handle("/stations", cached("stations", http.NewStationsHandler(mgr)))
+	mux.Handle("/nowplaying", cached("nowplaying", http.NewNowPlayingHandler(mgr)))
+	mux.Handle("/healthz", http.NewHealthzHandler(mgr))
+	mux.Handle("/metrics", http.NewMetricsHandler(mgr))
+	mux.Handle("/listmounts", http.NewListMountsHandler(mgr))
+
+	// Admin routes
+	mux.Handle("/admin/providers", http.AdminAuth(cfg.Server.AdminToken, http.NewProvidersHandler(mgr)))
+	mux.Handle("/admin/config", http.AdminAuth(cfg.Server.AdminToken, http.NewConfigHandler(mgr)))
+	mux.Handle("/admin/sources", http.AdminAuth(cfg.Server.AdminToken, http.NewSourcesHandler(mgr)))
+	mux.Handle("/admin/stations/", http.AdminAuth(cfg.Server.AdminToken, http.NewAdminStationRouter(map[string]nethttp.Handler{
+		"reset-peak":       http.NewResetPeakHandler(mgr),
+		"record":           http.NewRecordHandler(mgr),
+		"refresh-metadata": http.NewRefreshMetadataHandler(mgr),
+	})))
+	mux.Handle("/{station}/debug/metadata", http.AdminAuth(cfg.Server.AdminToken, http.NewDebugMetadataHandler(mgr)))
+
+	// Station-specific routes
+	streamHandler := http.NewStreamHandler(mgr).
+		WithConnectionClose(!cfg.Server.DisableConnectionClose).
+		WithLowLatency(cfg.Server.LowLatency).
+		WithConnectSampling(cfg.Logging.SampleConnects)
+	metaHandler := http.NewMetaHandler(mgr)
+	coverHandler := http.NewCoverHandler(mgr)
+	if cfg.Server.CoverProxy {
+		coverHandler.WithProxy(cfg.Server.CoverMaxBytes, time.Duration(cfg.Server.CoverTimeoutMs)*time.Millisecond)
+	}
+	shoutcastHandler := http.NewShoutcastHandler(mgr)
+
+	if tc := mgr.Config().Transcode; tc.Backend == "ffmpeg" {
+		ffmpeg := transcode.NewFFmpeg(transcode.FFmpegConfig{
+			Path:        tc.FFmpegPath,
+			MaxRestarts: *tc.MaxRestarts,
+		})
+		if err := ffmpeg.Check(); err != nil {
+			return nil, fmt.Errorf("transcode: %w", err)
+		}
+		streamHandler.WithTranscoder(ffmpeg, tc.Bitrates)
+	}
+
+	stationRouter := http.NewStationRouter(map[string]nethttp.Handler{
+		"stream":    cached("stream", streamHandler),
+		"meta":      cached("meta", metaHandler),
+		"meta.txt":  cached("meta", metaHandler),
+		"cover":     cached("cover", coverHandler),
+		"7.html":    shoutcastHandler,
+		"admin.cgi": shoutcastHandler,
+		"clients":   http.AdminAuth(cfg.Server.AdminToken, http.NewClientsHandler(mgr)),
+		"levels":    cached("levels", http.NewLevelsHandler(mgr)),
+	}).WithDefaultStation(cfg.Server.DefaultStation)
+	if cfg.Server.Player {
+		stationRouter.WithIndex(http.NewPlayerHandler(mgr))
+	}
+	mux.Handle("/", stationRouter)
+
+	// Paths are cleaned before the base path is stripped: ServeMux would
+	// otherwise redirect a path like /radio/fip//stream to a cleaned path
+	// without the base, and the station router would never see it
+	requestTimeout := time.Duration(mgr.Config().Server.RequestTimeoutMs) * time.Millisecond
+	handler := http.WithBasePath(mgr.Config().Server.BasePath, http.WithRequestTimeout(requestTimeout, http.Gzip(mux)))
+	return http.WithCleanPath(handler), nil
+}
+
+// waitForDrain returns once no streams remain or timeout passes
+func waitForDrain(mgr *manager.Manager, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if active, _ := mgr.Connections(); active == 0 {
+			return
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
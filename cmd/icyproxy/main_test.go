@@ -0,0 +1,98 @@
+// ABOUTME: Tests for the server handler chain built by main
+// ABOUTME: Exercises routing through path cleaning, base path and ServeMux together
+package main
+
+import (
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/config"
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/http"
+)
+
+func newTestHandler(t *testing.T, basePath string) nethttp.Handler {
+	t.Helper()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{BasePath: basePath},
+		Stations: []config.StationConfig{
+			{
+				ID:     "fip",
+				ICY:    config.ICYConfig{Name: "FIP", MetaInt: 16384},
+				Source: config.SourceConfig{URL: "http://127.0.0.1:1/live"},
+			},
+		},
+	}
+	mgr, err := manager.NewFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := newHandler(cfg, mgr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestHandler_DuplicateSlashes(t *testing.T) {
+	tests := []struct {
+		base string
+		path string
+		want int
+	}{
+		{"", "/fip//meta", nethttp.StatusOK},
+		{"", "//FIP/meta/", nethttp.StatusOK},
+		{"", "/fip/./meta", nethttp.StatusOK},
+		{"/radio", "/radio/fip//meta", nethttp.StatusOK},
+		{"/radio", "/radio//fip/meta", nethttp.StatusOK},
+		{"/radio", "/radio//stations", nethttp.StatusOK},
+		{"/radio", "/radio/../fip/meta", nethttp.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		newTestHandler(t, tt.base).ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s under %q: expected %d, got %d (Location %q)", tt.path, tt.base, tt.want, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+}
+
+func TestHandler_BasePathRedirectKeepsPrefix(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestHandler(t, "/radio").ServeHTTP(rec, httptest.NewRequest("GET", "/radio//admin/stations", nil))
+
+	if rec.Code/100 != 3 {
+		t.Fatalf("expected ServeMux's subtree redirect, got %d", rec.Code)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/radio/admin/stations/" {
+		t.Errorf("expected the redirect to stay under the base path, got %q", loc)
+	}
+}
+
+func TestHandler_CacheControlDefaults(t *testing.T) {
+	h := newTestHandler(t, "")
+
+	// Every route wrapped with a cache policy must have a default, or the
+	// wrapper silently sends no Cache-Control at all
+	for path, route := range map[string]string{
+		"/stations":   "stations",
+		"/nowplaying": "nowplaying",
+		"/fip/meta":   "meta",
+		"/fip/cover":  "cover",
+		"/fip/levels": "levels",
+	} {
+		want := http.DefaultCacheControl[route]
+		if want == "" {
+			t.Errorf("route %q has no default Cache-Control", route)
+			continue
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if got := rec.Header().Get("Cache-Control"); got != want {
+			t.Errorf("%s: expected Cache-Control %q, got %q", path, want, got)
+		}
+	}
+}
@@ -3,74 +3,367 @@
 package config
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Listen   ListenConfig    `yaml:"listen"`
-	Stations []StationConfig `yaml:"stations"`
-	Logging  LoggingConfig   `yaml:"logging"`
+	Listen    ListenConfig    `yaml:"listen"`
+	Server    ServerConfig    `yaml:"server"`
+	Stations  []StationConfig `yaml:"stations"`
+	Logging   LoggingConfig   `yaml:"logging"`
+	Transcode TranscodeConfig `yaml:"transcode"`
+	Hooks     []HookConfig    `yaml:"hooks"`
+	Recording RecordingConfig `yaml:"recording"`
+	State     StateConfig     `yaml:"state"`
+}
+
+// StateConfig persists each station's last-known metadata so /meta isn't
+// blank after a restart
+type StateConfig struct {
+	Path           string `yaml:"path"`             // JSON state file (empty = disabled)
+	SaveIntervalMs int    `yaml:"save_interval_ms"` // default 30000; state is also saved on shutdown
 }
 
 type ListenConfig struct {
 	Host string `yaml:"host"`
 	Port int    `yaml:"port"`
+
+	// Serving TLS also enables HTTP/2, negotiated per connection via ALPN
+	TLSCertFile string `yaml:"tls_cert_file"`
+	TLSKeyFile  string `yaml:"tls_key_file"`
+}
+
+type ServerConfig struct {
+	MaxConnections int    `yaml:"max_connections"`  // global cap on concurrent streams (0 = unlimited)
+	AdminToken     string `yaml:"admin_token"`      // bearer token for /admin/ endpoints (empty = disabled)
+	BasePath       string `yaml:"base_path"`        // mount every route under this prefix, e.g. /radio
+	DefaultStation string `yaml:"default_station"`  // serve /stream, /meta, ... for this station without its ID
+	MetaTimeFormat string `yaml:"meta_time_format"` // updated_at in /meta and /nowplaying: rfc3339 (default), unix, unixmilli
+
+	CaseSensitiveIDs bool `yaml:"case_sensitive_ids"` // station IDs in URLs match case-insensitively unless set
+	Player           bool `yaml:"player"`             // serve a built-in HTML player at /{station}/
+
+	// CacheControl overrides per-route Cache-Control (stream, meta, cover,
+	// stations, nowplaying, levels); an empty value removes the header
+	CacheControl           map[string]string `yaml:"cache_control"`
+	DisableConnectionClose bool              `yaml:"disable_connection_close"` // stop sending Connection: close on HTTP/1.x streams
+
+	// LowLatency flushes each connect-burst slice as it is written rather
+	// than the whole burst at the end. Go already sets TCP_NODELAY on every
+	// connection, so this is the only buffering left to control.
+	LowLatency bool `yaml:"low_latency"`
+
+	// DrainTimeoutMs keeps existing streams playing this long after SIGTERM
+	// while /healthz fails and new streams get 503 (0 = end streams at once)
+	DrainTimeoutMs int `yaml:"drain_timeout_ms"`
+
+	// RequestTimeoutMs bounds reading the request and writing the response on
+	// every route except streams, and how long idle keep-alive connections are
+	// kept (default 10000)
+	RequestTimeoutMs int `yaml:"request_timeout_ms"`
+
+	// CoverProxy fetches /{station}/cover artwork server-side instead of
+	// redirecting, refusing bodies over CoverMaxBytes (default 5 MiB), fetches
+	// slower than CoverTimeoutMs (default 5000) and non-image responses
+	CoverProxy     bool  `yaml:"cover_proxy"`
+	CoverMaxBytes  int64 `yaml:"cover_max_bytes"`
+	CoverTimeoutMs int   `yaml:"cover_timeout_ms"`
+
+	// MetadataCoalesceMs lets stations whose metadata requests are identical
+	// (same URL, credentials and headers) share one response for this long,
+	// so a station network polling one now-playing API makes one request (0 = off)
+	MetadataCoalesceMs int `yaml:"metadata_coalesce_ms"`
+}
+
+// HookConfig fires a webhook and/or command on station source transitions
+type HookConfig struct {
+	Events    []string `yaml:"events"`     // source_up, source_down, metadata_stale; empty = all
+	Webhook   string   `yaml:"webhook"`    // POSTed a JSON {event, station, time} body
+	Command   []string `yaml:"command"`    // argv, run with ICYPROXY_EVENT and ICYPROXY_STATION set
+	TimeoutMs int      `yaml:"timeout_ms"` // default 10000
+}
+
+// RecordingConfig enables POST /admin/stations/{id}/record
+type RecordingConfig struct {
+	Dir           string `yaml:"dir"`            // recordings directory (empty = disabled)
+	RotateMinutes int    `yaml:"rotate_minutes"` // start a new file after this many minutes (default 60)
+	RotateMB      int    `yaml:"rotate_mb"`      // start a new file after this many MiB (0 = no limit)
 }
 
 type StationConfig struct {
 	ID        string          `yaml:"id"`
+	Enabled   *bool           `yaml:"enabled"` // false keeps the station configured but off air; default true
 	ICY       ICYConfig       `yaml:"icy"`
 	Source    SourceConfig    `yaml:"source"`
 	Metadata  MetadataConfig  `yaml:"metadata"`
 	Buffering BufferingConfig `yaml:"buffering"`
+	Levels    LevelsConfig    `yaml:"levels"`
+
+	// Variants are the same programme from other upstreams, usually other
+	// bitrates, served as /{station}/stream?variant=NAME. They show this
+	// station's metadata instead of polling their own.
+	Variants map[string]VariantConfig `yaml:"variants"`
+}
+
+// VariantConfig is one alternate upstream of a station
+type VariantConfig struct {
+	Source SourceConfig `yaml:"source"`
+	ICY    *ICYConfig   `yaml:"icy"` // replaces the station's icy settings; omitted keeps them, an empty name keeps the station's
+}
+
+// IsEnabled reports whether the station should run; omitted means enabled
+func (s StationConfig) IsEnabled() bool {
+	return s.Enabled == nil || *s.Enabled
+}
+
+// LevelsConfig enables the /{station}/levels RMS/peak estimate (MP3 only)
+type LevelsConfig struct {
+	Enabled  bool `yaml:"enabled"`
+	WindowMs int  `yaml:"window_ms"` // measurement window, default 250
 }
 
 type ICYConfig struct {
 	Name            string `yaml:"name"`
-	MetaInt         int    `yaml:"metaint"`
+	MetaInt         int    `yaml:"metaint"` // audio bytes between metadata blocks; 8192-65536 recommended, larger values are clamped
 	BitrateHintKbps int    `yaml:"bitrate_hint_kbps"`
+	Passthrough     bool   `yaml:"passthrough"`       // never inject ICY metadata (implied for Ogg/WebM content types)
+	DetectBitrate   bool   `yaml:"detect_bitrate"`    // measure icy-br from MP3/AAC frame headers, falling back to the hint
+	SendInitialMeta bool   `yaml:"send_initial_meta"` // send a metadata block before the first audio byte so titles show at once
+
+	// Fields are extra Name='value'; pairs sent after StreamTitle in every
+	// metadata block, e.g. StationName or a {artist} placeholder
+	Fields []ICYFieldConfig `yaml:"fields"`
+}
+
+// ICYFieldConfig is one extra ICY metadata field
+type ICYFieldConfig struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"` // may use {artist}, {title}, ... from the current metadata
 }
 
 type SourceConfig struct {
+	Type             string            `yaml:"type"` // "http" (default), "file", or "tone" (silent MP3 for tests)
 	URL              string            `yaml:"url"`
+	Mirrors          []MirrorConfig    `yaml:"mirrors"`             // equivalent upstreams chosen by weight on each connect, instead of url
+	Username         string            `yaml:"username"`            // Basic auth, overriding any user:pass@ in the URL
+	Password         string            `yaml:"password"`            // may use {env:NAME}
+	Path             string            `yaml:"path"`                // file or FIFO for type: file; regular files loop
+	ContentType      string            `yaml:"content_type"`        // audio MIME type, defaults to audio/mpeg
+	DetectContent    bool              `yaml:"detect_content_type"` // sniff MP3/AAC/Ogg/WebM from the first bytes of each connection, falling back to content_type
 	RequestHeaders   map[string]string `yaml:"request_headers"`
-	ConnectTimeoutMs int               `yaml:"connect_timeout_ms"`
-	ReadTimeoutMs    int               `yaml:"read_timeout_ms"`
+	ConnectTimeoutMs int               `yaml:"connect_timeout_ms"` // TCP and TLS setup with the upstream
+	ReadTimeoutMs    int               `yaml:"read_timeout_ms"`    // wait for the upstream's response headers
+	StallTimeoutMs   int               `yaml:"stall_timeout_ms"`   // reader restarts after this long without audio (0 = off)
+	Reconnect        ReconnectConfig   `yaml:"reconnect"`
+	Pace             bool              `yaml:"pace"` // throttle reads to icy.bitrate_hint_kbps for non-realtime sources
+	Token            TokenConfig       `yaml:"token"`
+}
+
+// TokenConfig fetches a rotating credential from an endpoint and sends it
+// in a header on every source connect; it is enabled by setting url
+type TokenConfig struct {
+	URL            string            `yaml:"url"`
+	RequestHeaders map[string]string `yaml:"request_headers"` // sent to the token endpoint; may use {env:NAME}
+	Field          string            `yaml:"field"`           // dotted JSON path of the token, empty for a plain-text body
+	RefreshMs      int               `yaml:"refresh_ms"`      // refetch interval, default 5 minutes; a 401 also refetches
+	Header         string            `yaml:"header"`          // default Authorization
+	Prefix         string            `yaml:"prefix"`          // e.g. "Bearer "
+}
+
+// MirrorConfig is one upstream in a weighted mirror set
+type MirrorConfig struct {
+	URL    string `yaml:"url"`
+	Weight *int   `yaml:"weight"` // relative share of connections, default 1; 0 disables the mirror
+}
+
+// EffectiveWeight returns the mirror's weight, 1 when unset
+func (m MirrorConfig) EffectiveWeight() int {
+	if m.Weight == nil {
+		return 1
+	}
+	return *m.Weight
+}
+
+type ReconnectConfig struct {
+	BackoffInitialMs  int `yaml:"backoff_initial_ms"`
+	BackoffMaxMs      int `yaml:"backoff_max_ms"`
+	BackoffRejectedMs int `yaml:"backoff_rejected_ms"` // delay after a 4xx other than 408/429, default 5 minutes
+
+	// Jitter randomly shortens each delay by up to this fraction so stations
+	// on a shared upstream don't reconnect in lockstep (0 = off, 0.5 = equal
+	// jitter, 1 = full jitter)
+	Jitter float64 `yaml:"jitter"`
 }
 
 type MetadataConfig struct {
-	URL    string      `yaml:"url"`
-	PollMs int         `yaml:"poll_ms"`
-	Build  BuildConfig `yaml:"build"`
+	Type            string            `yaml:"type"` // "http" (default), "synthetic" (fake titles for tests) or "ogg" (in-band Vorbis comments)
+	URL             string            `yaml:"url"`
+	Username        string            `yaml:"username"` // Basic auth, overriding any user:pass@ in the URL
+	Password        string            `yaml:"password"` // may use {env:NAME}
+	RequestHeaders  map[string]string `yaml:"request_headers"`
+	PollMs          int               `yaml:"poll_ms"`
+	Build           BuildConfig       `yaml:"build"`
+	Formats         map[string]string `yaml:"formats"`                // named alternates selectable via /meta?format=
+	DefaultTitle    string            `yaml:"default_title"`          // shown until the first fetch succeeds
+	UnhealthyTitle  string            `yaml:"when_unhealthy_title"`   // shown instead of the last title while the source is down
+	UnhealthyPollMs int               `yaml:"when_unhealthy_poll_ms"` // slower poll interval while the source is down (0 = keep poll_ms)
+	Providers       []ProviderConfig  `yaml:"providers"`              // extra providers filling fields the primary leaves empty
+	Schedule        ScheduleConfig    `yaml:"schedule"`               // fixed titles shown on a schedule, e.g. overnight
+	StaleAfterMs    int               `yaml:"stale_after_ms"`         // fire metadata_stale hooks when the title hasn't changed this long while on air (0 = off)
+	OnAirHours      OnAirHoursConfig  `yaml:"on_air_hours"`           // limits stale_after_ms to these hours (empty = always on air)
+}
+
+// OnAirHoursConfig lists the daily ranges a station is on air
+type OnAirHoursConfig struct {
+	Timezone string            `yaml:"timezone"` // IANA zone for the ranges, default UTC
+	Ranges   []TimeRangeConfig `yaml:"ranges"`
+}
+
+// TimeRangeConfig runs from Start to End ("HH:MM"), wrapping past midnight
+// like a scheduled title. Days (mon..sun) restrict the days it starts on.
+type TimeRangeConfig struct {
+	Start string   `yaml:"start"`
+	End   string   `yaml:"end"`
+	Days  []string `yaml:"days"`
+}
+
+// ScheduleConfig overrides the feed's title during scheduled time ranges
+type ScheduleConfig struct {
+	Timezone string                 `yaml:"timezone"` // IANA zone for the ranges, default UTC
+	Titles   []ScheduledTitleConfig `yaml:"titles"`
+}
+
+// ScheduledTitleConfig shows Title from Start to End ("HH:MM"); ranges may
+// wrap past midnight. Days (mon..sun) restrict the days a range starts on.
+type ScheduledTitleConfig struct {
+	Title string   `yaml:"title"`
+	Start string   `yaml:"start"`
+	End   string   `yaml:"end"`
+	Days  []string `yaml:"days"`
+}
+
+// ProviderConfig is an additional metadata endpoint with its own poll interval
+type ProviderConfig struct {
+	Name           string            `yaml:"name"`
+	URL            string            `yaml:"url"`
+	Username       string            `yaml:"username"`
+	Password       string            `yaml:"password"`
+	RequestHeaders map[string]string `yaml:"request_headers"`
+	PollMs         int               `yaml:"poll_ms"` // defaults to the station's metadata.poll_ms
+	Build          BuildConfig       `yaml:"build"`
 }
 
 type BuildConfig struct {
+	Mode                string   `yaml:"mode"` // "template" (default) or "passthrough"
 	Format              string   `yaml:"format"`
+	PassthroughField    string   `yaml:"passthrough_field"`    // JSON path of a ready-made StreamTitle='...'; value
+	ValidatePassthrough bool     `yaml:"validate_passthrough"` // reject passthrough values that aren't ICY strings
 	StripSingleQuotes   bool     `yaml:"strip_single_quotes"`
 	Encoding            string   `yaml:"encoding"`
 	NormalizeWhitespace bool     `yaml:"normalize_whitespace"`
 	FallbackKeyOrder    []string `yaml:"fallback_key_order"`
+	DedupeSegments      bool     `yaml:"dedupe_segments"` // collapse "A - B - A - B" into "A - B"
+	MaxTitleBytes       int      `yaml:"max_title_bytes"`
+
+	Defaults            map[string]string `yaml:"defaults"`              // placeholder fallbacks, e.g. {album: "Unknown Album"}
+	DropEmptySeparators bool              `yaml:"drop_empty_separators"` // "Artist - " becomes "Artist" when a field or value leaves a gap
+	CleanSeparator      string            `yaml:"clean_separator"`       // e.g. " - ": also collapse repeated/dangling copies in the finished title
+	Split               SplitConfig       `yaml:"split"`                 // split a combined "Artist - Title" field before templating
+	TitleOverrides      map[string]string `yaml:"title_overrides"`       // replace sentinel titles, e.g. {"": "Station X", adbreak: "Commercial break"}
+
+	// Transforms is an ordered pipeline applied to the title, e.g.
+	// [trim, strip_html, {replace: {from: "feat.", to: "ft."}}]
+	Transforms []TransformConfig `yaml:"transforms"`
+}
+
+// SplitConfig splits one combined upstream field into two placeholders: the
+// text before the first separator goes to Into[0], the rest to Into[1]
+type SplitConfig struct {
+	Field     string   `yaml:"field"`     // JSON path of the combined value
+	Separator string   `yaml:"separator"` // default " - "
+	Into      []string `yaml:"into"`      // default [artist, title]
+}
+
+// TransformConfig names a metadata transform and its arguments. In YAML it
+// is either a bare name or a single-key map of name to arguments.
+type TransformConfig struct {
+	Name string
+	Args map[string]string
+}
+
+func (t *TransformConfig) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		t.Name = value.Value
+		return nil
+	case yaml.MappingNode:
+		if len(value.Content) != 2 {
+			return fmt.Errorf("line %d: transform must have exactly one name", value.Line)
+		}
+		t.Name = value.Content[0].Value
+		return value.Content[1].Decode(&t.Args)
+	default:
+		return fmt.Errorf("line %d: transform must be a name or a map", value.Line)
+	}
+}
+
+func (t TransformConfig) MarshalYAML() (interface{}, error) {
+	if len(t.Args) == 0 {
+		return t.Name, nil
+	}
+	return map[string]map[string]string{t.Name: t.Args}, nil
 }
 
 type BufferingConfig struct {
-	RingBytes             int `yaml:"ring_bytes"`
-	ClientPendingMaxBytes int `yaml:"client_pending_max_bytes"`
+	RingBytes             int  `yaml:"ring_bytes"`
+	ClientPendingMaxBytes int  `yaml:"client_pending_max_bytes"`
+	BurstOnConnectBytes   int  `yaml:"burst_on_connect_bytes"` // recent audio sent to new clients first (0 = off, max ring_bytes)
+	ClearOnReconnect      bool `yaml:"clear_on_reconnect"`     // drop buffered audio when the source reconnects, so bursts never splice old and new streams
+	FanoutWorkers         int  `yaml:"fanout_workers"`         // goroutines sharing delivery to thousands of clients (default 1)
+}
+
+type TranscodeConfig struct {
+	Backend     string `yaml:"backend"` // "" (disabled) or "ffmpeg"
+	FFmpegPath  string `yaml:"ffmpeg_path"`
+	Bitrates    []int  `yaml:"bitrates"`     // allowed ?bitrate= targets in kbps
+	MaxRestarts *int   `yaml:"max_restarts"` // per-client ffmpeg restarts after a crash; default 3, 0 = none
 }
 
 type LoggingConfig struct {
-	Level string `yaml:"level"`
-	JSON  bool   `yaml:"json"`
+	Level          string `yaml:"level"`
+	JSON           bool   `yaml:"json"`
+	SampleConnects int    `yaml:"sample_connects"` // log 1 in N client connects/disconnects (0 or 1 = all)
 }
 
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Load parses a YAML config file, decompressing it first when it is gzipped
+// (detected by its magic bytes or a .gz extension). {env:NAME} references
+// are left in place and expanded where each value is used.
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("read config: %w", err)
 	}
 
+	if bytes.HasPrefix(data, gzipMagic) || strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decompress config: %w", err)
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			return nil, fmt.Errorf("decompress config: %w", err)
+		}
+	}
+
 	var cfg Config
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("parse yaml: %w", err)
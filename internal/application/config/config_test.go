@@ -3,6 +3,8 @@
 package config
 
 import (
+	"bytes"
+	"compress/gzip"
 	"os"
 	"path/filepath"
 	"testing"
@@ -62,3 +64,81 @@ stations:
 		t.Errorf("expected ID test_station, got %s", st.ID)
 	}
 }
+
+func TestLoad_Gzipped(t *testing.T) {
+	yamlContent := `
+stations:
+  - id: gz_station
+    source:
+      url: "http://example.com/stream.mp3"
+      password: "{env:SOURCE_PASSWORD}"
+`
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(yamlContent))
+	zw.Close()
+
+	// Detected by magic bytes whatever the file is called
+	for _, name := range []string{"config.yaml.gz", "config.yaml"} {
+		cfgPath := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(cfgPath, buf.Bytes(), 0644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+
+		cfg, err := Load(cfgPath)
+		if err != nil {
+			t.Fatalf("%s: Load failed: %v", name, err)
+		}
+		if len(cfg.Stations) != 1 || cfg.Stations[0].ID != "gz_station" {
+			t.Fatalf("%s: expected gz_station, got %+v", name, cfg.Stations)
+		}
+		if pw := cfg.Stations[0].Source.Password; pw != "{env:SOURCE_PASSWORD}" {
+			t.Errorf("%s: expected env reference kept for expansion, got %q", name, pw)
+		}
+	}
+
+	// A .gz name that isn't gzip is an error rather than garbage YAML
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml.gz")
+	os.WriteFile(cfgPath, []byte(yamlContent), 0644)
+	if _, err := Load(cfgPath); err == nil {
+		t.Error("expected error for a .gz file that isn't gzipped")
+	}
+}
+
+func TestLoad_Transforms(t *testing.T) {
+	yamlContent := `
+stations:
+  - id: test_station
+    metadata:
+      build:
+        transforms:
+          - trim
+          - strip_html
+          - replace: { from: "feat.", to: "ft." }
+`
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := Load(cfgPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	transforms := cfg.Stations[0].Metadata.Build.Transforms
+	if len(transforms) != 3 {
+		t.Fatalf("expected 3 transforms, got %d", len(transforms))
+	}
+
+	if transforms[0].Name != "trim" || transforms[1].Name != "strip_html" {
+		t.Errorf("unexpected transform names %+v", transforms)
+	}
+
+	replace := transforms[2]
+	if replace.Name != "replace" || replace.Args["from"] != "feat." || replace.Args["to"] != "ft." {
+		t.Errorf("unexpected replace transform %+v", replace)
+	}
+}
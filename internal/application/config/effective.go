@@ -0,0 +1,249 @@
+// ABOUTME: Effective configuration with implicit defaults and secret redaction
+// ABOUTME: Backs the /admin/config view of what is actually running
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"strings"
+
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/redact"
+)
+
+// redacted replaces secret values in Redacted output
+const redacted = redact.Mask
+
+// DefaultPollMs is the metadata poll interval when poll_ms is omitted
+const DefaultPollMs = 5000
+
+// DefaultRingBytes is the ring buffer size when ring_bytes is omitted
+const DefaultRingBytes = 262144
+
+// MinRingBytes is the smallest accepted ring_bytes: two 8 KiB source reads,
+// so a single read never overwrites the whole buffer
+const MinRingBytes = 16384
+
+// MaxMetaInt is the largest icy.metaint honored; larger values are clamped.
+// 8192 to 65536 is the range players commonly expect.
+const MaxMetaInt = 65536
+
+// DefaultRequestTimeoutMs is the non-stream request deadline when
+// request_timeout_ms is omitted
+const DefaultRequestTimeoutMs = 10000
+
+// DefaultTokenRefreshMs is how long a fetched source token is reused
+const DefaultTokenRefreshMs = 300000
+
+// Metadata timestamp formats selectable with server.meta_time_format
+const (
+	TimeFormatRFC3339   = "rfc3339"
+	TimeFormatUnix      = "unix"
+	TimeFormatUnixMilli = "unixmilli"
+)
+
+// WithDefaults returns a copy of c with the implicit defaults filled in
+func (c *Config) WithDefaults() *Config {
+	out := c.clone()
+
+	if out.Server.MetaTimeFormat == "" {
+		out.Server.MetaTimeFormat = TimeFormatRFC3339
+	}
+
+	if out.Server.RequestTimeoutMs <= 0 {
+		out.Server.RequestTimeoutMs = DefaultRequestTimeoutMs
+	}
+
+	// Normalize the base path to "/prefix" without a trailing slash
+	if base := strings.Trim(out.Server.BasePath, "/"); base != "" {
+		out.Server.BasePath = "/" + base
+	} else {
+		out.Server.BasePath = ""
+	}
+
+	for i := range out.Stations {
+		st := &out.Stations[i]
+
+		sourceDefaults(&st.Source)
+		for name, v := range st.Variants {
+			sourceDefaults(&v.Source)
+			st.Variants[name] = v
+		}
+
+		if st.Buffering.RingBytes == 0 {
+			st.Buffering.RingBytes = DefaultRingBytes
+		}
+
+		if st.Levels.Enabled && st.Levels.WindowMs <= 0 {
+			st.Levels.WindowMs = 250
+		}
+
+		if st.Metadata.PollMs <= 0 {
+			st.Metadata.PollMs = DefaultPollMs
+		}
+
+		for j := range st.Metadata.Providers {
+			p := &st.Metadata.Providers[j]
+			if p.Name == "" {
+				p.Name = fmt.Sprintf("provider-%d", j+1)
+			}
+			if p.PollMs <= 0 {
+				p.PollMs = st.Metadata.PollMs
+			}
+		}
+	}
+
+	if out.Recording.Dir != "" && out.Recording.RotateMinutes == 0 {
+		out.Recording.RotateMinutes = 60
+	}
+
+	if out.State.Path != "" && out.State.SaveIntervalMs <= 0 {
+		out.State.SaveIntervalMs = 30000
+	}
+
+	if out.Transcode.Backend != "" && out.Transcode.MaxRestarts == nil {
+		restarts := 3
+		out.Transcode.MaxRestarts = &restarts
+	}
+
+	return out
+}
+
+// sourceDefaults fills in the implicit source settings
+func sourceDefaults(src *SourceConfig) {
+	if src.Type == "" {
+		src.Type = "http"
+	}
+	if src.ContentType == "" {
+		src.ContentType = "audio/mpeg"
+	}
+	if src.Reconnect.BackoffInitialMs <= 0 {
+		src.Reconnect.BackoffInitialMs = 1000
+	}
+	if src.Reconnect.BackoffMaxMs <= 0 {
+		src.Reconnect.BackoffMaxMs = 30000
+	}
+	if src.Reconnect.BackoffMaxMs < src.Reconnect.BackoffInitialMs {
+		src.Reconnect.BackoffMaxMs = src.Reconnect.BackoffInitialMs
+	}
+	if src.Reconnect.BackoffRejectedMs <= 0 {
+		src.Reconnect.BackoffRejectedMs = 300000
+	}
+
+	if src.Token.URL != "" && src.Token.RefreshMs <= 0 {
+		src.Token.RefreshMs = DefaultTokenRefreshMs
+	}
+}
+
+// Redacted returns a copy of c that is safe to expose: the admin token,
+// passwords, URL credentials and query values, and every request header
+// value are masked
+func (c *Config) Redacted() *Config {
+	out := c.clone()
+
+	if out.Server.AdminToken != "" {
+		out.Server.AdminToken = redacted
+	}
+
+	for i := range out.Stations {
+		st := &out.Stations[i]
+		redactSource(&st.Source)
+		for name, v := range st.Variants {
+			redactSource(&v.Source)
+			st.Variants[name] = v
+		}
+
+		st.Metadata.RequestHeaders = redactHeaders(st.Metadata.RequestHeaders)
+		st.Metadata.URL = redact.URL(st.Metadata.URL)
+		st.Metadata.Password = redactSecret(st.Metadata.Password)
+		for j := range st.Metadata.Providers {
+			p := &st.Metadata.Providers[j]
+			p.RequestHeaders = redactHeaders(p.RequestHeaders)
+			p.URL = redact.URL(p.URL)
+			p.Password = redactSecret(p.Password)
+		}
+	}
+
+	// Webhook paths and queries, and command arguments, routinely carry
+	// tokens; only the host and program are kept
+	if out.Hooks != nil {
+		out.Hooks = append([]HookConfig(nil), out.Hooks...)
+		for i := range out.Hooks {
+			h := &out.Hooks[i]
+			h.Webhook = redactWebhook(h.Webhook)
+			if len(h.Command) > 1 {
+				h.Command = append([]string{h.Command[0]}, redacted)
+			}
+		}
+	}
+
+	return out
+}
+
+// redactWebhook keeps only a webhook's scheme and host
+func redactWebhook(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+	// Host excludes any user:pass@ userinfo
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host}).String() + "/" + redacted
+}
+
+// redactSource masks the credentials a source config may carry
+func redactSource(src *SourceConfig) {
+	src.RequestHeaders = redactHeaders(src.RequestHeaders)
+	src.URL = redact.URL(src.URL)
+	src.Password = redactSecret(src.Password)
+	src.Token.URL = redact.URL(src.Token.URL)
+	src.Token.RequestHeaders = redactHeaders(src.Token.RequestHeaders)
+	if src.Mirrors != nil {
+		src.Mirrors = append([]MirrorConfig(nil), src.Mirrors...)
+		for j := range src.Mirrors {
+			src.Mirrors[j].URL = redact.URL(src.Mirrors[j].URL)
+		}
+	}
+}
+
+func redactSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func redactHeaders(h map[string]string) map[string]string {
+	if h == nil {
+		return nil
+	}
+
+	out := make(map[string]string, len(h))
+	for k := range h {
+		out[k] = redacted
+	}
+	return out
+}
+
+// clone copies c deeply enough that WithDefaults and Redacted never modify
+// the original's stations or providers
+func (c *Config) clone() *Config {
+	out := *c
+
+	out.Stations = make([]StationConfig, len(c.Stations))
+	copy(out.Stations, c.Stations)
+
+	for i := range out.Stations {
+		providers := out.Stations[i].Metadata.Providers
+		if providers != nil {
+			out.Stations[i].Metadata.Providers = append([]ProviderConfig(nil), providers...)
+		}
+		if variants := out.Stations[i].Variants; variants != nil {
+			out.Stations[i].Variants = maps.Clone(variants)
+		}
+	}
+
+	return &out
+}
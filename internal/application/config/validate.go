@@ -0,0 +1,116 @@
+// ABOUTME: Validation of settings that can be checked without building anything
+// ABOUTME: Catches bad server and per-station values before the manager starts work
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validate reports the first server or station setting that can't work.
+// It expects the defaults from WithDefaults to be filled in. Checks that need
+// the running stations, such as duplicate IDs, are left to the manager.
+func (c *Config) Validate() error {
+	if (c.Listen.TLSCertFile == "") != (c.Listen.TLSKeyFile == "") {
+		return fmt.Errorf("listen.tls_cert_file and listen.tls_key_file must be set together")
+	}
+
+	switch c.Server.MetaTimeFormat {
+	case TimeFormatRFC3339, TimeFormatUnix, TimeFormatUnixMilli:
+	default:
+		return fmt.Errorf("unknown meta_time_format %q", c.Server.MetaTimeFormat)
+	}
+
+	if c.Server.DrainTimeoutMs < 0 {
+		return fmt.Errorf("drain_timeout_ms must not be negative")
+	}
+
+	if c.Server.MetadataCoalesceMs < 0 {
+		return fmt.Errorf("metadata_coalesce_ms must not be negative")
+	}
+
+	return c.ValidateStations()
+}
+
+// ValidateStations checks every enabled station, as a reload does when the
+// server settings stay as they were
+func (c *Config) ValidateStations() error {
+	for _, st := range c.Stations {
+		if !st.IsEnabled() {
+			continue
+		}
+		if err := st.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the settings of one station, including its variants
+func (s StationConfig) Validate() error {
+	if s.Buffering.RingBytes < MinRingBytes {
+		return fmt.Errorf("station %s: ring_bytes %d is below the minimum of %d", s.ID, s.Buffering.RingBytes, MinRingBytes)
+	}
+
+	if burst := s.Buffering.BurstOnConnectBytes; burst < 0 || burst > s.Buffering.RingBytes {
+		return fmt.Errorf("station %s: burst_on_connect_bytes %d must be between 0 and ring_bytes", s.ID, burst)
+	}
+
+	if s.Buffering.FanoutWorkers < 0 {
+		return fmt.Errorf("station %s: fanout_workers must not be negative", s.ID)
+	}
+
+	if err := validateSource(s.ID, s.Source, s.ICY); err != nil {
+		return err
+	}
+
+	if s.Metadata.StaleAfterMs < 0 {
+		return fmt.Errorf("station %s: stale_after_ms must not be negative", s.ID)
+	}
+
+	for name, v := range s.Variants {
+		if name == "" || strings.ContainsAny(name, "/?#&= ") {
+			return fmt.Errorf("station %s: invalid variant name %q", s.ID, name)
+		}
+		icy := s.ICY
+		if v.ICY != nil {
+			icy = *v.ICY
+		}
+		if err := validateSource(s.ID+"/"+name, v.Source, icy); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateSource checks the source and ICY settings a station or variant
+// owns
+func validateSource(id string, src SourceConfig, icy ICYConfig) error {
+	if j := src.Reconnect.Jitter; j < 0 || j > 1 {
+		return fmt.Errorf("station %s: reconnect jitter %v must be between 0 and 1", id, j)
+	}
+
+	if src.StallTimeoutMs < 0 {
+		return fmt.Errorf("station %s: stall_timeout_ms must not be negative", id)
+	}
+
+	if icy.MetaInt < 0 {
+		return fmt.Errorf("station %s: metaint must not be negative", id)
+	}
+
+	if len(src.Mirrors) > 0 {
+		total := 0
+		for _, m := range src.Mirrors {
+			if m.EffectiveWeight() < 0 {
+				return fmt.Errorf("station %s: source mirror %s: weight must not be negative", id, m.URL)
+			}
+			total += m.EffectiveWeight()
+		}
+		if total == 0 {
+			return fmt.Errorf("station %s: every source mirror has weight 0", id)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+// ABOUTME: Tests for config validation
+// ABOUTME: Verifies server and per-station checks, variants and disabled stations
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	valid := func() *Config {
+		return (&Config{Stations: []StationConfig{{ID: "fip", Source: SourceConfig{URL: "http://example.com/live"}}}}).WithDefaults()
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("valid config rejected: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		spoil func(c *Config)
+		want  string
+	}{
+		{"half a TLS pair", func(c *Config) { c.Listen.TLSCertFile = "cert.pem" }, "must be set together"},
+		{"time format", func(c *Config) { c.Server.MetaTimeFormat = "iso" }, "meta_time_format"},
+		{"drain timeout", func(c *Config) { c.Server.DrainTimeoutMs = -1 }, "drain_timeout_ms"},
+		{"coalesce", func(c *Config) { c.Server.MetadataCoalesceMs = -1 }, "metadata_coalesce_ms"},
+		{"ring", func(c *Config) { c.Stations[0].Buffering.RingBytes = 1024 }, "ring_bytes"},
+		{"burst", func(c *Config) { c.Stations[0].Buffering.BurstOnConnectBytes = 1 << 30 }, "burst_on_connect_bytes"},
+		{"jitter", func(c *Config) { c.Stations[0].Source.Reconnect.Jitter = 2 }, "jitter"},
+		{"metaint", func(c *Config) { c.Stations[0].ICY.MetaInt = -1 }, "metaint"},
+		{"variant name", func(c *Config) { c.Stations[0].Variants = map[string]VariantConfig{"a/b": {}} }, "variant name"},
+		{"variant metaint", func(c *Config) {
+			c.Stations[0].Variants = map[string]VariantConfig{"low": {ICY: &ICYConfig{MetaInt: -1}}}
+		}, "station fip/low: metaint"},
+	}
+	for _, tt := range tests {
+		c := valid()
+		tt.spoil(c)
+		if err := c.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error about %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	// Disabled stations may carry settings that don't work yet
+	c := valid()
+	off := false
+	c.Stations[0].Enabled = &off
+	c.Stations[0].Buffering.RingBytes = 1
+	if err := c.Validate(); err != nil {
+		t.Errorf("disabled station validated: %v", err)
+	}
+}
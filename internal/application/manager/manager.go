@@ -4,82 +4,730 @@ package manager
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"maps"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/harper/radio-metadata-proxy/internal/application/config"
+	"github.com/harper/radio-metadata-proxy/internal/domain"
 	"github.com/harper/radio-metadata-proxy/internal/domain/station"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/audio"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/hooks"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/icy"
 	"github.com/harper/radio-metadata-proxy/internal/infrastructure/metadata"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/record"
 	"github.com/harper/radio-metadata-proxy/internal/infrastructure/ring"
 	"github.com/harper/radio-metadata-proxy/internal/infrastructure/source"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/state"
 )
 
 type Manager struct {
-	stations map[string]*station.Station
-	mu       sync.RWMutex
-	ctx      context.Context
-	cancel   context.CancelFunc
-	wg       sync.WaitGroup
+	stations  map[string]*station.Station
+	disabled  map[string]bool // keys of configured stations with enabled: false
+	cfg       *config.Config  // effective config with defaults applied
+	mu        sync.RWMutex
+	reloadMu  sync.Mutex      // serializes Start, Reload and Shutdown
+	started   bool            // guarded by reloadMu
+	ctx       context.Context // cancelled once shutdown has finished draining
+	cancel    context.CancelFunc
+	closing   chan struct{} // closed when shutdown begins
+	closeOnce sync.Once
+	wg        sync.WaitGroup // the state saver
+	hooksDone chan struct{}  // closed when the hook dispatcher returns; nil before Start
+	hooks     *hooks.Dispatcher
+	recorder  *record.Manager
+	coalescer *metadata.Coalescer    // nil unless server.metadata_coalesce_ms is set
+	state     *state.Store           // nil unless state.path is set
+	restored  map[string]state.Entry // entries loaded at startup, by station ID
+
+	maxConns      int
+	activeConns   atomic.Int64
+	draining      atomic.Bool
+	caseSensitive bool
 }
 
 func NewFromConfig(cfg *config.Config) (*Manager, error) {
-	ctx, cancel := context.WithCancel(context.Background())
+	cfg = cfg.WithDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 
 	mgr := &Manager{
 		stations: make(map[string]*station.Station),
-		ctx:      ctx,
-		cancel:   cancel,
+		disabled: make(map[string]bool),
+		cfg:      cfg,
+		maxConns: cfg.Server.MaxConnections,
+		closing:  make(chan struct{}),
+
+		caseSensitive: cfg.Server.CaseSensitiveIDs,
+	}
+
+	if ms := cfg.Server.MetadataCoalesceMs; ms > 0 {
+		mgr.coalescer = metadata.NewCoalescer(time.Duration(ms) * time.Millisecond)
 	}
 
+	hookList, err := newHooks(cfg.Hooks)
+	if err != nil {
+		return nil, err
+	}
+	mgr.hooks = hooks.NewDispatcher(hookList)
+	mgr.recorder = record.NewManager(record.Config{
+		Dir:         cfg.Recording.Dir,
+		RotateEvery: time.Duration(cfg.Recording.RotateMinutes) * time.Minute,
+		RotateBytes: int64(cfg.Recording.RotateMB) << 20,
+	})
+
 	for _, stCfg := range cfg.Stations {
-		// Create dependencies
-		srcCfg := source.HTTPConfig{
+		k := mgr.key(stCfg.ID)
+		if _, exists := mgr.stations[k]; exists || mgr.disabled[k] {
+			return nil, fmt.Errorf("duplicate station id %q", stCfg.ID)
+		}
+		if !stCfg.IsEnabled() {
+			mgr.disabled[k] = true
+			continue
+		}
+
+		st, err := mgr.newStation(stCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		mgr.stations[k] = st
+	}
+
+	if id := cfg.Server.DefaultStation; id != "" && mgr.stations[mgr.key(id)] == nil && !mgr.disabled[mgr.key(id)] {
+		return nil, fmt.Errorf("default station %q is not configured", id)
+	}
+
+	// Nothing above starts work, so the context only exists once the
+	// config is known to be good
+	mgr.ctx, mgr.cancel = context.WithCancel(context.Background())
+
+	if cfg.State.Path != "" {
+		mgr.restoreState(state.New(cfg.State.Path))
+	}
+
+	return mgr, nil
+}
+
+// restoreState seeds stations with the metadata saved before the last
+// shutdown. A missing or unreadable file just means starting blank.
+func (m *Manager) restoreState(store *state.Store) {
+	m.state = store
+
+	entries, err := store.Load()
+	if err != nil {
+		log.Printf("state: %v; starting without saved metadata", err)
+		return
+	}
+	m.restored = entries
+	restoreStations(slices.Collect(maps.Values(m.stations)), entries)
+}
+
+// restoreStations seeds each station that has a saved entry
+func restoreStations(stations []*station.Station, entries map[string]state.Entry) {
+	for _, st := range stations {
+		if e, ok := entries[st.ID()]; ok {
+			st.Restore(e.Metadata, e.Fields)
+		}
+	}
+}
+
+// saveState writes every station's last-known metadata. Stations that
+// haven't fetched yet keep what was restored for them, so a quick restart
+// cycle doesn't lose it.
+func (m *Manager) saveState() {
+	m.mu.RLock()
+	restored := m.restored
+	m.mu.RUnlock()
+
+	entries := make(map[string]state.Entry)
+	for _, st := range m.List() {
+		meta, fields, ok := st.LastKnownMetadata()
+		if !ok {
+			if e, restored := restored[st.ID()]; restored {
+				entries[st.ID()] = e
+			}
+			continue
+		}
+		entries[st.ID()] = state.Entry{Metadata: meta, Fields: fields, UpdatedAt: *st.LastMetadataUpdate()}
+	}
+
+	if err := m.state.Save(entries); err != nil {
+		log.Printf("state: save: %v", err)
+	}
+}
+
+// runStateSaver saves state every save_interval_ms until shutdown
+func (m *Manager) runStateSaver(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.closing:
+			return
+		case <-ticker.C:
+			m.saveState()
+		}
+	}
+}
+
+// newStation builds one station, unstarted, from a config that passed
+// config.StationConfig.Validate. Checks that need the infrastructure, such
+// as parsing transforms or codec support, happen here.
+func (m *Manager) newStation(stCfg config.StationConfig) (*station.Station, error) {
+	metaInt := stCfg.ICY.MetaInt
+	if metaInt > config.MaxMetaInt {
+		// Players size their metadata buffers from metaint, and huge values
+		// hold back titles for minutes of audio
+		log.Printf("station %s: metaint %d is above %d, clamping", stCfg.ID, metaInt, config.MaxMetaInt)
+		metaInt = config.MaxMetaInt
+	}
+
+	icyFields, err := newICYFields(stCfg.ICY.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("station %s: %w", stCfg.ID, err)
+	}
+
+	// Create dependencies
+	src, err := newStreamSource(stCfg)
+	if err != nil {
+		return nil, fmt.Errorf("station %s: %w", stCfg.ID, err)
+	}
+
+	// Stations without a metadata URL keep their default title
+	var metaProv domain.MetadataProvider
+	var oggTags *audio.OggTags
+	switch stCfg.Metadata.Type {
+	case "", "http":
+		if stCfg.Metadata.URL != "" {
+			var metaURL string
+			if metaURL, err = resolveMetadataURL(stCfg.Metadata.URL, stCfg.Source.URL); err != nil {
+				break
+			}
+			metaProv, err = m.newMetadataProvider(stCfg.ID, metaURL, stCfg.Metadata.Username, stCfg.Metadata.Password, stCfg.Metadata.RequestHeaders, stCfg.Metadata.PollMs, stCfg.Metadata.Build)
+		}
+	case "synthetic":
+		var build metadata.BuildConfig
+		if build, err = newBuildConfig(stCfg.Metadata.Build); err == nil {
+			metaProv = metadata.NewSynthetic(build)
+		}
+	case "ogg":
+		// Tags come from the station's own audio, fed in by the source reader
+		if !oggTypes[stCfg.Source.ContentType] {
+			err = fmt.Errorf("metadata type ogg requires an Ogg source, not %s", stCfg.Source.ContentType)
+			break
+		}
+		var build metadata.BuildConfig
+		if build, err = newBuildConfig(stCfg.Metadata.Build); err == nil {
+			oggTags = audio.NewOggTags()
+			metaProv = metadata.NewInStream(oggTags, build)
+		}
+	default:
+		err = fmt.Errorf("unknown metadata type %q", stCfg.Metadata.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("station %s: %w", stCfg.ID, err)
+	}
+
+	providers := make([]station.Provider, 0, len(stCfg.Metadata.Providers))
+	for _, pCfg := range stCfg.Metadata.Providers {
+		provURL, err := resolveMetadataURL(pCfg.URL, stCfg.Source.URL)
+		if err != nil {
+			return nil, fmt.Errorf("station %s provider %s: %w", stCfg.ID, pCfg.Name, err)
+		}
+		prov, err := m.newMetadataProvider(stCfg.ID, provURL, pCfg.Username, pCfg.Password, pCfg.RequestHeaders, pCfg.PollMs, pCfg.Build)
+		if err != nil {
+			return nil, fmt.Errorf("station %s provider %s: %w", stCfg.ID, pCfg.Name, err)
+		}
+
+		providers = append(providers, station.Provider{
+			Name:         pCfg.Name,
+			Source:       prov,
+			PollInterval: time.Duration(pCfg.PollMs) * time.Millisecond,
+		})
+	}
+
+	buffer := ring.New(stCfg.Buffering.RingBytes)
+
+	// Create station
+	stationCfg := station.Config{
+		ID:             stCfg.ID,
+		ICYName:        stCfg.ICY.Name,
+		ContentType:    stCfg.Source.ContentType,
+		SniffContent:   stCfg.Source.DetectContent,
+		ICYPassthrough: stCfg.ICY.Passthrough,
+		MetaInt:        metaInt,
+		InitialMeta:    stCfg.ICY.SendInitialMeta,
+		ICYFields:      icyFields,
+		BitrateHint:    stCfg.ICY.BitrateHintKbps,
+		PollInterval:   time.Duration(stCfg.Metadata.PollMs) * time.Millisecond,
+		RingBufferSize: stCfg.Buffering.RingBytes,
+		ChunkBusCap:    32,
+		FanoutWorkers:  stCfg.Buffering.FanoutWorkers,
+		Formats:        stCfg.Metadata.Formats,
+		DefaultTitle:   stCfg.Metadata.DefaultTitle,
+		UnhealthyTitle: stCfg.Metadata.UnhealthyTitle,
+
+		DownPollInterval: time.Duration(stCfg.Metadata.UnhealthyPollMs) * time.Millisecond,
+		Providers:        providers,
+
+		ReconnectInitial: time.Duration(stCfg.Source.Reconnect.BackoffInitialMs) * time.Millisecond,
+		ReconnectMax:     time.Duration(stCfg.Source.Reconnect.BackoffMaxMs) * time.Millisecond,
+		RejectedBackoff:  time.Duration(stCfg.Source.Reconnect.BackoffRejectedMs) * time.Millisecond,
+		ReconnectJitter:  stCfg.Source.Reconnect.Jitter,
+		StallTimeout:     time.Duration(stCfg.Source.StallTimeoutMs) * time.Millisecond,
+		BurstBytes:       stCfg.Buffering.BurstOnConnectBytes,
+		ClearOnReconnect: stCfg.Buffering.ClearOnReconnect,
+
+		Observer:   m.hooks,
+		StaleAfter: time.Duration(stCfg.Metadata.StaleAfterMs) * time.Millisecond,
+	}
+
+	// Files would otherwise be fanned out instantly, so they are always
+	// played at the advertised bitrate
+	if stCfg.Source.Pace || stCfg.Source.Type == "file" {
+		stationCfg.PaceKbps = stCfg.ICY.BitrateHintKbps
+	}
+
+	schedule, err := newSchedule(stCfg.Metadata.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("station %s: schedule: %w", stCfg.ID, err)
+	}
+	stationCfg.Schedule = schedule
+
+	onAir, err := newOnAirHours(stCfg.Metadata.OnAirHours)
+	if err != nil {
+		return nil, fmt.Errorf("station %s: on_air_hours: %w", stCfg.ID, err)
+	}
+	stationCfg.OnAirHours = onAir
+
+	if stCfg.Levels.Enabled {
+		if stCfg.Source.ContentType != "audio/mpeg" {
+			return nil, fmt.Errorf("station %s: levels require audio/mpeg, not %s", stCfg.ID, stCfg.Source.ContentType)
+		}
+		stationCfg.Levels = audio.NewLevelMeter(time.Duration(stCfg.Levels.WindowMs) * time.Millisecond)
+	}
+
+	if stCfg.ICY.DetectBitrate {
+		if !bitrateDetectable[stCfg.Source.ContentType] {
+			return nil, fmt.Errorf("station %s: detect_bitrate supports MP3 and AAC, not %s", stCfg.ID, stCfg.Source.ContentType)
+		}
+		stationCfg.Bitrate = audio.NewBitrateMeter()
+	}
+
+	stationCfg.OggTags = oggTags
+
+	if len(stCfg.Variants) > 0 {
+		stationCfg.Variants = make(map[string]*station.Station, len(stCfg.Variants))
+		for name, vCfg := range stCfg.Variants {
+			v, err := m.newVariant(stCfg, name, vCfg)
+			if err != nil {
+				return nil, err
+			}
+			stationCfg.Variants[name] = v
+		}
+	}
+
+	return station.New(stationCfg, src, metaProv, buffer), nil
+}
+
+// newVariant builds a station variant as its own station, identified as
+// {id}/{name}, that keeps the parent's titles but polls no metadata: the
+// parent pushes its metadata to it
+func (m *Manager) newVariant(parent config.StationConfig, name string, vCfg config.VariantConfig) (*station.Station, error) {
+	stCfg := parent
+	stCfg.ID = parent.ID + "/" + name
+	stCfg.Source = vCfg.Source
+	if vCfg.ICY != nil {
+		stCfg.ICY = *vCfg.ICY
+		if stCfg.ICY.Name == "" {
+			stCfg.ICY.Name = parent.ICY.Name
+		}
+	}
+	stCfg.Metadata = config.MetadataConfig{
+		PollMs:         parent.Metadata.PollMs,
+		DefaultTitle:   parent.Metadata.DefaultTitle,
+		UnhealthyTitle: parent.Metadata.UnhealthyTitle,
+		Schedule:       parent.Metadata.Schedule,
+	}
+	stCfg.Levels = config.LevelsConfig{}
+	stCfg.Variants = nil
+
+	return m.newStation(stCfg)
+}
+
+func newHooks(cfgs []config.HookConfig) ([]hooks.Hook, error) {
+	out := make([]hooks.Hook, 0, len(cfgs))
+	for i, hCfg := range cfgs {
+		if hCfg.Webhook == "" && len(hCfg.Command) == 0 {
+			return nil, fmt.Errorf("hook %d: needs a webhook or command", i+1)
+		}
+		for _, ev := range hCfg.Events {
+			if ev != hooks.EventSourceUp && ev != hooks.EventSourceDown && ev != hooks.EventMetadataStale {
+				return nil, fmt.Errorf("hook %d: unknown event %q", i+1, ev)
+			}
+		}
+		out = append(out, hooks.Hook{
+			Events:  hCfg.Events,
+			Webhook: hCfg.Webhook,
+			Command: hCfg.Command,
+			Timeout: time.Duration(hCfg.TimeoutMs) * time.Millisecond,
+		})
+	}
+	return out, nil
+}
+
+// oggTypes are the content types metadata type ogg can read tags from
+var oggTypes = map[string]bool{
+	"audio/ogg":       true,
+	"application/ogg": true,
+}
+
+// bitrateDetectable lists content types whose frame headers the bitrate
+// meter understands
+var bitrateDetectable = map[string]bool{
+	"audio/mpeg": true,
+	"audio/aac":  true,
+	"audio/aacp": true,
+}
+
+// weekdays maps schedule day names to time.Weekday
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func newSchedule(cfg config.ScheduleConfig) (*station.Schedule, error) {
+	if len(cfg.Titles) == 0 {
+		return nil, nil
+	}
+
+	loc, err := loadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	sched := &station.Schedule{Location: loc}
+	for _, tCfg := range cfg.Titles {
+		start, err := parseClock(tCfg.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(tCfg.End)
+		if err != nil {
+			return nil, err
+		}
+
+		days, err := parseDays(tCfg.Days)
+		if err != nil {
+			return nil, err
+		}
+
+		sched.Titles = append(sched.Titles, station.ScheduledTitle{
+			Title: tCfg.Title,
+			Start: start,
+			End:   end,
+			Days:  days,
+		})
+	}
+	return sched, nil
+}
+
+// newOnAirHours returns nil, meaning always on air, without ranges
+func newOnAirHours(cfg config.OnAirHoursConfig) (*station.Hours, error) {
+	if len(cfg.Ranges) == 0 {
+		return nil, nil
+	}
+
+	loc, err := loadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	hours := &station.Hours{Location: loc}
+	for _, rCfg := range cfg.Ranges {
+		start, err := parseClock(rCfg.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(rCfg.End)
+		if err != nil {
+			return nil, err
+		}
+		days, err := parseDays(rCfg.Days)
+		if err != nil {
+			return nil, err
+		}
+		hours.Ranges = append(hours.Ranges, station.TimeRange{Start: start, End: end, Days: days})
+	}
+	return hours, nil
+}
+
+// loadLocation loads an IANA zone, defaulting to UTC
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// parseDays parses day names such as "mon" or "Monday"
+func parseDays(names []string) ([]time.Weekday, error) {
+	days := make([]time.Weekday, 0, len(names))
+	for _, d := range names {
+		wd, ok := weekdays[strings.ToLower(d)[:min(3, len(d))]]
+		if !ok {
+			return nil, fmt.Errorf("unknown day %q", d)
+		}
+		days = append(days, wd)
+	}
+	return days, nil
+}
+
+// newICYFields validates extra ICY field names; they are written unquoted,
+// so they must be plain words, and StreamTitle always comes from metadata
+func newICYFields(cfgs []config.ICYFieldConfig) ([]icy.Field, error) {
+	var fields []icy.Field
+	for _, f := range cfgs {
+		if f.Name == "" || strings.ContainsAny(f.Name, "=';\x00 ") {
+			return nil, fmt.Errorf("invalid icy field name %q", f.Name)
+		}
+		if strings.EqualFold(f.Name, "StreamTitle") {
+			return nil, fmt.Errorf("icy field %s is reserved", f.Name)
+		}
+		fields = append(fields, icy.Field{Name: f.Name, Value: f.Value})
+	}
+	return fields, nil
+}
+
+// parseClock parses "HH:MM" into an offset from midnight
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// newTokenSource returns nil when no token endpoint is configured
+func newTokenSource(cfg config.TokenConfig) source.TokenSource {
+	if cfg.URL == "" {
+		return nil
+	}
+	return source.NewHTTPToken(source.HTTPTokenConfig{
+		URL:     cfg.URL,
+		Headers: cfg.RequestHeaders,
+		Field:   cfg.Field,
+		Refresh: time.Duration(cfg.RefreshMs) * time.Millisecond,
+	})
+}
+
+func newStreamSource(stCfg config.StationConfig) (domain.StreamSource, error) {
+	switch stCfg.Source.Type {
+	case "", "http":
+		if stCfg.Source.URL != "" && len(stCfg.Source.Mirrors) > 0 {
+			return nil, fmt.Errorf("source url and mirrors are mutually exclusive")
+		}
+		var mirrors []source.Mirror
+		for _, m := range stCfg.Source.Mirrors {
+			if m.URL == "" {
+				return nil, fmt.Errorf("source mirror requires url")
+			}
+			mirrors = append(mirrors, source.Mirror{URL: m.URL, Weight: m.EffectiveWeight()})
+		}
+		return source.NewHTTP(source.HTTPConfig{
 			URL:            stCfg.Source.URL,
+			Mirrors:        mirrors,
+			Username:       stCfg.Source.Username,
+			Password:       stCfg.Source.Password,
 			ConnectTimeout: time.Duration(stCfg.Source.ConnectTimeoutMs) * time.Millisecond,
 			ReadTimeout:    time.Duration(stCfg.Source.ReadTimeoutMs) * time.Millisecond,
 			Headers:        stCfg.Source.RequestHeaders,
+			Token:          newTokenSource(stCfg.Source.Token),
+			TokenHeader:    stCfg.Source.Token.Header,
+			TokenPrefix:    stCfg.Source.Token.Prefix,
+		}), nil
+	case "file":
+		return source.NewFile(source.FileConfig{Path: stCfg.Source.Path}), nil
+	case "tone":
+		if stCfg.Source.ContentType != "" && stCfg.Source.ContentType != "audio/mpeg" {
+			return nil, fmt.Errorf("tone source only produces audio/mpeg")
 		}
-		src := source.NewHTTP(srcCfg)
+		return source.NewTone(source.ToneConfig{BitrateKbps: stCfg.ICY.BitrateHintKbps})
+	default:
+		return nil, fmt.Errorf("unknown source type %q", stCfg.Source.Type)
+	}
+}
 
-		metaCfg := metadata.HTTPConfig{
-			URL:     stCfg.Metadata.URL,
-			Timeout: time.Duration(stCfg.Metadata.PollMs) * time.Millisecond,
-			Build: metadata.BuildConfig{
-				Format:              stCfg.Metadata.Build.Format,
-				StripSingleQuotes:   stCfg.Metadata.Build.StripSingleQuotes,
-				NormalizeWhitespace: stCfg.Metadata.Build.NormalizeWhitespace,
-				FallbackKeyOrder:    stCfg.Metadata.Build.FallbackKeyOrder,
-			},
-		}
-		metaProv := metadata.NewHTTP(metaCfg)
+// resolveMetadataURL resolves a metadata URL starting with "/" (such as
+// Icecast's /status-json.xsl) against the source URL's scheme and host
+func resolveMetadataURL(metaURL, sourceURL string) (string, error) {
+	if !strings.HasPrefix(metaURL, "/") {
+		return metaURL, nil
+	}
+	if sourceURL == "" {
+		return "", fmt.Errorf("relative metadata url %s needs a source url", metaURL)
+	}
+
+	base, err := url.Parse(sourceURL)
+	if err != nil {
+		return "", fmt.Errorf("source url: %w", err)
+	}
+	ref, err := url.Parse(metaURL)
+	if err != nil {
+		return "", fmt.Errorf("metadata url: %w", err)
+	}
+
+	// Scheme and host only; source credentials aren't carried over
+	resolved := (&url.URL{Scheme: base.Scheme, Host: base.Host}).ResolveReference(ref)
+	if (resolved.Scheme != "http" && resolved.Scheme != "https") || resolved.Host == "" {
+		return "", fmt.Errorf("relative metadata url %s doesn't resolve to an absolute http url against %s", metaURL, base.Redacted())
+	}
+	return resolved.String(), nil
+}
+
+func (m *Manager) newMetadataProvider(stationID, url, username, password string, headers map[string]string, pollMs int, build config.BuildConfig) (domain.MetadataProvider, error) {
+	buildCfg, err := newBuildConfig(build)
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata.NewHTTP(metadata.HTTPConfig{
+		URL:      url,
+		Username: username,
+		Password: password,
+		Timeout:  time.Duration(pollMs) * time.Millisecond,
+		Headers:  headers,
+		Build:    buildCfg,
+		Shared:   m.coalescer,
 
-		buffer := ring.New(stCfg.Buffering.RingBytes)
+		StationID: stationID,
+	}), nil
+}
 
-		// Create station
-		stationCfg := station.Config{
-			ID:             stCfg.ID,
-			ICYName:        stCfg.ICY.Name,
-			MetaInt:        stCfg.ICY.MetaInt,
-			BitrateHint:    stCfg.ICY.BitrateHintKbps,
-			PollInterval:   time.Duration(stCfg.Metadata.PollMs) * time.Millisecond,
-			RingBufferSize: stCfg.Buffering.RingBytes,
-			ChunkBusCap:    32,
+// newBuildConfig validates a metadata build config and resolves its transforms
+func newBuildConfig(build config.BuildConfig) (metadata.BuildConfig, error) {
+	switch build.Mode {
+	case "", metadata.ModeTemplate:
+	case metadata.ModePassthrough:
+		if build.PassthroughField == "" {
+			return metadata.BuildConfig{}, fmt.Errorf("build mode passthrough requires passthrough_field")
 		}
+	default:
+		return metadata.BuildConfig{}, fmt.Errorf("unknown build mode %q", build.Mode)
+	}
 
-		st := station.New(stationCfg, src, metaProv, buffer)
+	split := metadata.Split{Field: build.Split.Field, Separator: build.Split.Separator, First: "artist", Rest: "title"}
+	if split.Separator == "" {
+		split.Separator = " - "
+	}
+	if into := build.Split.Into; len(into) > 0 {
+		if len(into) != 2 || !metadata.KnownPlaceholder(into[0]) || !metadata.KnownPlaceholder(into[1]) || into[0] == into[1] {
+			return metadata.BuildConfig{}, fmt.Errorf("split into must name two different placeholders, got %v", into)
+		}
+		split.First, split.Rest = into[0], into[1]
+	}
 
-		mgr.stations[stCfg.ID] = st
+	if build.MaxTitleBytes != 0 && build.MaxTitleBytes < metadata.MinTitleBytes {
+		return metadata.BuildConfig{}, fmt.Errorf("max_title_bytes must be 0 (no limit) or at least %d, got %d", metadata.MinTitleBytes, build.MaxTitleBytes)
 	}
 
-	return mgr, nil
+	if build.CleanSeparator != "" && strings.TrimSpace(build.CleanSeparator) == "" {
+		return metadata.BuildConfig{}, fmt.Errorf("clean_separator %q has no visible characters", build.CleanSeparator)
+	}
+
+	var overrides map[string]string
+	for title, replacement := range build.TitleOverrides {
+		key := metadata.OverrideKey(title)
+		if _, dup := overrides[key]; dup {
+			return metadata.BuildConfig{}, fmt.Errorf("title_overrides has %q more than once", key)
+		}
+		if overrides == nil {
+			overrides = make(map[string]string, len(build.TitleOverrides))
+		}
+		overrides[key] = replacement
+	}
+
+	transforms := make([]metadata.Transform, 0, len(build.Transforms))
+	for _, tCfg := range build.Transforms {
+		t, err := metadata.NewTransform(tCfg.Name, tCfg.Args)
+		if err != nil {
+			return metadata.BuildConfig{}, err
+		}
+		transforms = append(transforms, t)
+	}
+
+	return metadata.BuildConfig{
+		Mode:                build.Mode,
+		Format:              build.Format,
+		PassthroughField:    build.PassthroughField,
+		ValidatePassthrough: build.ValidatePassthrough,
+		StripSingleQuotes:   build.StripSingleQuotes,
+		NormalizeWhitespace: build.NormalizeWhitespace,
+		FallbackKeyOrder:    build.FallbackKeyOrder,
+		DedupeSegments:      build.DedupeSegments,
+		Transforms:          transforms,
+		MaxTitleBytes:       build.MaxTitleBytes,
+		Defaults:            build.Defaults,
+		DropEmptySeparators: build.DropEmptySeparators,
+		CleanSeparator:      build.CleanSeparator,
+		Split:               split,
+		TitleOverrides:      overrides,
+	}, nil
+}
+
+// Config returns the effective configuration the manager was built from
+func (m *Manager) Config() *config.Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.cfg
+}
+
+// Recorder returns the on-demand station recorder
+func (m *Manager) Recorder() *record.Manager {
+	return m.recorder
+}
+
+// key maps a station ID to its lookup key; IDs match case-insensitively
+// unless server.case_sensitive_ids is set
+func (m *Manager) key(id string) string {
+	if m.caseSensitive {
+		return id
+	}
+	return strings.ToLower(id)
 }
 
 func (m *Manager) Get(id string) *station.Station {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return m.stations[id]
+	return m.stations[m.key(id)]
+}
+
+// sameStation reports whether two enabled station configs are equivalent,
+// so adding an explicit enabled: true doesn't restart a running station
+func sameStation(a, b config.StationConfig) bool {
+	a.Enabled, b.Enabled = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// Disabled reports whether id names a configured station with enabled: false
+func (m *Manager) Disabled(id string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.disabled[m.key(id)]
 }
 
+// List returns every enabled station sorted by ID, so /stations and other listings
+// keep a stable order
 func (m *Manager) List() []*station.Station {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -88,13 +736,89 @@ func (m *Manager) List() []*station.Station {
 	for _, st := range m.stations {
 		result = append(result, st)
 	}
+	slices.SortFunc(result, func(a, b *station.Station) int {
+		return strings.Compare(a.ID(), b.ID())
+	})
 	return result
 }
 
+// AcquireConnection reserves one of the server-wide streaming slots. It
+// returns false when server.max_connections streams are already active.
+func (m *Manager) AcquireConnection() bool {
+	n := m.activeConns.Add(1)
+	if m.maxConns > 0 && n > int64(m.maxConns) {
+		m.activeConns.Add(-1)
+		return false
+	}
+	return true
+}
+
+// ReleaseConnection frees a slot taken by AcquireConnection
+func (m *Manager) ReleaseConnection() {
+	m.activeConns.Add(-1)
+}
+
+// Connections returns the active stream count and the configured cap (0 = unlimited)
+func (m *Manager) Connections() (active, max int) {
+	return int(m.activeConns.Load()), m.maxConns
+}
+
+// Closing returns a channel that is closed once shutdown begins. Stream
+// handlers watch it to finish their current write and disconnect.
+func (m *Manager) Closing() <-chan struct{} {
+	return m.closing
+}
+
+func (m *Manager) closed() bool {
+	select {
+	case <-m.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// BeginDrain stops accepting new streams while existing ones keep playing,
+// so load balancers can move listeners away before BeginShutdown
+func (m *Manager) BeginDrain() {
+	m.draining.Store(true)
+}
+
+// Draining reports whether BeginDrain or BeginShutdown has been called
+func (m *Manager) Draining() bool {
+	return m.draining.Load() || m.closed()
+}
+
+// BeginShutdown signals Closing without stopping stations, so in-flight
+// streams end cleanly while the HTTP server drains. Hooks and state saving
+// keep running until Shutdown.
+func (m *Manager) BeginShutdown() {
+	m.closeOnce.Do(func() { close(m.closing) })
+}
+
 func (m *Manager) Start() error {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	m.started = true
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	m.hooksDone = make(chan struct{})
+	go func() {
+		defer close(m.hooksDone)
+		m.hooks.Run(m.ctx)
+	}()
+
+	if m.state != nil {
+		interval := time.Duration(m.cfg.State.SaveIntervalMs) * time.Millisecond
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			m.runStateSaver(interval)
+		}()
+	}
+
 	for _, st := range m.stations {
 		if err := st.Start(); err != nil {
 			return err
@@ -105,17 +829,149 @@ func (m *Manager) Start() error {
 }
 
 func (m *Manager) Shutdown() error {
-	m.cancel()
+	// Wait out any reload so the stations stopped below are the final set
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	// The root context outlives the drain below, so hooks already running
+	// and the final state save aren't cut off
+	defer m.cancel()
+
+	m.BeginShutdown()
 	m.wg.Wait()
+	m.recorder.StopAll()
 
 	// Shutdown all stations
+	for _, st := range m.List() {
+		if err := st.Shutdown(); err != nil {
+			return err
+		}
+	}
+
+	if m.state != nil {
+		m.saveState()
+	}
+
+	m.drainHooks()
+	return nil
+}
+
+// hookDrainTimeout bounds how long Shutdown waits for queued hook events
+const hookDrainTimeout = 10 * time.Second
+
+// drainHooks delivers the hook events still queued, giving up on them after
+// hookDrainTimeout
+func (m *Manager) drainHooks() {
+	if m.hooksDone == nil {
+		return
+	}
+
+	m.hooks.Stop()
+	select {
+	case <-m.hooksDone:
+	case <-time.After(hookDrainTimeout):
+		log.Printf("hooks: still delivering after %s, abandoning the rest", hookDrainTimeout)
+		m.cancel()
+		<-m.hooksDone
+	}
+}
+
+// Reload applies the station list from cfg: unchanged stations keep running,
+// removed ones are stopped, and new or changed ones are rebuilt and started.
+// Other settings (listen, server, hooks, ...) only change on restart. Reloads
+// are serialized, so overlapping SIGHUPs apply one after another, and an
+// invalid config leaves the running stations untouched.
+func (m *Manager) Reload(cfg *config.Config) error {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	if m.closed() {
+		return fmt.Errorf("shutting down")
+	}
+
+	cfg = cfg.WithDefaults()
+	if err := cfg.ValidateStations(); err != nil {
+		return err
+	}
+
 	m.mu.RLock()
-	defer m.mu.RUnlock()
+	current, old := m.cfg, m.stations
+	m.mu.RUnlock()
 
-	for _, st := range m.stations {
-		if err := st.Shutdown(); err != nil {
+	prev := make(map[string]config.StationConfig, len(current.Stations))
+	for _, stCfg := range current.Stations {
+		prev[m.key(stCfg.ID)] = stCfg
+	}
+
+	next := make(map[string]*station.Station, len(cfg.Stations))
+	disabled := make(map[string]bool)
+	var fresh []*station.Station
+	for _, stCfg := range cfg.Stations {
+		k := m.key(stCfg.ID)
+		if _, exists := next[k]; exists || disabled[k] {
+			return fmt.Errorf("duplicate station id %q", stCfg.ID)
+		}
+		if !stCfg.IsEnabled() {
+			disabled[k] = true
+			continue
+		}
+		if p, ok := prev[k]; ok && p.IsEnabled() && sameStation(p, stCfg) {
+			next[k] = old[k]
+			continue
+		}
+
+		st, err := m.newStation(stCfg)
+		if err != nil {
 			return err
 		}
+		next[k] = st
+		fresh = append(fresh, st)
+	}
+
+	if id := current.Server.DefaultStation; id != "" && next[m.key(id)] == nil && !disabled[m.key(id)] {
+		return fmt.Errorf("default station %q is not configured", id)
+	}
+
+	// New and rebuilt stations pick up their saved metadata like those
+	// built at startup
+	m.mu.RLock()
+	restored := m.restored
+	m.mu.RUnlock()
+	if m.state != nil && len(fresh) > 0 {
+		entries, err := m.state.Load()
+		if err != nil {
+			log.Printf("state: %v; reloaded stations start without saved metadata", err)
+		}
+		restoreStations(fresh, entries)
+		restored = maps.Clone(restored)
+		if restored == nil {
+			restored = make(map[string]state.Entry)
+		}
+		for _, st := range fresh {
+			if e, ok := entries[st.ID()]; ok {
+				restored[st.ID()] = e
+			}
+		}
+	}
+
+	updated := *current
+	updated.Stations = cfg.Stations
+
+	m.mu.Lock()
+	m.stations, m.disabled, m.cfg, m.restored = next, disabled, &updated, restored
+	m.mu.Unlock()
+
+	for k, st := range old {
+		if next[k] != st {
+			st.Shutdown()
+		}
+	}
+	if m.started {
+		for _, st := range fresh {
+			if err := st.Start(); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -3,9 +3,22 @@
 package manager
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/harper/radio-metadata-proxy/internal/application/config"
+	"github.com/harper/radio-metadata-proxy/internal/domain/station"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/metadata"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/record"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/state"
 )
 
 func TestManager_NewFromConfig(t *testing.T) {
@@ -51,3 +64,917 @@ func TestManager_NewFromConfig(t *testing.T) {
 		t.Errorf("expected ID test1, got %s", st.ID())
 	}
 }
+
+func TestManager_AudioOnlyStation(t *testing.T) {
+	audio := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Write([]byte("audio data"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer audio.Close()
+
+	cfg := &config.Config{
+		Stations: []config.StationConfig{
+			{
+				ID:  "audio_only",
+				ICY: config.ICYConfig{Name: "Audio Only", MetaInt: 16384, BitrateHintKbps: 128},
+				Source: config.SourceConfig{
+					URL:              audio.URL,
+					ConnectTimeoutMs: 5000,
+				},
+				Metadata: config.MetadataConfig{
+					DefaultTitle: "Audio Only",
+				},
+				Buffering: config.BufferingConfig{RingBytes: 262144},
+			},
+		},
+	}
+
+	mgr, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer mgr.Shutdown()
+
+	time.Sleep(100 * time.Millisecond)
+
+	st := mgr.Get("audio_only")
+	if meta := st.CurrentMetadata(); meta != "StreamTitle='Audio Only';" {
+		t.Errorf("expected default title, got %q", meta)
+	}
+
+	if !st.SourceHealthy() {
+		t.Error("expected source to be healthy")
+	}
+}
+
+func TestManager_FileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loop.mp3")
+	if err := os.WriteFile(path, []byte("audio data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Stations: []config.StationConfig{
+			{
+				ID:        "local",
+				ICY:       config.ICYConfig{Name: "Local", MetaInt: 16384, BitrateHintKbps: 128},
+				Source:    config.SourceConfig{Type: "file", Path: path},
+				Buffering: config.BufferingConfig{RingBytes: 262144},
+			},
+		},
+	}
+
+	mgr, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer mgr.Shutdown()
+
+	time.Sleep(50 * time.Millisecond)
+
+	if !mgr.Get("local").SourceHealthy() {
+		t.Error("expected file source to be healthy")
+	}
+}
+
+func TestManager_ToneSourceWithSyntheticMetadata(t *testing.T) {
+	cfg := &config.Config{
+		Stations: []config.StationConfig{
+			{
+				ID:        "test",
+				ICY:       config.ICYConfig{Name: "Test", MetaInt: 16384, BitrateHintKbps: 32},
+				Source:    config.SourceConfig{Type: "tone"},
+				Metadata:  config.MetadataConfig{Type: "synthetic", PollMs: 1000},
+				Buffering: config.BufferingConfig{RingBytes: 262144},
+			},
+		},
+	}
+
+	mgr, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer mgr.Shutdown()
+
+	time.Sleep(100 * time.Millisecond)
+
+	st := mgr.Get("test")
+	if !st.SourceHealthy() {
+		t.Error("expected tone source to be healthy")
+	}
+	if meta := st.CurrentMetadata(); !strings.Contains(meta, "Test Artist 1") {
+		t.Errorf("expected synthetic title, got %q", meta)
+	}
+}
+
+func TestManager_ToneSourceValidation(t *testing.T) {
+	for name, stCfg := range map[string]config.StationConfig{
+		"bitrate":       {ID: "bad", ICY: config.ICYConfig{BitrateHintKbps: 100}, Source: config.SourceConfig{Type: "tone"}},
+		"content type":  {ID: "bad", ICY: config.ICYConfig{BitrateHintKbps: 32}, Source: config.SourceConfig{Type: "tone", ContentType: "audio/aac"}},
+		"metadata type": {ID: "bad", Metadata: config.MetadataConfig{Type: "carrier-pigeon"}},
+	} {
+		if _, err := NewFromConfig(&config.Config{Stations: []config.StationConfig{stCfg}}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestManager_OggMetadataRequiresOggSource(t *testing.T) {
+	stCfg := config.StationConfig{
+		ID:       "ogg",
+		Source:   config.SourceConfig{URL: "http://example.com/live.ogg", ContentType: "audio/ogg"},
+		Metadata: config.MetadataConfig{Type: "ogg", PollMs: 1000},
+	}
+	if _, err := NewFromConfig(&config.Config{Stations: []config.StationConfig{stCfg}}); err != nil {
+		t.Fatalf("expected Ogg station to be accepted: %v", err)
+	}
+
+	stCfg.Source.ContentType = "audio/mpeg"
+	if _, err := NewFromConfig(&config.Config{Stations: []config.StationConfig{stCfg}}); err == nil {
+		t.Error("expected error for in-stream Ogg tags on an MP3 source")
+	}
+}
+
+func TestManager_UnknownSourceType(t *testing.T) {
+	cfg := &config.Config{
+		Stations: []config.StationConfig{
+			{ID: "bad", Source: config.SourceConfig{Type: "carrier-pigeon"}},
+		},
+	}
+
+	if _, err := NewFromConfig(cfg); err == nil {
+		t.Error("expected error for unknown source type")
+	}
+}
+
+func TestManager_SourceMirrorsValidation(t *testing.T) {
+	weight := func(n int) *int { return &n }
+	cases := map[string]config.SourceConfig{
+		"url and mirrors": {URL: "http://example.com/a.mp3", Mirrors: []config.MirrorConfig{{URL: "http://example.com/b.mp3"}}},
+		"missing url":     {Mirrors: []config.MirrorConfig{{Weight: weight(2)}}},
+		"negative weight": {Mirrors: []config.MirrorConfig{{URL: "http://example.com/b.mp3", Weight: weight(-1)}}},
+		"all disabled": {Mirrors: []config.MirrorConfig{
+			{URL: "http://example.com/a.mp3", Weight: weight(0)},
+			{URL: "http://example.com/b.mp3", Weight: weight(0)},
+		}},
+	}
+
+	for name, src := range cases {
+		cfg := &config.Config{Stations: []config.StationConfig{{ID: "bad", Source: src}}}
+		if _, err := NewFromConfig(cfg); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestManager_DuplicateStationIDs(t *testing.T) {
+	cfg := &config.Config{
+		Stations: []config.StationConfig{
+			{ID: "fip", Source: config.SourceConfig{URL: "http://example.com/a.mp3"}},
+			{ID: "nts", Source: config.SourceConfig{URL: "http://example.com/b.mp3"}},
+			{ID: "fip", Source: config.SourceConfig{URL: "http://example.com/c.mp3"}},
+		},
+	}
+
+	_, err := NewFromConfig(cfg)
+	if err == nil {
+		t.Fatal("expected error for duplicate station IDs")
+	}
+
+	if !strings.Contains(err.Error(), `"fip"`) {
+		t.Errorf("expected error to name the duplicate ID, got %v", err)
+	}
+}
+
+func TestManager_CaseInsensitiveIDs(t *testing.T) {
+	cfg := &config.Config{
+		Stations: []config.StationConfig{
+			{ID: "MyStation", Source: config.SourceConfig{URL: "http://example.com/a.mp3"}},
+		},
+	}
+
+	mgr, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+
+	st := mgr.Get("mystation")
+	if st == nil {
+		t.Fatal("expected case-insensitive lookup to find MyStation")
+	}
+	if st.ID() != "MyStation" {
+		t.Errorf("expected original ID preserved, got %s", st.ID())
+	}
+
+	// IDs differing only by case collide
+	cfg.Stations = append(cfg.Stations, config.StationConfig{ID: "mystation"})
+	if _, err := NewFromConfig(cfg); err == nil {
+		t.Error("expected error for IDs differing only by case")
+	}
+
+	cfg.Server.CaseSensitiveIDs = true
+	mgr, err = NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig with case-sensitive IDs failed: %v", err)
+	}
+	if mgr.Get("MYSTATION") != nil {
+		t.Error("expected case-sensitive lookup to miss")
+	}
+}
+
+func TestManager_UnknownDefaultStation(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{DefaultStation: "missing"},
+		Stations: []config.StationConfig{
+			{ID: "fip", Source: config.SourceConfig{URL: "http://example.com/a.mp3"}},
+		},
+	}
+
+	if _, err := NewFromConfig(cfg); err == nil {
+		t.Error("expected error for unknown default station")
+	}
+}
+
+func TestManager_UnknownMetaTimeFormat(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{MetaTimeFormat: "iso"},
+		Stations: []config.StationConfig{
+			{ID: "fip", Source: config.SourceConfig{URL: "http://example.com/a.mp3"}},
+		},
+	}
+
+	if _, err := NewFromConfig(cfg); err == nil {
+		t.Error("expected error for unknown meta_time_format")
+	}
+}
+
+func TestManager_RingBytesTooSmall(t *testing.T) {
+	cfg := &config.Config{
+		Stations: []config.StationConfig{
+			{
+				ID:        "fip",
+				Source:    config.SourceConfig{URL: "http://example.com/a.mp3"},
+				Buffering: config.BufferingConfig{RingBytes: 100},
+			},
+		},
+	}
+
+	if _, err := NewFromConfig(cfg); err == nil {
+		t.Error("expected error for ring_bytes below minimum")
+	}
+}
+
+func TestManager_InvalidSchedule(t *testing.T) {
+	for _, sched := range []config.ScheduleConfig{
+		{Titles: []config.ScheduledTitleConfig{{Title: "x", Start: "25:00", End: "06:00"}}},
+		{Titles: []config.ScheduledTitleConfig{{Title: "x", Start: "22:00", End: "06:00", Days: []string{"someday"}}}},
+		{Timezone: "Not/AZone", Titles: []config.ScheduledTitleConfig{{Title: "x", Start: "22:00", End: "06:00"}}},
+	} {
+		cfg := &config.Config{
+			Stations: []config.StationConfig{{
+				ID:       "fip",
+				Source:   config.SourceConfig{URL: "http://example.com/a.mp3"},
+				Metadata: config.MetadataConfig{Schedule: sched},
+			}},
+		}
+		if _, err := NewFromConfig(cfg); err == nil {
+			t.Errorf("expected error for schedule %+v", sched)
+		}
+	}
+}
+
+func TestManager_InvalidOnAirHours(t *testing.T) {
+	for _, hours := range []config.OnAirHoursConfig{
+		{Ranges: []config.TimeRangeConfig{{Start: "6am", End: "22:00"}}},
+		{Ranges: []config.TimeRangeConfig{{Start: "06:00", End: "22:00", Days: []string{"someday"}}}},
+		{Timezone: "Not/AZone", Ranges: []config.TimeRangeConfig{{Start: "06:00", End: "22:00"}}},
+	} {
+		cfg := &config.Config{
+			Stations: []config.StationConfig{{
+				ID:       "fip",
+				Source:   config.SourceConfig{URL: "http://example.com/a.mp3"},
+				Metadata: config.MetadataConfig{StaleAfterMs: 60000, OnAirHours: hours},
+			}},
+		}
+		if _, err := NewFromConfig(cfg); err == nil {
+			t.Errorf("expected error for on_air_hours %+v", hours)
+		}
+	}
+}
+
+// toneStations builds a config of synthetic stations with the given IDs
+func toneStations(ids ...string) *config.Config {
+	cfg := &config.Config{}
+	for _, id := range ids {
+		cfg.Stations = append(cfg.Stations, config.StationConfig{
+			ID:       id,
+			ICY:      config.ICYConfig{BitrateHintKbps: 32},
+			Source:   config.SourceConfig{Type: "tone"},
+			Metadata: config.MetadataConfig{Type: "synthetic"},
+		})
+	}
+	return cfg
+}
+
+func TestManager_ShutdownFinishesHooks(t *testing.T) {
+	arrived := make(chan struct{}, 8)
+	completed := make(chan bool, 8)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		select {
+		case <-time.After(100 * time.Millisecond):
+			completed <- true
+		case <-r.Context().Done():
+			completed <- false
+		}
+	}))
+	defer srv.Close()
+
+	cfg := toneStations("fip")
+	cfg.Hooks = []config.HookConfig{{Webhook: srv.URL, Events: []string{"source_up"}}}
+	mgr, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	select {
+	case <-arrived:
+	case <-time.After(2 * time.Second):
+		t.Fatal("source_up hook never fired")
+	}
+
+	// Shutting down with the webhook in flight lets it finish
+	mgr.BeginShutdown()
+	if err := mgr.Shutdown(); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if !<-completed {
+		t.Error("expected the in-flight webhook to complete, it was cancelled")
+	}
+}
+
+func TestManager_Reload(t *testing.T) {
+	mgr, err := NewFromConfig(toneStations("a", "b"))
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer mgr.Shutdown()
+
+	a := mgr.Get("a")
+	if err := mgr.Reload(toneStations("a", "c")); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if mgr.Get("a") != a {
+		t.Error("expected unchanged station to keep running untouched")
+	}
+	if mgr.Get("b") != nil {
+		t.Error("expected removed station to be gone")
+	}
+	if mgr.Get("c") == nil {
+		t.Fatal("expected added station")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if !mgr.Get("c").SourceHealthy() {
+		t.Error("expected added station to be started")
+	}
+
+	// An invalid config leaves the running set alone
+	if err := mgr.Reload(toneStations("a", "a")); err == nil {
+		t.Error("expected error for duplicate IDs")
+	}
+	if mgr.Get("a") != a || mgr.Get("c") == nil {
+		t.Error("expected failed reload to keep the previous stations")
+	}
+}
+
+func TestManager_ReloadEndsRecording(t *testing.T) {
+	cfg := toneStations("a", "b")
+	cfg.Recording.Dir = t.TempDir()
+	mgr, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer mgr.Shutdown()
+
+	for _, id := range []string{"a", "b"} {
+		if _, err := mgr.Recorder().Start(mgr.Get(id)); err != nil {
+			t.Fatalf("record %s: %v", id, err)
+		}
+	}
+
+	// b is removed and a is rebuilt with a new bitrate
+	next := toneStations("a")
+	next.Stations[0].ICY.BitrateHintKbps = 64
+	next.Recording = cfg.Recording
+	if err := mgr.Reload(next); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	for _, id := range []string{"a", "b"} {
+		var status record.Status
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+			if status, _ = mgr.Recorder().Status(id); !status.Recording {
+				break
+			}
+		}
+		if status.Recording || status.Error != record.ErrStationStopped.Error() {
+			t.Errorf("%s: expected the recording ended by the reload, got %+v", id, status)
+		}
+	}
+
+	// The rebuilt station can be recorded again
+	if _, err := mgr.Recorder().Start(mgr.Get("a")); err != nil {
+		t.Errorf("expected a new recording of the rebuilt station, got %v", err)
+	}
+}
+
+func TestManager_ConcurrentReloads(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	mgr, err := NewFromConfig(toneStations("a", "b"))
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	configs := []*config.Config{
+		toneStations("a", "b"),
+		toneStations("a", "c"),
+		toneStations("c"),
+		toneStations("a", "b", "c"),
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := mgr.Reload(configs[i%len(configs)]); err != nil {
+				t.Errorf("Reload failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The running stations must match whichever config was applied last
+	var running, configured []string
+	for _, st := range mgr.List() {
+		running = append(running, st.ID())
+	}
+	for _, stCfg := range mgr.Config().Stations {
+		configured = append(configured, stCfg.ID)
+	}
+	slices.Sort(running)
+	slices.Sort(configured)
+	if !slices.Equal(running, configured) {
+		t.Errorf("running stations %v don't match config %v", running, configured)
+	}
+
+	// Every station built along the way, replaced or not, must have stopped
+	mgr.Shutdown()
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > baseline {
+		t.Errorf("expected goroutines to return to %d after shutdown, got %d", baseline, n)
+	}
+}
+
+func TestManager_SplitIntoValidation(t *testing.T) {
+	for _, into := range [][]string{{"artist"}, {"artist", "artist"}, {"artist", "nope"}} {
+		cfg := &config.Config{
+			Stations: []config.StationConfig{{
+				ID:     "fip",
+				Source: config.SourceConfig{URL: "http://example.com/a.mp3"},
+				Metadata: config.MetadataConfig{
+					URL:   "http://example.com/meta",
+					Build: config.BuildConfig{Split: config.SplitConfig{Field: "now", Into: into}},
+				},
+			}},
+		}
+		if _, err := NewFromConfig(cfg); err == nil {
+			t.Errorf("expected error for split into %v", into)
+		}
+	}
+}
+
+func TestManager_CleanSeparatorValidation(t *testing.T) {
+	cfg := toneStations("fip")
+	cfg.Stations[0].Metadata.Build.CleanSeparator = "   "
+	if _, err := NewFromConfig(cfg); err == nil {
+		t.Error("expected a whitespace-only clean_separator to be rejected")
+	}
+
+	cfg.Stations[0].Metadata.Build.CleanSeparator = " - "
+	if _, err := NewFromConfig(cfg); err != nil {
+		t.Errorf("expected clean_separator accepted, got %v", err)
+	}
+}
+
+func TestManager_ListSortedByID(t *testing.T) {
+	mgr, err := NewFromConfig(toneStations("nts", "fip", "kexp", "bbc"))
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+
+	want := []string{"bbc", "fip", "kexp", "nts"}
+	for i := 0; i < 10; i++ {
+		var got []string
+		for _, st := range mgr.List() {
+			got = append(got, st.ID())
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestResolveMetadataURL(t *testing.T) {
+	tests := []struct {
+		meta, source, want string
+	}{
+		{"/status-json.xsl", "https://user:pw@ice.example.com:8443/live.mp3", "https://ice.example.com:8443/status-json.xsl"},
+		{"/api/now?station=fip", "http://radio.example.com/fip.mp3", "http://radio.example.com/api/now?station=fip"},
+		{"https://api.example.com/now", "http://radio.example.com/fip.mp3", "https://api.example.com/now"},
+	}
+	for _, tt := range tests {
+		got, err := resolveMetadataURL(tt.meta, tt.source)
+		if err != nil || got != tt.want {
+			t.Errorf("resolve(%q, %q) = %q, %v; want %q", tt.meta, tt.source, got, err, tt.want)
+		}
+	}
+
+	for _, source := range []string{"", "relative/stream.mp3"} {
+		if _, err := resolveMetadataURL("/status-json.xsl", source); err == nil {
+			t.Errorf("expected error resolving against %q", source)
+		}
+	}
+
+	// Stations without a source URL can't use relative metadata URLs
+	cfg := toneStations("test")
+	cfg.Stations[0].Metadata = config.MetadataConfig{URL: "/status-json.xsl"}
+	if _, err := NewFromConfig(cfg); err == nil {
+		t.Error("expected error for relative metadata url on a tone source")
+	}
+}
+
+func TestManager_ICYFieldValidation(t *testing.T) {
+	for _, name := range []string{"", "Stream Name", "a=b", "x'", "StreamTitle", "streamtitle"} {
+		cfg := toneStations("fip")
+		cfg.Stations[0].ICY.Fields = []config.ICYFieldConfig{{Name: name, Value: "v"}}
+		if _, err := NewFromConfig(cfg); err == nil {
+			t.Errorf("expected error for icy field name %q", name)
+		}
+	}
+
+	cfg := toneStations("fip")
+	cfg.Stations[0].ICY.Fields = []config.ICYFieldConfig{{Name: "StationName", Value: "FIP"}}
+	if _, err := NewFromConfig(cfg); err != nil {
+		t.Errorf("valid icy field rejected: %v", err)
+	}
+}
+
+func TestManager_TitleOverridesNormalized(t *testing.T) {
+	build, err := newBuildConfig(config.BuildConfig{TitleOverrides: map[string]string{" AdBreak ": "Commercial break"}})
+	if err != nil {
+		t.Fatalf("newBuildConfig failed: %v", err)
+	}
+	if build.TitleOverrides["adbreak"] != "Commercial break" {
+		t.Errorf("expected normalized key, got %v", build.TitleOverrides)
+	}
+
+	_, err = newBuildConfig(config.BuildConfig{TitleOverrides: map[string]string{"News": "a", "news": "b"}})
+	if err == nil {
+		t.Error("expected error for keys that collide after normalization")
+	}
+}
+
+func TestManager_MaxTitleBytesMinimum(t *testing.T) {
+	for _, n := range []int{-1, 1, metadata.MinTitleBytes - 1} {
+		if _, err := newBuildConfig(config.BuildConfig{MaxTitleBytes: n}); err == nil {
+			t.Errorf("expected max_title_bytes %d rejected", n)
+		}
+	}
+	for _, n := range []int{0, metadata.MinTitleBytes} {
+		if _, err := newBuildConfig(config.BuildConfig{MaxTitleBytes: n}); err != nil {
+			t.Errorf("max_title_bytes %d rejected: %v", n, err)
+		}
+	}
+}
+
+func TestManager_ShutdownWaitsForStations(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	mgr, err := NewFromConfig(toneStations("fip", "kexp"))
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if runtime.NumGoroutine() <= before {
+		t.Fatal("expected station goroutines to be running")
+	}
+
+	mgr.Shutdown()
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines still running after Shutdown returned", after-before)
+	}
+}
+
+func TestManager_DisabledStations(t *testing.T) {
+	off := false
+	cfg := toneStations("a", "b")
+	cfg.Stations[1].Enabled = &off
+	cfg.Server.DefaultStation = "b"
+
+	mgr, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer mgr.Shutdown()
+
+	if mgr.Get("b") != nil || !mgr.Disabled("B") || len(mgr.List()) != 1 {
+		t.Fatal("expected b to be configured but not running")
+	}
+	if mgr.Disabled("a") || mgr.Disabled("nope") {
+		t.Error("only disabled stations should report Disabled")
+	}
+
+	// Enabling b starts it without touching a
+	a := mgr.Get("a")
+	on := true
+	cfg = toneStations("a", "b")
+	cfg.Stations[0].Enabled = &on
+	if err := mgr.Reload(cfg); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if mgr.Get("a") != a {
+		t.Error("an explicit enabled: true shouldn't restart a running station")
+	}
+	if mgr.Get("b") == nil || mgr.Disabled("b") {
+		t.Fatal("expected b to be enabled by reload")
+	}
+
+	// Disabling it again stops it
+	cfg = toneStations("a", "b")
+	cfg.Stations[1].Enabled = &off
+	if err := mgr.Reload(cfg); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if mgr.Get("b") != nil || !mgr.Disabled("b") || mgr.Get("a") != a {
+		t.Error("expected only b to be stopped")
+	}
+}
+
+func TestManager_MetaIntRange(t *testing.T) {
+	cfg := toneStations("fip")
+	cfg.Stations[0].ICY.MetaInt = -1
+	if _, err := NewFromConfig(cfg); err == nil {
+		t.Error("expected error for negative metaint")
+	}
+
+	cfg = toneStations("fip")
+	cfg.Stations[0].ICY.MetaInt = 10 << 20
+	mgr, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	if got := mgr.Get("fip").MetaInt(); got != config.MaxMetaInt {
+		t.Errorf("expected metaint clamped to %d, got %d", config.MaxMetaInt, got)
+	}
+
+	cfg = toneStations("fip")
+	cfg.Stations[0].ICY.MetaInt = 8192
+	if mgr, _ = NewFromConfig(cfg); mgr.Get("fip").MetaInt() != 8192 {
+		t.Error("expected in-range metaint to be kept")
+	}
+}
+
+func TestManager_StatePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	os.WriteFile(path, []byte(`{"stations": {"fip": {"metadata": "StreamTitle='Saved';"}, "kexp": {"metadata": "StreamTitle='Old';"}}}`), 0o644)
+
+	cfg := toneStations("fip", "kexp")
+	cfg.Stations[1].Metadata = config.MetadataConfig{} // never fetches
+	cfg.State.Path = path
+
+	mgr, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	fip := mgr.Get("fip")
+	if got := fip.CurrentMetadata(); got != "StreamTitle='Saved';" || !fip.Provisional() {
+		t.Errorf("expected restored provisional title, got %q", got)
+	}
+
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for fip.Provisional() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	live := fip.CurrentMetadata()
+	mgr.Shutdown()
+
+	entries, err := state.New(path).Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if entries["fip"].Metadata != live {
+		t.Errorf("expected saved live title %q, got %q", live, entries["fip"].Metadata)
+	}
+	if entries["kexp"].Metadata != "StreamTitle='Old';" {
+		t.Errorf("expected unfetched station to keep its restored title, got %q", entries["kexp"].Metadata)
+	}
+}
+
+func TestManager_ReloadRestoresState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	os.WriteFile(path, []byte(`{"stations": {"kexp": {"metadata": "StreamTitle='Old';"}}}`), 0o644)
+
+	cfg := toneStations("fip")
+	cfg.State.Path = path
+	mgr, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	defer mgr.Shutdown()
+
+	cfg = toneStations("fip", "kexp")
+	cfg.Stations[1].Metadata = config.MetadataConfig{} // never fetches
+	cfg.State.Path = path
+	if err := mgr.Reload(cfg); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	kexp := mgr.Get("kexp")
+	if got := kexp.CurrentMetadata(); got != "StreamTitle='Old';" || !kexp.Provisional() {
+		t.Errorf("expected added station to restore its saved title, got %q", got)
+	}
+
+	mgr.saveState()
+	entries, _ := state.New(path).Load()
+	if entries["kexp"].Metadata != "StreamTitle='Old';" {
+		t.Errorf("expected unfetched added station to keep its saved title, got %q", entries["kexp"].Metadata)
+	}
+}
+
+func TestManager_CorruptStateIgnored(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	os.WriteFile(path, []byte("garbage"), 0o644)
+
+	cfg := toneStations("fip")
+	cfg.State.Path = path
+	mgr, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("a corrupt state file must not prevent startup: %v", err)
+	}
+	if mgr.Get("fip").CurrentMetadata() != "" {
+		t.Error("expected a blank title without usable state")
+	}
+}
+
+func TestManager_Variants(t *testing.T) {
+	cfg := toneStations("main")
+	cfg.Stations[0].ICY.Name = "Main"
+	cfg.Stations[0].Variants = map[string]config.VariantConfig{
+		"low": {
+			Source: config.SourceConfig{Type: "tone"},
+			ICY:    &config.ICYConfig{BitrateHintKbps: 64},
+		},
+		"same": {Source: config.SourceConfig{Type: "tone"}},
+	}
+
+	mgr, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer mgr.Shutdown()
+
+	st := mgr.Get("main")
+	low := st.Variant("low")
+	if low == nil {
+		t.Fatal("expected a low variant")
+	}
+	if low.ID() != "main/low" || low.ICYName() != "Main" || low.BitrateHint() != 64 {
+		t.Errorf("unexpected variant id=%q name=%q bitrate=%d", low.ID(), low.ICYName(), low.BitrateHint())
+	}
+	if same := st.Variant("same"); same.BitrateHint() != 32 {
+		t.Errorf("expected variant without icy to keep the station's, got bitrate %d", same.BitrateHint())
+	}
+	if mgr.Get("main/low") != nil || len(mgr.List()) != 1 {
+		t.Error("variants shouldn't be listed as stations of their own")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for (st.Provisional() || low.CurrentMetadata() != st.CurrentMetadata()) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if meta := low.CurrentMetadata(); meta == "" || meta != st.CurrentMetadata() {
+		t.Errorf("expected variant metadata %q to match the station's %q", meta, st.CurrentMetadata())
+	}
+
+	for _, name := range []string{"", "a/b", "x y"} {
+		bad := toneStations("main")
+		bad.Stations[0].Variants = map[string]config.VariantConfig{name: {Source: config.SourceConfig{Type: "tone"}}}
+		if _, err := NewFromConfig(bad); err == nil {
+			t.Errorf("expected variant name %q to be rejected", name)
+		}
+	}
+}
+
+func TestManager_MetadataCoalesce(t *testing.T) {
+	cfg := toneStations("fip")
+	cfg.Server.MetadataCoalesceMs = -1
+	if _, err := NewFromConfig(cfg); err == nil {
+		t.Error("expected negative metadata_coalesce_ms to be rejected")
+	}
+
+	cfg.Server.MetadataCoalesceMs = 2000
+	mgr, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	if mgr.coalescer == nil {
+		t.Error("expected a shared metadata coalescer")
+	}
+}
+
+func TestManager_DumpStats(t *testing.T) {
+	cfg := toneStations("fip", "kexp")
+	cfg.Stations[0].Variants = map[string]config.VariantConfig{"low": {Source: config.SourceConfig{Type: "tone"}}}
+	mgr, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	mgr.Start()
+	defer mgr.Shutdown()
+
+	st := mgr.Get("fip")
+	client := &station.Client{}
+	chunks := st.Subscribe(client)
+	defer st.Unsubscribe(client)
+	select {
+	case <-chunks:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected audio from the tone source")
+	}
+
+	var buf strings.Builder
+	if err := mgr.DumpStats(&buf); err != nil {
+		t.Fatalf("DumpStats failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected a summary and three station lines, got:\n%s", buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "stations=2 ") {
+		t.Errorf("unexpected summary %q", lines[0])
+	}
+	for _, want := range []string{"station=fip ", "clients=1", "source=up", "connects=1", "reconnects=0", "metadata="} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("expected %q in %q", want, lines[1])
+		}
+	}
+	if strings.Contains(lines[1], "bytes_served=0 ") {
+		t.Errorf("expected bytes served to count delivered audio: %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "station=fip/low ") || !strings.HasPrefix(lines[3], "station=kexp ") {
+		t.Errorf("expected variant after its station, then the next station:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,56 @@
+// ABOUTME: Plain-text snapshot of every station for on-box diagnostics
+// ABOUTME: Written to the log on SIGUSR1 when the HTTP API isn't reachable
+package manager
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/harper/radio-metadata-proxy/internal/domain/station"
+)
+
+// DumpStats writes a summary line followed by one key=value line per
+// station and variant: listeners, source health and reconnects, bytes
+// served and the current metadata
+func (m *Manager) DumpStats(w io.Writer) error {
+	stations := m.List()
+	active, maxConns := m.Connections()
+	if _, err := fmt.Fprintf(w, "stations=%d connections=%d max_connections=%d draining=%t\n",
+		len(stations), active, maxConns, m.Draining()); err != nil {
+		return err
+	}
+
+	for _, st := range stations {
+		if err := dumpStation(w, st); err != nil {
+			return err
+		}
+		for _, name := range st.VariantNames() {
+			if err := dumpStation(w, st.Variant(name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func dumpStation(w io.Writer, st *station.Station) error {
+	source := "down"
+	if st.SourceHealthy() {
+		source = "up"
+	}
+	connects := st.SourceConnects()
+	lastChange := "never"
+	if t := st.LastMetadataChange(); t != nil {
+		lastChange = t.UTC().Format(time.RFC3339)
+	}
+	var sourceErr string
+	if e := st.SourceError(); e != "" {
+		sourceErr = fmt.Sprintf(" source_error=%q", e)
+	}
+
+	_, err := fmt.Fprintf(w, "station=%s clients=%d peak=%d source=%s connects=%d reconnects=%d bytes_served=%d metadata=%q metadata_changes=%d last_change=%s%s\n",
+		st.ID(), st.ClientCount(), st.PeakClientCount(), source, connects, max(connects-1, 0),
+		st.BytesServed(), st.CurrentMetadata(), st.MetadataChangeCount(), lastChange, sourceErr)
+	return err
+}
@@ -12,7 +12,62 @@ type StreamSource interface {
 	Connect(ctx context.Context) (io.ReadCloser, error)
 }
 
+// MirroredSource is a StreamSource that reports which upstream its latest
+// connection used, by position in the configured mirrors (-1 before the
+// first attempt) and by URL with credentials redacted
+type MirroredSource interface {
+	StreamSource
+	ActiveMirror() int
+	ActiveURL() string
+}
+
+// StatusSource is a StreamSource that reports the HTTP status of its
+// latest connection attempt (0 when there was no response)
+type StatusSource interface {
+	StreamSource
+	LastStatus() int
+}
+
 // MetadataProvider fetches current track metadata
 type MetadataProvider interface {
 	Fetch(ctx context.Context) (string, error)
 }
+
+// MetadataDebug is the outcome of a one-off metadata fetch, including the
+// raw upstream response, for troubleshooting templates
+type MetadataDebug struct {
+	Status        int               // upstream HTTP status, 0 if not HTTP or no response
+	Body          string            // raw upstream body, cut at BodyTruncated
+	BodyTruncated bool              // Body is a prefix of the full response
+	Fields        map[string]string // placeholder values extracted from the body
+	Metadata      string            // final formatted ICY string
+	Error         string
+}
+
+// MetadataDebugger is a MetadataProvider that can report a fetch in detail
+type MetadataDebugger interface {
+	MetadataProvider
+	FetchDebug(ctx context.Context) MetadataDebug
+}
+
+// Transcoder re-encodes a station's audio stream to a target bitrate
+type Transcoder interface {
+	Transcode(ctx context.Context, in io.Reader, bitrateKbps int) (io.ReadCloser, error)
+}
+
+// StructuredMetadataProvider is a MetadataProvider that also exposes the
+// fields behind the formatted string so it can be re-rendered on demand
+type StructuredMetadataProvider interface {
+	MetadataProvider
+	FetchFields(ctx context.Context) (string, map[string]string, error)
+	Render(format string, fields map[string]string) string
+}
+
+// StationObserver is notified of station source health transitions and of
+// metadata going stale. Implementations must return quickly; the station
+// calls them inline.
+type StationObserver interface {
+	OnSourceUp(stationID string)
+	OnSourceDown(stationID string)
+	OnMetadataStale(stationID string)
+}
@@ -0,0 +1,123 @@
+// ABOUTME: Scheduled title overrides such as overnight "quiet hours", and on-air hours
+// ABOUTME: Replaces the feed's metadata with a fixed title during time ranges
+package station
+
+import (
+	"slices"
+	"time"
+
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/icy"
+)
+
+// ScheduledTitle shows Title instead of the feed's metadata between Start
+// and End, measured from local midnight. An End at or before Start wraps
+// past midnight; Days lists the weekdays a range may start on (empty = all).
+type ScheduledTitle struct {
+	Title string
+	Start time.Duration
+	End   time.Duration
+	Days  []time.Weekday
+
+	meta  string // pre-encoded in New
+	block []byte
+}
+
+// Schedule is a station's set of title overrides in one time zone
+type Schedule struct {
+	Location *time.Location // defaults to UTC
+	Titles   []ScheduledTitle
+}
+
+// active returns the first override in effect at now, or nil
+func (sc *Schedule) active(now time.Time) *ScheduledTitle {
+	if sc == nil || len(sc.Titles) == 0 {
+		return nil
+	}
+
+	t := inLocation(now, sc.Location)
+	for i := range sc.Titles {
+		st := &sc.Titles[i]
+		if inRange(t, st.Start, st.End, st.Days) {
+			return st
+		}
+	}
+	return nil
+}
+
+// Hours is a set of daily time ranges in one time zone, such as the hours a
+// station is on air
+type Hours struct {
+	Location *time.Location // defaults to UTC
+	Ranges   []TimeRange
+}
+
+// TimeRange runs from Start to End, measured from local midnight, wrapping
+// past midnight like a ScheduledTitle; Days lists the weekdays it may start
+// on (empty = all)
+type TimeRange struct {
+	Start time.Duration
+	End   time.Duration
+	Days  []time.Weekday
+}
+
+// Contains reports whether now falls in one of the ranges. Nil hours, or
+// hours without ranges, contain every time.
+func (h *Hours) Contains(now time.Time) bool {
+	if h == nil || len(h.Ranges) == 0 {
+		return true
+	}
+
+	t := inLocation(now, h.Location)
+	for _, r := range h.Ranges {
+		if inRange(t, r.Start, r.End, r.Days) {
+			return true
+		}
+	}
+	return false
+}
+
+// inLocation returns now in loc, or in UTC without one
+func inLocation(now time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return now.In(loc)
+}
+
+// inRange reports whether the local time t falls between start and end
+// after midnight, on a range that started on one of days
+func inRange(t time.Time, start, end time.Duration, days []time.Weekday) bool {
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+
+	day := t.Weekday()
+	switch {
+	case start < end:
+		if offset < start || offset >= end {
+			return false
+		}
+	case offset >= start:
+		// Evening part of a range that wraps past midnight
+	case offset < end:
+		// Morning part: the range started the previous day
+		day = (day + 6) % 7
+	default:
+		return false
+	}
+
+	return len(days) == 0 || slices.Contains(days, day)
+}
+
+// prepare copies the titles and pre-encodes their ICY metadata with build
+func (sc *Schedule) prepare(build func(meta string, fields map[string]string) []byte) *Schedule {
+	if sc == nil || len(sc.Titles) == 0 {
+		return nil
+	}
+
+	out := &Schedule{Location: sc.Location, Titles: slices.Clone(sc.Titles)}
+	for i := range out.Titles {
+		st := &out.Titles[i]
+		st.meta = icy.StreamTitle(st.Title)
+		st.block = build(st.meta, nil)
+	}
+	return out
+}
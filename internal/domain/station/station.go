@@ -4,28 +4,138 @@ package station
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"log"
+	"maps"
+	"math/rand/v2"
+	"net"
+	"runtime/debug"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/harper/radio-metadata-proxy/internal/domain"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/audio"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/expand"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/icy"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/metadata"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/metrics"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/redact"
 	"github.com/harper/radio-metadata-proxy/internal/infrastructure/ring"
 )
 
+// containerTypes carry their own framing, so interleaving ICY metadata
+// blocks would corrupt them
+var containerTypes = map[string]bool{
+	"audio/ogg":       true,
+	"application/ogg": true,
+	"audio/webm":      true,
+	"video/webm":      true,
+}
+
+// initialFetchAttempts bounds the startup metadata fetch; retries back off
+// from initialFetchBackoff, doubling each time
+var (
+	initialFetchAttempts = 4
+	initialFetchBackoff  = 500 * time.Millisecond
+)
+
+// clientChanCap is the number of chunks queued per client before drops
+const clientChanCap = 64
+
+// clientFillBuckets bound the per-client channel fill histogram (in chunks)
+var clientFillBuckets = []float64{0, 1, 2, 4, 8, 16, 32, 48, 63, 64}
+
 type Config struct {
 	ID             string
 	ICYName        string
+	ContentType    string // audio MIME type served to clients, defaults to audio/mpeg
+	SniffContent   bool   // detect the type from each connection's first bytes, falling back to ContentType
+	ICYPassthrough bool   // never interleave ICY metadata (implied for Ogg/WebM)
 	MetaInt        int
+	InitialMeta    bool        // send a metadata block before any audio
+	ICYFields      []icy.Field // extra fields sent after StreamTitle; values may use {field} placeholders
 	BitrateHint    int
 	PollInterval   time.Duration
 	RingBufferSize int
 	ChunkBusCap    int
+	FanoutWorkers  int               // goroutines delivering each chunk to large client sets (<= 1 = inline)
+	Formats        map[string]string // named alternate metadata templates
+	DefaultTitle   string            // seeds metadata before the first successful fetch
+	UnhealthyTitle string            // reported instead of the last title while the source is down
+
+	DownPollInterval time.Duration // metadata poll interval while the source is down (0 = unchanged)
+	Schedule         *Schedule     // scheduled title overrides, e.g. quiet hours (optional)
+
+	// StaleAfter flags metadata that hasn't changed this long while the
+	// source is up, notifying Observer once per stale spell (0 = off)
+	StaleAfter time.Duration
+	OnAirHours *Hours // limits stale alerts to these hours (nil = always)
+
+	Providers []Provider // extra metadata providers merged into the primary's fields
+
+	ReconnectInitial time.Duration // first reconnect delay, doubled up to ReconnectMax
+	ReconnectMax     time.Duration
+	RejectedBackoff  time.Duration // reconnect delay after a permanent upstream error such as 404
+	ReconnectJitter  float64       // fraction of each reconnect delay randomized away: 0 off, 0.5 equal, 1 full jitter
+	StallTimeout     time.Duration // restart the reader after this long without audio (0 = off)
+	PaceKbps         int           // throttle source reads to this bitrate (0 = unpaced)
+	BurstBytes       int           // recent audio sent to new clients before live data (0 = off)
+	ClearOnReconnect bool          // drop buffered audio when the source reconnects
+
+	Observer domain.StationObserver // notified on source up/down transitions (optional)
+	Levels   *audio.LevelMeter      // estimates audio levels from the source (optional)
+	Bitrate  *audio.BitrateMeter    // detects the source bitrate from frame headers (optional)
+	OggTags  *audio.OggTags         // collects in-band Vorbis comments for metadata (optional)
+
+	// Variants carry the same programme from other upstreams, by name. They
+	// start and stop with this station and mirror its metadata, so they
+	// should have no provider of their own.
+	Variants map[string]*Station
+}
+
+// Provider is an additional metadata source polled on its own interval.
+// Its fields fill any the primary provider left empty; the ICY title
+// still comes from the primary.
+type Provider struct {
+	Name         string
+	Source       domain.MetadataProvider
+	PollInterval time.Duration // defaults to the station PollInterval
+}
+
+// ProviderStatus describes a metadata provider for the admin view
+type ProviderStatus struct {
+	Name         string
+	PollInterval time.Duration
+	LastFetchAt  *time.Time
+	LastError    string
+	Panics       int64 // fetches that panicked and were recovered
+}
+
+// providerState tracks one polled metadata provider
+type providerState struct {
+	name     string
+	source   domain.MetadataProvider
+	interval time.Duration
+	primary  bool
+	pollNow  chan chan bool // refresh requests, answered with the poll's outcome
+
+	fields      map[string]string // guarded by Station.fieldsMu
+	lastFetchAt atomic.Pointer[time.Time]
+	lastErr     atomic.Pointer[string]
+	panics      atomic.Int64
 }
 
 type Station struct {
 	id          string
 	icyName     string
+	contentType string
+	sniffed     atomic.Pointer[string] // detected type of the current connection, nil when unknown
+	sniff       bool
+	passthrough bool
 	metaInt     int
 	bitrateHint int
 
@@ -33,14 +143,65 @@ type Station struct {
 	metadata domain.MetadataProvider
 	buffer   *ring.Buffer
 
-	pollInterval time.Duration
+	pollInterval     time.Duration
+	downPollInterval time.Duration
+	formats          map[string]string
+	providers        []*providerState
+	fieldsMu         sync.Mutex
 
-	currentMeta   atomic.Pointer[string]
-	lastMetaAt    atomic.Pointer[time.Time]
-	sourceHealthy atomic.Bool
+	reconnectInitial time.Duration
+	reconnectMax     time.Duration
+	rejectedBackoff  time.Duration
+	reconnectJitter  float64
+	stallTimeout     time.Duration
+	paceKbps         int
+	burstBytes       int
+	initialMeta      bool
+	icyFields        []icy.Field
+	clearOnReconnect bool
+	connects         atomic.Int64 // successful source connections
+	bytesServed      atomic.Int64 // audio bytes queued to clients by the fan-out
+	observer         domain.StationObserver
+	staleAfter       time.Duration
+	onAirHours       *Hours
+	onAirSince       time.Time // start of the current on-air spell; used only by the stale watch
+	metadataStale    atomic.Bool
+	healthySince     atomic.Pointer[time.Time]
+	levels           *audio.LevelMeter
+	bitrateMeter     *audio.BitrateMeter
+	oggTags          *audio.OggTags
+	variants         map[string]*Station
+	readerCancel     atomic.Pointer[context.CancelFunc]
+	wg               sync.WaitGroup                // goroutines started by Start
+	lastChunkAt      atomic.Int64                  // unix nanos of the last audio read
+	sourceErr        atomic.Pointer[sourceFailure] // latest connect error, nil once connected
 
-	clients   map[*Client]struct{}
-	clientsMu sync.Mutex
+	currentMeta atomic.Pointer[string]
+	metaBlock   atomic.Pointer[[]byte]
+
+	unhealthyMeta  string // empty when no when_unhealthy_title is configured
+	unhealthyBlock []byte
+	schedule       *Schedule
+	now            func() time.Time // clock for schedule checks, replaced in tests
+	currentFields  atomic.Pointer[map[string]string]
+	lastMetaAt     atomic.Pointer[time.Time]
+	metaChanges    atomic.Int64
+	lastChangeAt   atomic.Pointer[time.Time]
+	sourceHealthy  atomic.Bool
+	sourceFailed   atomic.Bool                   // a connect failed or a connection dropped since start
+	recovered      atomic.Pointer[chan struct{}] // closed and replaced when the source comes back up
+
+	clients       map[*Client]struct{}
+	internal      int // Internal clients in clients, left out of listener counts
+	clientsMu     sync.Mutex
+	stopped       bool          // set by Shutdown under clientsMu; later subscribers get a closed channel
+	sendMu        sync.RWMutex  // held for reading while delivering, so Unsubscribe never closes a channel mid-send
+	targets       []chan []byte // fan-out's reusable snapshot of client channels
+	fanoutWorkers int
+	clientFill    *metrics.Histogram
+	peakClients   atomic.Int64
+	dailyPeak     atomic.Int64 // peak for the UTC day in dailyPeakDay
+	dailyPeakDay  atomic.Int64 // days since the Unix epoch
 
 	chunkBus chan []byte
 
@@ -48,34 +209,132 @@ type Station struct {
 	cancel context.CancelFunc
 }
 
+// clientSeq numbers clients process-wide so IDs never repeat
+var clientSeq atomic.Uint64
+
 type Client struct {
-	ID string
-	ch chan []byte
+	ID          string // assigned by Subscribe when empty
+	Addr        string // remote address, used for unique listener counts
+	UserAgent   string
+	ConnectedAt time.Time
+	ICYMetadata bool // client sent Icy-MetaData: 1
+	MetaInt     int  // negotiated metadata interval, 0 when metadata is off
+	Internal    bool // an in-process consumer such as a recording, not a listener
+	ch          chan []byte
 }
 
 func New(cfg Config, source domain.StreamSource, metadata domain.MetadataProvider, buffer *ring.Buffer) *Station {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &Station{
+	s := &Station{
 		id:           cfg.ID,
 		icyName:      cfg.ICYName,
+		contentType:  cfg.ContentType,
+		sniff:        cfg.SniffContent,
+		passthrough:  cfg.ICYPassthrough,
 		metaInt:      cfg.MetaInt,
 		bitrateHint:  cfg.BitrateHint,
 		source:       source,
 		metadata:     metadata,
 		buffer:       buffer,
 		pollInterval: cfg.PollInterval,
-		clients:      make(map[*Client]struct{}),
-		chunkBus:     make(chan []byte, cfg.ChunkBusCap),
-		ctx:          ctx,
-		cancel:       cancel,
+		formats:      cfg.Formats,
+
+		downPollInterval: cfg.DownPollInterval,
+
+		reconnectInitial: cfg.ReconnectInitial,
+		reconnectMax:     cfg.ReconnectMax,
+		rejectedBackoff:  cfg.RejectedBackoff,
+		reconnectJitter:  min(max(cfg.ReconnectJitter, 0), 1),
+		stallTimeout:     cfg.StallTimeout,
+		paceKbps:         cfg.PaceKbps,
+		burstBytes:       cfg.BurstBytes,
+		initialMeta:      cfg.InitialMeta,
+		icyFields:        cfg.ICYFields,
+		clearOnReconnect: cfg.ClearOnReconnect,
+		observer:         cfg.Observer,
+		staleAfter:       cfg.StaleAfter,
+		onAirHours:       cfg.OnAirHours,
+		levels:           cfg.Levels,
+		bitrateMeter:     cfg.Bitrate,
+		oggTags:          cfg.OggTags,
+		variants:         cfg.Variants,
+		now:              time.Now,
+
+		clients:       make(map[*Client]struct{}),
+		fanoutWorkers: cfg.FanoutWorkers,
+		clientFill:    metrics.NewHistogram(clientFillBuckets),
+		chunkBus:      make(chan []byte, cfg.ChunkBusCap),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+
+	if s.contentType == "" {
+		s.contentType = "audio/mpeg"
+	}
+	if s.reconnectInitial <= 0 {
+		s.reconnectInitial = time.Second
+	}
+	if s.reconnectMax <= 0 {
+		s.reconnectMax = 30 * time.Second
+	}
+	if s.reconnectMax < s.reconnectInitial {
+		s.reconnectMax = s.reconnectInitial
 	}
+	if s.rejectedBackoff <= 0 {
+		s.rejectedBackoff = 5 * time.Minute
+	}
+	recovered := make(chan struct{})
+	s.recovered.Store(&recovered)
+
+	if metadata != nil {
+		s.providers = append(s.providers, &providerState{
+			name:     "primary",
+			source:   metadata,
+			interval: cfg.PollInterval,
+			primary:  true,
+			pollNow:  make(chan chan bool),
+		})
+	}
+	for _, p := range cfg.Providers {
+		interval := p.PollInterval
+		if interval <= 0 {
+			interval = cfg.PollInterval
+		}
+		s.providers = append(s.providers, &providerState{
+			name:     p.Name,
+			source:   p.Source,
+			interval: interval,
+			pollNow:  make(chan chan bool),
+		})
+	}
+
+	if cfg.UnhealthyTitle != "" {
+		s.unhealthyMeta = icy.StreamTitle(cfg.UnhealthyTitle)
+		s.unhealthyBlock = s.buildBlock(s.unhealthyMeta, nil)
+	}
+	s.schedule = cfg.Schedule.prepare(s.buildBlock)
+
+	// Seed a provisional title so clients never see a blank state at startup
+	if cfg.DefaultTitle != "" {
+		s.setMetadata(icy.StreamTitle(cfg.DefaultTitle))
+	} else {
+		s.setMetadata("")
+	}
+
+	return s
 }
 
 func (s *Station) ID() string {
 	return s.id
 }
 
+// CurrentMetadata returns the ICY metadata clients should see, which is the
+// unhealthy title while the source is down or a scheduled title if one applies
 func (s *Station) CurrentMetadata() string {
+	if meta, _, ok := s.override(); ok {
+		return meta
+	}
+
 	p := s.currentMeta.Load()
 	if p == nil {
 		return ""
@@ -84,9 +343,180 @@ func (s *Station) CurrentMetadata() string {
 }
 
 func (s *Station) UpdateMetadata(meta string) {
-	s.currentMeta.Store(&meta)
+	prev := s.currentMeta.Load()
+	s.setMetadata(meta)
 	now := time.Now()
 	s.lastMetaAt.Store(&now)
+
+	if prev == nil || *prev != meta {
+		s.metaChanges.Add(1)
+		s.lastChangeAt.Store(&now)
+	}
+}
+
+// MetadataChangeCount returns how many times fetched metadata has differed
+// from the previous value since start
+func (s *Station) MetadataChangeCount() int64 {
+	return s.metaChanges.Load()
+}
+
+// LastMetadataChange returns when metadata last changed, as opposed to
+// LastMetadataUpdate which moves on every successful poll
+func (s *Station) LastMetadataChange() *time.Time {
+	return s.lastChangeAt.Load()
+}
+
+// CurrentMetadataBlock returns the ICY-encoded block for the current
+// metadata. The block is rebuilt once per change in UpdateMetadata and shared
+// by all clients, so callers must not modify it.
+func (s *Station) CurrentMetadataBlock() []byte {
+	if _, block, ok := s.override(); ok {
+		return block
+	}
+	return *s.metaBlock.Load()
+}
+
+// override returns metadata that replaces the feed's: the unhealthy title
+// while the source is down after failing, else any scheduled title in effect
+func (s *Station) override() (meta string, block []byte, ok bool) {
+	if s.unhealthyMeta != "" && !s.sourceHealthy.Load() && s.sourceFailed.Load() {
+		return s.unhealthyMeta, s.unhealthyBlock, true
+	}
+	if st := s.schedule.active(s.now()); st != nil {
+		return st.meta, st.block, true
+	}
+	return "", nil, false
+}
+
+// setMetadata stores meta and swaps in its pre-encoded ICY block
+func (s *Station) setMetadata(meta string) {
+	text := meta
+	if text == "" {
+		// Always send a title at intervals (ICY spec requires a block)
+		text = "StreamTitle='';"
+	}
+	var fields map[string]string
+	if p := s.currentFields.Load(); p != nil {
+		fields = *p
+	}
+	block := s.buildBlock(text, fields)
+
+	s.metaBlock.Store(&block)
+	s.currentMeta.Store(&meta)
+}
+
+// buildBlock encodes meta followed by the configured extra ICY fields, with
+// {field} placeholders in their values filled from fields. Without fields,
+// values that still hold a placeholder are left out rather than sent raw.
+func (s *Station) buildBlock(meta string, fields map[string]string) []byte {
+	if len(s.icyFields) == 0 {
+		return icy.BuildBlock(meta)
+	}
+	extra := make([]icy.Field, 0, len(s.icyFields))
+	for _, f := range s.icyFields {
+		// Without fields a placeholder would go out raw, so leave the field
+		// out; literal braces such as "Live {Uncut}" are kept
+		if fields == nil && slices.ContainsFunc(expand.Tokens(f.Value), metadata.KnownPlaceholder) {
+			continue
+		}
+		value := expand.String(f.Value, fields)
+		extra = append(extra, icy.Field{Name: f.Name, Value: value})
+	}
+	return icy.BuildBlock(icy.WithFields(meta, extra))
+}
+
+// UpdateMetadataFields stores meta together with the structured fields it was built from
+func (s *Station) UpdateMetadataFields(meta string, fields map[string]string) {
+	s.currentFields.Store(&fields)
+	s.UpdateMetadata(meta)
+}
+
+// Fields returns the structured fields behind the current metadata, if known
+func (s *Station) Fields() map[string]string {
+	p := s.currentFields.Load()
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+// RenderMetadata renders the current fields with the named format template.
+// An empty name returns the primary metadata; ok is false for unknown names.
+func (s *Station) RenderMetadata(name string) (meta string, ok bool) {
+	if name == "" {
+		return s.CurrentMetadata(), true
+	}
+
+	format, ok := s.formats[name]
+	if !ok {
+		return "", false
+	}
+
+	if meta, _, ok := s.override(); ok {
+		return meta, true
+	}
+
+	sp, structured := s.metadata.(domain.StructuredMetadataProvider)
+	fields := s.Fields()
+	if !structured || fields == nil {
+		return "", true
+	}
+
+	return sp.Render(format, fields), true
+}
+
+// Provisional reports whether metadata has yet to be fetched from the
+// upstream; until then CurrentMetadata returns the seeded default title.
+func (s *Station) Provisional() bool {
+	return s.lastMetaAt.Load() == nil
+}
+
+// Restore seeds metadata saved before a restart. It stays provisional until
+// the first fetch, and is ignored once metadata has been fetched.
+func (s *Station) Restore(meta string, fields map[string]string) {
+	if !s.Provisional() || meta == "" {
+		return
+	}
+	if fields != nil {
+		s.currentFields.Store(&fields)
+	}
+	s.setMetadata(meta)
+
+	for _, v := range s.variants {
+		v.Restore(meta, fields)
+	}
+}
+
+// LastKnownMetadata returns the latest fetched metadata and its fields,
+// ignoring unhealthy and scheduled titles, or false while still provisional
+func (s *Station) LastKnownMetadata() (string, map[string]string, bool) {
+	if s.Provisional() {
+		return "", nil, false
+	}
+	return *s.currentMeta.Load(), s.Fields(), true
+}
+
+// Variant returns the named variant, or nil if the station has none by that name
+func (s *Station) Variant(name string) *Station {
+	return s.variants[name]
+}
+
+// VariantNames returns the station's variant names, sorted
+func (s *Station) VariantNames() []string {
+	return slices.Sorted(maps.Keys(s.variants))
+}
+
+// syncVariants copies the current metadata to every variant. Callers hold fieldsMu.
+func (s *Station) syncVariants() {
+	meta := *s.currentMeta.Load()
+	fields := s.Fields()
+	for _, v := range s.variants {
+		if fields != nil {
+			v.UpdateMetadataFields(meta, fields)
+		} else {
+			v.UpdateMetadata(meta)
+		}
+	}
 }
 
 func (s *Station) LastMetadataUpdate() *time.Time {
@@ -95,105 +525,646 @@ func (s *Station) LastMetadataUpdate() *time.Time {
 
 func (s *Station) AddClient(c *Client) {
 	s.clientsMu.Lock()
-	s.clients[c] = struct{}{}
+	s.addLocked(c)
 	s.clientsMu.Unlock()
 }
 
 func (s *Station) RemoveClient(c *Client) {
 	s.clientsMu.Lock()
-	delete(s.clients, c)
+	s.removeLocked(c)
 	s.clientsMu.Unlock()
 }
 
+// ClientCount returns the number of connected listeners
 func (s *Station) ClientCount() int {
 	s.clientsMu.Lock()
 	defer s.clientsMu.Unlock()
-	return len(s.clients)
+	return s.listenersLocked()
+}
+
+func (s *Station) addLocked(c *Client) {
+	if _, ok := s.clients[c]; !ok && c.Internal {
+		s.internal++
+	}
+	s.clients[c] = struct{}{}
+}
+
+func (s *Station) removeLocked(c *Client) {
+	if _, ok := s.clients[c]; ok && c.Internal {
+		s.internal--
+	}
+	delete(s.clients, c)
+}
+
+func (s *Station) listenersLocked() int {
+	return len(s.clients) - s.internal
 }
 
 func (s *Station) ICYName() string {
 	return s.icyName
 }
 
+// ContentType returns the type sniffed from the current connection, if
+// any, else the configured one
+func (s *Station) ContentType() string {
+	if p := s.sniffed.Load(); p != nil {
+		return *p
+	}
+	return s.contentType
+}
+
+// InjectsICY reports whether clients may request interleaved ICY metadata.
+// Container formats are relayed untouched; their metadata is only
+// available out-of-band via /meta.
+func (s *Station) InjectsICY() bool {
+	return !s.passthrough && !containerTypes[s.ContentType()]
+}
+
+// sniffContentType detects the codec from a connection's first chunk. The
+// configured type is kept when the bytes are inconclusive or the same codec
+// family: ADTS framing can't tell HE-AAC (audio/aacp) from AAC-LC.
+func (s *Station) sniffContentType(chunk []byte) {
+	detected := audio.SniffContentType(chunk)
+	if detected == "" || audio.Family(detected) == audio.Family(s.contentType) {
+		s.sniffed.Store(nil)
+		return
+	}
+	prev := s.sniffed.Swap(&detected)
+	if detected != s.contentType && (prev == nil || *prev != detected) {
+		log.Printf("station %s: source sends %s, not the configured %s", s.id, detected, s.contentType)
+	}
+}
+
 func (s *Station) MetaInt() int {
 	return s.metaInt
 }
 
+// SourceMirror returns the position of the upstream used by the latest
+// connection attempt in the configured mirrors, or -1 before the first
+// attempt and for sources without mirrors
+func (s *Station) SourceMirror() int {
+	if m, ok := s.source.(domain.MirroredSource); ok {
+		return m.ActiveMirror()
+	}
+	return -1
+}
+
+// SourceURL returns the upstream URL of the latest connection attempt with
+// credentials redacted, or "" for sources that don't report one. Paths can
+// still be sensitive, so it is meant for admin views only.
+func (s *Station) SourceURL() string {
+	if m, ok := s.source.(domain.MirroredSource); ok {
+		return m.ActiveURL()
+	}
+	return ""
+}
+
+// SourceStatus returns the HTTP status of the latest upstream response, or
+// 0 when there was none or the source isn't HTTP
+func (s *Station) SourceStatus() int {
+	if src, ok := s.source.(domain.StatusSource); ok {
+		return src.LastStatus()
+	}
+	return 0
+}
+
+// sourceFailure is why the latest source connection failed
+type sourceFailure struct {
+	kind   string // short and free of URLs and addresses, e.g. "http 503"
+	detail string // the full error with URL credentials redacted
+}
+
+// categorizedError is implemented by source errors that can name their kind
+// without revealing URLs, credentials or addresses
+type categorizedError interface {
+	Category() string
+}
+
+func (s *Station) setSourceFailure(kind, detail string) {
+	s.sourceErr.Store(&sourceFailure{kind: kind, detail: detail})
+}
+
+// connectFailed records err as the reason the source isn't connected
+func (s *Station) connectFailed(err error) {
+	kind := "connect failed"
+	var cat categorizedError
+	if errors.As(err, &cat) {
+		kind = cat.Category()
+	}
+	s.setSourceFailure(kind, redact.Error(err))
+}
+
+// SourceError returns why the latest connection attempt failed, such as a
+// token refresh error, with URL credentials redacted, or "" once the source
+// is connected. It can still name internal hosts, so it is meant for admin
+// views; SourceErrorKind is the public summary.
+func (s *Station) SourceError() string {
+	if f := s.sourceErr.Load(); f != nil {
+		return f.detail
+	}
+	return ""
+}
+
+// SourceErrorKind returns a short category for SourceError that is safe to
+// show publicly, such as "connect failed", "http 503" or "stalled"
+func (s *Station) SourceErrorKind() string {
+	if f := s.sourceErr.Load(); f != nil {
+		return f.kind
+	}
+	return ""
+}
+
 func (s *Station) BitrateHint() int {
 	return s.bitrateHint
 }
 
+// DetectedBitrate returns the bitrate measured from the source's frame
+// headers, or 0 when detection is off or nothing has been measured yet
+func (s *Station) DetectedBitrate() int {
+	if s.bitrateMeter == nil {
+		return 0
+	}
+	return s.bitrateMeter.Kbps()
+}
+
+// Bitrate returns the bitrate to advertise: detected if known, else the hint
+func (s *Station) Bitrate() int {
+	if kbps := s.DetectedBitrate(); kbps > 0 {
+		return kbps
+	}
+	return s.bitrateHint
+}
+
+// SendsInitialMeta reports whether metadata clients get a block before any audio
+func (s *Station) SendsInitialMeta() bool {
+	return s.initialMeta
+}
+
+// BurstBytes returns how much recent audio new clients receive up front
+func (s *Station) BurstBytes() int {
+	return s.burstBytes
+}
+
+// Levels returns the latest audio level estimate; ok is false when level
+// metering is off or no full window has been measured yet
+func (s *Station) Levels() (lvl audio.Level, window time.Duration, ok bool) {
+	if s.levels == nil {
+		return audio.Level{}, 0, false
+	}
+	lvl, ok = s.levels.Level()
+	return lvl, s.levels.Window(), ok
+}
+
+// LevelsEnabled reports whether level metering is configured
+func (s *Station) LevelsEnabled() bool {
+	return s.levels != nil
+}
+
 func (s *Station) SourceHealthy() bool {
 	return s.sourceHealthy.Load()
 }
 
+// SetSourceHealthy records source health and notifies the observer when it
+// changes, so the first connect, every drop and every recovery fire once
 func (s *Station) SetSourceHealthy(healthy bool) {
-	s.sourceHealthy.Store(healthy)
+	if !healthy {
+		s.sourceFailed.Store(true)
+	}
+	if s.sourceHealthy.Swap(healthy) == healthy {
+		return
+	}
+
+	// Wake metadata pollers that slowed down during the outage
+	if healthy {
+		now := time.Now()
+		s.healthySince.Store(&now)
+
+		next := make(chan struct{})
+		if prev := s.recovered.Swap(&next); prev != nil {
+			close(*prev)
+		}
+	}
+
+	if s.observer == nil {
+		return
+	}
+
+	if healthy {
+		s.observer.OnSourceUp(s.id)
+	} else {
+		s.observer.OnSourceDown(s.id)
+	}
 }
 
+// Clients returns a snapshot of the connected listeners
+func (s *Station) Clients() []Client {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+
+	result := make([]Client, 0, s.listenersLocked())
+	for c := range s.clients {
+		if c.Internal {
+			continue
+		}
+		info := *c
+		info.ch = nil
+		result = append(result, info)
+	}
+	return result
+}
+
+// SubscribeBurst subscribes c and returns up to maxBytes of the most recent
+// audio to send first, so players fill their buffers without waiting for
+// live data. The burst ends exactly where the channel's chunks begin.
+func (s *Station) SubscribeBurst(c *Client, maxBytes int) (burst []byte, chunks <-chan []byte) {
+	// Only the stream position is taken with the subscribe; the copy happens
+	// after clientsMu is released so a large burst doesn't hold up fan-out
+	s.clientsMu.Lock()
+	var end int64
+	if s.buffer != nil {
+		end = s.buffer.Written()
+	}
+	chunks = s.subscribeLocked(c)
+	s.clientsMu.Unlock()
+
+	if maxBytes > 0 && s.buffer != nil {
+		burst = s.buffer.Tail(end, maxBytes)
+	}
+	return burst, chunks
+}
+
+// Subscribe registers c for chunk delivery and returns its channel.
+// Subscribing an already-subscribed client returns its existing channel.
 func (s *Station) Subscribe(c *Client) <-chan []byte {
-	c.ch = make(chan []byte, 64)
-	s.AddClient(c)
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	return s.subscribeLocked(c)
+}
+
+func (s *Station) subscribeLocked(c *Client) <-chan []byte {
+	if s.stopped {
+		// The station was removed between lookup and subscribe
+		ch := make(chan []byte)
+		close(ch)
+		return ch
+	}
+	if _, ok := s.clients[c]; ok && c.ch != nil {
+		return c.ch
+	}
+
+	if c.ID == "" {
+		c.ID = fmt.Sprintf("c%d", clientSeq.Add(1))
+	}
+
+	c.ch = make(chan []byte, clientChanCap)
+	s.addLocked(c)
+	s.recordPeak(int64(s.listenersLocked()))
 	return c.ch
 }
 
+// recordPeak raises the all-time and daily high-water marks of concurrent
+// clients to n. Callers hold clientsMu.
+func (s *Station) recordPeak(n int64) {
+	storeMax(&s.peakClients, n)
+
+	if today := utcDay(time.Now()); s.dailyPeakDay.Load() != today {
+		s.dailyPeakDay.Store(today)
+		s.dailyPeak.Store(n)
+		return
+	}
+	storeMax(&s.dailyPeak, n)
+}
+
+func storeMax(v *atomic.Int64, n int64) {
+	for {
+		cur := v.Load()
+		if n <= cur || v.CompareAndSwap(cur, n) {
+			return
+		}
+	}
+}
+
+func utcDay(t time.Time) int64 {
+	return t.Unix() / 86400
+}
+
+// PeakClientCount returns the highest concurrent client count since start
+// or the last ResetPeak
+func (s *Station) PeakClientCount() int {
+	return int(s.peakClients.Load())
+}
+
+// DailyPeakClientCount returns the highest concurrent client count during
+// the current UTC day
+func (s *Station) DailyPeakClientCount() int {
+	if s.dailyPeakDay.Load() != utcDay(time.Now()) {
+		// Nobody has connected yet today
+		return s.ClientCount()
+	}
+	return int(s.dailyPeak.Load())
+}
+
+// ResetPeak restarts the all-time high-water mark from the current client count
+func (s *Station) ResetPeak() {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	s.peakClients.Store(int64(s.listenersLocked()))
+}
+
+// UniqueClientCount returns the number of distinct client hosts connected
+func (s *Station) UniqueClientCount() int {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+
+	hosts := make(map[string]struct{}, len(s.clients))
+	for c := range s.clients {
+		if c.Internal {
+			continue
+		}
+		host, _, err := net.SplitHostPort(c.Addr)
+		if err != nil {
+			host = c.Addr
+		}
+		hosts[host] = struct{}{}
+	}
+	return len(hosts)
+}
+
+// Unsubscribe removes c and closes its channel. Safe to call more than once.
 func (s *Station) Unsubscribe(c *Client) {
-	s.RemoveClient(c)
-	if c.ch != nil {
-		close(c.ch)
-		c.ch = nil
+	s.clientsMu.Lock()
+	if _, ok := s.clients[c]; !ok {
+		s.clientsMu.Unlock()
+		return
 	}
+	s.removeLocked(c)
+	ch := c.ch
+	c.ch = nil
+	s.clientsMu.Unlock()
+
+	if ch != nil {
+		// An in-flight delivery may still hold ch in its snapshot
+		s.sendMu.Lock()
+		close(ch)
+		s.sendMu.Unlock()
+	}
+}
+
+// BytesServed returns the audio bytes the fan-out has queued to clients
+// since start, excluding connect bursts
+func (s *Station) BytesServed() int64 {
+	return s.bytesServed.Load()
+}
+
+// SourceConnects returns how many times the source has connected since start
+func (s *Station) SourceConnects() int64 {
+	return s.connects.Load()
+}
+
+// ClientFill returns the histogram of client channel fill sampled at fan-out.
+// High values indicate slow clients close to dropping chunks.
+func (s *Station) ClientFill() *metrics.Histogram {
+	return s.clientFill
 }
 
 func (s *Station) Start() error {
 	// Start source reader goroutine
-	go s.runSourceReader()
+	s.goTracked(s.runSourceReader)
 
-	// Start metadata poller goroutine
-	go s.runMetadataPoller()
+	// Start one poller per metadata provider, each on its own ticker;
+	// stations without a provider keep their default title
+	for _, p := range s.providers {
+		s.goTracked(func() { s.runMetadataPoller(p) })
+	}
 
 	// Start fan-out goroutine
-	go s.runFanOut()
+	s.goTracked(func() { s.supervise("fan-out", s.runFanOut) })
+
+	// Start stall watchdog
+	if s.stallTimeout > 0 {
+		s.goTracked(s.runWatchdog)
+	}
+
+	if s.staleAfter > 0 {
+		s.goTracked(s.runStaleWatch)
+	}
+
+	for _, v := range s.variants {
+		if err := v.Start(); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
+// goTracked runs fn in a goroutine that Shutdown waits for
+func (s *Station) goTracked(fn func()) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		fn()
+	}()
+}
+
+// Shutdown stops the station and returns once all of its goroutines have
+// exited. Subscribed clients have their channels closed so stream handlers
+// end, as when a reload removes the station mid-stream; their deferred
+// Unsubscribe calls are then no-ops.
 func (s *Station) Shutdown() error {
 	s.cancel()
+	s.wg.Wait()
+	s.disconnectClients()
+	for _, v := range s.variants {
+		v.Shutdown()
+	}
 	return nil
 }
 
+// disconnectClients unsubscribes every client and refuses new ones
+func (s *Station) disconnectClients() {
+	s.clientsMu.Lock()
+	s.stopped = true
+	channels := make([]chan []byte, 0, len(s.clients))
+	for c := range s.clients {
+		if c.ch != nil {
+			channels = append(channels, c.ch)
+			c.ch = nil
+		}
+	}
+	clear(s.clients)
+	s.internal = 0
+	s.clientsMu.Unlock()
+
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	for _, ch := range channels {
+		close(ch)
+	}
+}
+
+// supervise runs fn until it returns normally, restarting it after a panic
+func (s *Station) supervise(name string, fn func()) {
+	for s.runRecovered(name, fn) && s.ctx.Err() == nil {
+	}
+}
+
+// runRecovered runs fn, reporting whether it panicked
+func (s *Station) runRecovered(name string, fn func()) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("station %s: %s panic: %v\n%s", s.id, name, r, debug.Stack())
+			panicked = true
+		}
+	}()
+	fn()
+	return false
+}
+
+// runSourceReader keeps the source connected, reconnecting with exponential
+// backoff after errors, end of stream, panics, or watchdog restarts.
 func (s *Station) runSourceReader() {
-	stream, err := s.source.Connect(s.ctx)
+	backoff := s.reconnectInitial
+
+	for {
+		var streamed bool
+		var err error
+		if s.runRecovered("source reader", func() { streamed, err = s.readSource() }) {
+			s.SetSourceHealthy(false)
+		}
+
+		if streamed {
+			backoff = s.reconnectInitial
+		}
+
+		// A 404 or 403 won't fix itself in seconds, so wait much longer
+		// instead of hammering a dead URL
+		wait := backoff
+		var perm permanentError
+		if errors.As(err, &perm) && perm.Permanent() {
+			wait = s.rejectedBackoff
+			log.Printf("station %s: source rejected: %s, retrying in %s", s.id, redact.Error(err), wait)
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(s.jitter(wait)):
+		}
+
+		backoff *= 2
+		if backoff > s.reconnectMax {
+			backoff = s.reconnectMax
+		}
+	}
+}
+
+// jitter shortens d by a random part of reconnectJitter*d, so stations
+// sharing an upstream don't all reconnect at the same moment
+func (s *Station) jitter(d time.Duration) time.Duration {
+	if s.reconnectJitter <= 0 {
+		return d
+	}
+	return d - time.Duration(rand.Float64()*s.reconnectJitter*float64(d))
+}
+
+// permanentError is implemented by source errors that retrying soon won't fix
+type permanentError interface {
+	Permanent() bool
+}
+
+// readSource streams one source connection into the ring buffer and fan-out.
+// It reports whether any audio was received, and the error if the source
+// could not be connected.
+func (s *Station) readSource() (streamed bool, connectErr error) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	s.readerCancel.Store(&cancel)
+
+	stream, err := s.source.Connect(ctx)
 	if err != nil {
+		s.connectFailed(err)
 		s.SetSourceHealthy(false)
-		return
+		return false, err
 	}
 	defer stream.Close()
+	s.sourceErr.Store(nil)
+
+	// Not every stream unblocks a pending Read when ctx ends (a FIFO with no
+	// writer never does), so close it to let the reader exit. Shutdown
+	// waits on this goroutine, so a close already under way is waited for.
+	closed := make(chan struct{})
+	stopClose := context.AfterFunc(ctx, func() {
+		defer close(closed)
+		stream.Close()
+	})
+	defer func() {
+		if !stopClose() {
+			<-closed
+		}
+	}()
 
 	s.SetSourceHealthy(true)
+	s.markChunk()
+
+	// An empty chunk tells the fan-out to clear the ring; sending it through
+	// chunkBus keeps it ordered after audio from the previous connection
+	if s.connects.Add(1) > 1 && s.clearOnReconnect {
+		select {
+		case s.chunkBus <- nil:
+		case <-ctx.Done():
+			return false, nil
+		}
+	}
+
+	start := time.Now()
+	var sent int64
+	var emptyReads int
+	sniffPending := s.sniff
+	meters := s.metersFor(s.contentType)
 
 	buf := make([]byte, 8192)
 	for {
 		select {
-		case <-s.ctx.Done():
-			return
+		case <-ctx.Done():
+			return streamed, nil
 		default:
 		}
 
+		if !s.pace(ctx, start, sent) {
+			return streamed, nil
+		}
+
 		n, err := stream.Read(buf)
 		if n > 0 {
+			streamed = true
+			sent += int64(n)
+			s.markChunk()
+
 			chunk := make([]byte, n)
 			copy(chunk, buf[:n])
 
-			// Write to ring buffer
-			s.buffer.Write(chunk)
+			// Before fan-out, so clients connecting now get the right type
+			if sniffPending {
+				s.sniffContentType(chunk)
+				sniffPending = false
+				meters = s.metersFor(s.ContentType())
+			}
+			if meters.levels != nil {
+				meters.levels.Write(chunk)
+			}
+			if meters.bitrate != nil {
+				meters.bitrate.Write(chunk)
+			}
+			if meters.oggTags != nil {
+				meters.oggTags.Write(chunk)
+			}
 
 			// Send to fan-out
 			select {
 			case s.chunkBus <- chunk:
-			case <-s.ctx.Done():
-				return
+			case <-ctx.Done():
+				return streamed, nil
 			}
 		}
 
@@ -201,50 +1172,509 @@ func (s *Station) runSourceReader() {
 			if err != io.EOF {
 				s.SetSourceHealthy(false)
 			}
+			return streamed, nil
+		}
+
+		// Empty reads without an error are legal but make no progress; back
+		// off instead of spinning, and leave a source that never recovers to
+		// the watchdog's stall timeout
+		if n == 0 {
+			emptyReads++
+			if !sleepCtx(ctx, emptyReadBackoff(emptyReads)) {
+				return streamed, nil
+			}
+		} else {
+			emptyReads = 0
+		}
+	}
+}
+
+// meters are the stream analyzers fed from one source connection
+type streamMeters struct {
+	levels  *audio.LevelMeter
+	bitrate *audio.BitrateMeter
+	oggTags *audio.OggTags
+}
+
+// metersFor returns the configured analyzers that can read contentType.
+// The manager checks them against the configured type; a sniffed type of
+// another codec family can rule some out for the connection.
+func (s *Station) metersFor(contentType string) streamMeters {
+	m := streamMeters{levels: s.levels, bitrate: s.bitrateMeter, oggTags: s.oggTags}
+	family := audio.Family(contentType)
+	if m.levels != nil && family != "mpeg" {
+		log.Printf("station %s: levels need MP3, source sends %s; pausing them", s.id, contentType)
+		m.levels = nil
+	}
+	if m.bitrate != nil && family != "mpeg" && family != "aac" {
+		log.Printf("station %s: bitrate detection needs MP3 or AAC, source sends %s; pausing it", s.id, contentType)
+		m.bitrate = nil
+	}
+	if m.oggTags != nil && family != "ogg" {
+		log.Printf("station %s: ogg metadata needs an Ogg stream, source sends %s; pausing it", s.id, contentType)
+		m.oggTags = nil
+	}
+	return m
+}
+
+// emptyReadBackoff is the pause after the nth consecutive empty read: 1ms
+// doubling up to 100ms
+func emptyReadBackoff(n int) time.Duration {
+	return min(time.Millisecond<<min(n-1, 7), 100*time.Millisecond)
+}
+
+// sleepCtx waits for d, returning false if ctx ends first
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// pace blocks until sent bytes are due at the pacing bitrate, so sources
+// that deliver faster than real time play at their nominal rate. It returns
+// false if ctx ends first.
+func (s *Station) pace(ctx context.Context, start time.Time, sent int64) bool {
+	if s.paceKbps <= 0 {
+		return true
+	}
+
+	// In floating point: sent*8*1e9 overflows int64 after about 1.15 GB,
+	// which a looping file source reaches
+	due := start.Add(time.Duration(float64(sent*8) / float64(s.paceKbps*1000) * float64(time.Second)))
+	wait := time.Until(due)
+	if wait <= 0 {
+		return true
+	}
+	return sleepCtx(ctx, wait)
+}
+
+func (s *Station) markChunk() {
+	s.lastChunkAt.Store(time.Now().UnixNano())
+}
+
+// runWatchdog restarts the source reader when a healthy source has not
+// produced audio within the stall timeout
+func (s *Station) runWatchdog() {
+	ticker := time.NewTicker(s.stallTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
 			return
+		case <-ticker.C:
+			last := time.Unix(0, s.lastChunkAt.Load())
+			if s.SourceHealthy() && time.Since(last) > s.stallTimeout {
+				stalled := time.Since(last).Round(time.Second)
+				log.Printf("station %s: no audio for %s, restarting source reader", s.id, stalled)
+				s.setSourceFailure("stalled", fmt.Sprintf("no audio for %s", stalled))
+				s.SetSourceHealthy(false)
+				if cancel := s.readerCancel.Load(); cancel != nil {
+					(*cancel)()
+				}
+			}
 		}
 	}
 }
 
-func (s *Station) runMetadataPoller() {
-	ticker := time.NewTicker(s.pollInterval)
+// runStaleWatch periodically checks whether metadata has gone stale
+func (s *Station) runStaleWatch() {
+	ticker := time.NewTicker(min(s.staleAfter/4, 30*time.Second))
 	defer ticker.Stop()
 
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkStale(time.Now())
+		}
+	}
+}
+
+// checkStale flags metadata that hasn't changed for staleAfter since the
+// latest of its last change, the source coming up and the station going on
+// air, notifying the observer once until the metadata changes, the source
+// drops or the on-air hours end
+func (s *Station) checkStale(now time.Time) {
+	if !s.onAirHours.Contains(now) {
+		s.onAirSince = time.Time{}
+		s.metadataStale.Store(false)
+		return
+	}
+	if s.onAirSince.IsZero() {
+		s.onAirSince = now
+	}
+
+	since := s.healthySince.Load()
+	if !s.SourceHealthy() || since == nil {
+		s.metadataStale.Store(false)
+		return
+	}
+
+	last := *since
+	if changed := s.lastChangeAt.Load(); changed != nil && changed.After(last) {
+		last = *changed
+	}
+	// A title left over from the night before isn't stale the moment the
+	// station goes on air
+	if s.onAirHours != nil && s.onAirSince.After(last) {
+		last = s.onAirSince
+	}
+	if now.Sub(last) < s.staleAfter {
+		s.metadataStale.Store(false)
+		return
+	}
+
+	if !s.metadataStale.Swap(true) {
+		log.Printf("station %s: metadata unchanged for %s", s.id, now.Sub(last).Round(time.Second))
+		if s.observer != nil {
+			s.observer.OnMetadataStale(s.id)
+		}
+	}
+}
+
+// MetadataStale reports whether metadata has exceeded its stale_after_ms
+// while the source is up
+func (s *Station) MetadataStale() bool {
+	return s.metadataStale.Load()
+}
+
+func (s *Station) runMetadataPoller(p *providerState) {
 	// Poll immediately on start
-	if meta, err := s.metadata.Fetch(s.ctx); err == nil {
-		s.UpdateMetadata(meta)
+	s.initialPoll(p)
+
+	// Non-positive intervals mean "fetch once"; the poller then only
+	// answers refresh requests
+	if p.interval <= 0 {
+		log.Printf("station %s: metadata provider %s has no poll interval, polling disabled", s.id, p.name)
 	}
 
 	for {
+		var (
+			timer     *time.Timer
+			tick      <-chan time.Time
+			recovered <-chan struct{}
+		)
+		if p.interval > 0 {
+			wait := p.interval
+
+			// While the source is down, poll less often to spare the metadata
+			// API, and resume as soon as the source recovers. The channel is
+			// taken before checking health so a recovery in between isn't missed.
+			if s.downPollInterval > 0 {
+				ch := *s.recovered.Load()
+				if !s.SourceHealthy() {
+					wait = max(wait, s.downPollInterval)
+					recovered = ch
+				}
+			}
+
+			timer = time.NewTimer(wait)
+			tick = timer.C
+		}
+
+		var reply chan bool
 		select {
 		case <-s.ctx.Done():
+		case reply = <-p.pollNow:
+		case <-recovered:
+		case <-tick:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if s.ctx.Err() != nil {
 			return
-		case <-ticker.C:
-			if meta, err := s.metadata.Fetch(s.ctx); err == nil {
-				s.UpdateMetadata(meta)
+		}
+
+		ok := s.pollProvider(p)
+		if reply != nil {
+			reply <- ok
+		}
+	}
+}
+
+// ErrNoMetadata is returned by RefreshMetadata for stations without a provider
+var ErrNoMetadata = errors.New("station has no metadata provider")
+
+// RefreshMetadata has every provider's poller fetch now instead of waiting
+// for its next tick, and waits for the fetches to finish. It fails if the
+// station isn't running or any provider's fetch fails.
+func (s *Station) RefreshMetadata(ctx context.Context) error {
+	if len(s.providers) == 0 {
+		return ErrNoMetadata
+	}
+
+	replies := make([]chan bool, len(s.providers))
+	for i, p := range s.providers {
+		// Buffered so a poller never blocks on a caller that gave up
+		replies[i] = make(chan bool, 1)
+		select {
+		case p.pollNow <- replies[i]:
+		case <-s.ctx.Done():
+			return errors.New("station stopped")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	var errs []error
+	for i, p := range s.providers {
+		select {
+		case ok := <-replies[i]:
+			if !ok {
+				msg := "fetch failed"
+				if e := p.lastErr.Load(); e != nil {
+					msg = *e
+				}
+				errs = append(errs, fmt.Errorf("provider %s: %s", p.name, msg))
 			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// initialPoll fetches right away, retrying a few times with a short backoff
+// so an upstream that is briefly down at boot doesn't leave metadata empty
+// until the first tick
+func (s *Station) initialPoll(p *providerState) {
+	backoff := initialFetchBackoff
+	for attempt := 1; !s.pollProvider(p) && attempt < initialFetchAttempts; attempt++ {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(backoff):
 		}
+		backoff *= 2
+	}
+}
+
+// pollMetadata polls every provider once
+func (s *Station) pollMetadata() {
+	for _, p := range s.providers {
+		s.pollProvider(p)
 	}
 }
 
+// fetch calls the provider, turning a panic on malformed upstream data into
+// an ordinary fetch error so the poller keeps its schedule
+func (s *Station) fetch(p *providerState) (meta string, fields map[string]string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.panics.Add(1)
+			log.Printf("station %s: metadata provider %s panic: %v\n%s", s.id, p.name, r, debug.Stack())
+			err = fmt.Errorf("provider panic: %v", r)
+		}
+	}()
+
+	if sp, ok := p.source.(domain.StructuredMetadataProvider); ok {
+		return sp.FetchFields(s.ctx)
+	}
+	meta, err = p.source.Fetch(s.ctx)
+	return meta, nil, err
+}
+
+// pollProvider fetches from p once and reports whether it succeeded
+func (s *Station) pollProvider(p *providerState) bool {
+	meta, fields, err := s.fetch(p)
+	if err != nil {
+		msg := err.Error()
+		p.lastErr.Store(&msg)
+		return false
+	}
+
+	now := time.Now()
+	p.lastFetchAt.Store(&now)
+	p.lastErr.Store(nil)
+
+	s.fieldsMu.Lock()
+	defer s.fieldsMu.Unlock()
+
+	if fields != nil {
+		p.fields = fields
+	}
+	merged := s.mergedFields()
+
+	switch {
+	case p.primary && merged != nil:
+		s.UpdateMetadataFields(meta, merged)
+	case p.primary:
+		s.UpdateMetadata(meta)
+	case merged != nil:
+		s.currentFields.Store(&merged)
+		if len(s.icyFields) > 0 {
+			// Re-encode so extra fields pick up the secondary provider's values
+			s.setMetadata(*s.currentMeta.Load())
+		}
+	}
+	s.syncVariants()
+	return true
+}
+
+// mergedFields combines provider fields in order, earlier providers
+// winning unless their value is empty. Callers hold fieldsMu.
+func (s *Station) mergedFields() map[string]string {
+	var merged map[string]string
+	for _, p := range s.providers {
+		if p.fields == nil {
+			continue
+		}
+		if merged == nil {
+			merged = make(map[string]string, len(p.fields))
+		}
+		for k, v := range p.fields {
+			if merged[k] == "" {
+				merged[k] = v
+			}
+		}
+	}
+	return merged
+}
+
+// ProviderStatus reports each metadata provider's interval and last fetch
+func (s *Station) ProviderStatus() []ProviderStatus {
+	result := make([]ProviderStatus, 0, len(s.providers))
+	for _, p := range s.providers {
+		status := ProviderStatus{
+			Name:         p.name,
+			PollInterval: p.interval,
+			LastFetchAt:  p.lastFetchAt.Load(),
+			Panics:       p.panics.Load(),
+		}
+		if msg := p.lastErr.Load(); msg != nil {
+			status.LastError = *msg
+		}
+		result = append(result, status)
+	}
+	return result
+}
+
+// ProviderDebug is one provider's result from DebugMetadata
+type ProviderDebug struct {
+	Name string
+	domain.MetadataDebug
+}
+
+// DebugMetadata fetches once from every provider without touching the
+// station's current metadata. Providers that can't report their raw
+// response still show the fields and string they produced.
+func (s *Station) DebugMetadata(ctx context.Context) []ProviderDebug {
+	result := make([]ProviderDebug, 0, len(s.providers))
+	for _, p := range s.providers {
+		result = append(result, ProviderDebug{Name: p.name, MetadataDebug: s.debugFetch(ctx, p)})
+	}
+	return result
+}
+
+func (s *Station) debugFetch(ctx context.Context, p *providerState) (d domain.MetadataDebug) {
+	dbg, ok := p.source.(domain.MetadataDebugger)
+	if !ok {
+		meta, fields, err := s.fetch(p)
+		d.Metadata, d.Fields = meta, fields
+		if err != nil {
+			d.Error = err.Error()
+		}
+		return d
+	}
+
+	// Debugging is for quirky feeds, so a payload that panics the builder
+	// is reported rather than failing the request
+	defer func() {
+		if r := recover(); r != nil {
+			d.Error = fmt.Sprintf("provider panic: %v", r)
+		}
+	}()
+	return dbg.FetchDebug(ctx)
+}
+
 func (s *Station) runFanOut() {
 	for {
 		select {
 		case <-s.ctx.Done():
 			return
 		case chunk := <-s.chunkBus:
-			// Distribute chunk to all subscribed clients
-			s.clientsMu.Lock()
-			for client := range s.clients {
-				if client.ch != nil {
-					select {
-					case client.ch <- chunk:
-					default:
-						// Client buffer full, skip this chunk
-					}
-				}
-			}
-			s.clientsMu.Unlock()
+			s.distribute(chunk)
+		}
+	}
+}
+
+// minFanoutShard is the fewest clients worth handing to another worker
+const minFanoutShard = 256
+
+// distribute sends chunk to all subscribed clients, skipping full ones. The
+// client channels are snapshotted under a short lock and delivered to
+// without it, so subscribes and unsubscribes don't wait on the fan-out.
+func (s *Station) distribute(chunk []byte) {
+	s.clientsMu.Lock()
+
+	// The ring is written under clientsMu so the position SubscribeBurst
+	// takes never overlaps chunks the client will also receive
+	if len(chunk) == 0 {
+		if s.buffer != nil {
+			s.buffer.Reset()
+		}
+		s.clientsMu.Unlock()
+		return
+	}
+	if s.buffer != nil {
+		s.buffer.Write(chunk)
+	}
+
+	targets := s.targets[:0]
+	for client := range s.clients {
+		if client.ch != nil {
+			targets = append(targets, client.ch)
+		}
+	}
+	s.targets = targets
+
+	// Taken before clientsMu is released so no snapshotted channel can be
+	// closed before the sends below
+	s.sendMu.RLock()
+	defer s.sendMu.RUnlock()
+	s.clientsMu.Unlock()
+
+	workers := min(s.fanoutWorkers, len(targets)/minFanoutShard)
+	if workers <= 1 {
+		s.send(targets, chunk)
+		return
+	}
+
+	// Each chunk is fully delivered before the next, so every client still
+	// sees chunks in order
+	var wg sync.WaitGroup
+	size := (len(targets) + workers - 1) / workers
+	for start := 0; start < len(targets); start += size {
+		shard := targets[start:min(start+size, len(targets))]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.send(shard, chunk)
+		}()
+	}
+	wg.Wait()
+}
+
+// send offers chunk to each channel, dropping it for clients whose buffer is full
+func (s *Station) send(targets []chan []byte, chunk []byte) {
+	delivered := 0
+	for _, ch := range targets {
+		s.clientFill.Observe(float64(len(ch)))
+		select {
+		case ch <- chunk:
+			delivered++
+		default:
+			// Client buffer full, skip this chunk
 		}
 	}
+	s.bytesServed.Add(int64(delivered * len(chunk)))
 }
@@ -5,10 +5,19 @@ package station
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/audio"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/icy"
 	"github.com/harper/radio-metadata-proxy/internal/infrastructure/ring"
 )
 
@@ -214,3 +223,1597 @@ func TestStation_Subscribe(t *testing.T) {
 		<-chunks
 	}
 }
+
+func TestStation_DoubleSubscribe(t *testing.T) {
+	s := New(Config{ID: "test", MetaInt: 16384}, nil, nil, nil)
+
+	client := &Client{ID: "c1"}
+	first := s.Subscribe(client)
+	second := s.Subscribe(client)
+
+	if first != second {
+		t.Error("expected second Subscribe to return the existing channel")
+	}
+
+	if count := s.ClientCount(); count != 1 {
+		t.Errorf("expected 1 client, got %d", count)
+	}
+
+	s.Unsubscribe(client)
+
+	if _, ok := <-first; ok {
+		t.Error("expected channel to be closed after Unsubscribe")
+	}
+}
+
+func TestStation_DoubleUnsubscribe(t *testing.T) {
+	s := New(Config{ID: "test", MetaInt: 16384}, nil, nil, nil)
+
+	client := &Client{ID: "c1"}
+	s.Subscribe(client)
+
+	s.Unsubscribe(client)
+	s.Unsubscribe(client)
+
+	if count := s.ClientCount(); count != 0 {
+		t.Errorf("expected 0 clients, got %d", count)
+	}
+
+	// Unsubscribing a client that never subscribed is a no-op
+	s.Unsubscribe(&Client{ID: "never"})
+}
+
+// endlessSource streams small chunks until the station is shut down
+type endlessSource struct{}
+
+func (endlessSource) Connect(ctx context.Context) (io.ReadCloser, error) {
+	return io.NopCloser(&endlessReader{ctx: ctx}), nil
+}
+
+type endlessReader struct {
+	ctx   context.Context
+	stall bool // block until cancelled without producing data
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	if r.stall {
+		<-r.ctx.Done()
+		return 0, r.ctx.Err()
+	}
+
+	select {
+	case <-r.ctx.Done():
+		return 0, io.EOF
+	case <-time.After(time.Millisecond):
+	}
+	n := len(p)
+	if n > 1024 {
+		n = 1024
+	}
+	return n, nil
+}
+
+// TestStation_ConcurrentStress exercises subscribe/unsubscribe, fan-out and
+// metadata updates concurrently. Run with -race to detect data races.
+func TestStation_ConcurrentStress(t *testing.T) {
+	cfg := Config{
+		ID:             "stress",
+		MetaInt:        16384,
+		PollInterval:   5 * time.Millisecond,
+		RingBufferSize: 4096,
+		ChunkBusCap:    32,
+	}
+
+	s := New(cfg, endlessSource{}, &mockMetadataProvider{meta: "StreamTitle='Polled';"}, ring.New(4096))
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer s.Shutdown()
+
+	var wg sync.WaitGroup
+
+	// Subscribers churning in and out
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				client := &Client{ID: fmt.Sprintf("c%d-%d", i, j)}
+				chunks := s.Subscribe(client)
+				select {
+				case <-chunks:
+				case <-time.After(10 * time.Millisecond):
+				}
+				if j%2 == 0 {
+					s.Subscribe(client)
+				}
+				s.Unsubscribe(client)
+				s.Unsubscribe(client)
+			}
+		}(i)
+	}
+
+	// Metadata writers and readers
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				s.UpdateMetadata(fmt.Sprintf("StreamTitle='Song %d-%d';", i, j))
+				_ = s.CurrentMetadata()
+				_ = s.LastMetadataUpdate()
+				_ = s.ClientCount()
+				_ = s.SourceHealthy()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if count := s.ClientCount(); count != 0 {
+		t.Errorf("expected 0 clients after stress, got %d", count)
+	}
+}
+
+type structuredMetadataProvider struct {
+	mockMetadataProvider
+	fields map[string]string
+}
+
+func (m *structuredMetadataProvider) FetchFields(ctx context.Context) (string, map[string]string, error) {
+	return m.meta, m.fields, nil
+}
+
+func (m *structuredMetadataProvider) Render(format string, fields map[string]string) string {
+	return strings.ReplaceAll(format, "{title}", fields["title"])
+}
+
+func TestStation_RenderMetadata(t *testing.T) {
+	provider := &structuredMetadataProvider{
+		mockMetadataProvider: mockMetadataProvider{meta: "StreamTitle='Artist - Song';"},
+		fields:               map[string]string{"title": "Song"},
+	}
+
+	cfg := Config{
+		ID:      "test",
+		MetaInt: 16384,
+		Formats: map[string]string{"short": "StreamTitle='{title}';"},
+	}
+
+	s := New(cfg, nil, provider, nil)
+	s.pollMetadata()
+
+	if meta, ok := s.RenderMetadata(""); !ok || meta != "StreamTitle='Artist - Song';" {
+		t.Errorf("expected primary metadata, got %q (ok=%v)", meta, ok)
+	}
+
+	if meta, ok := s.RenderMetadata("short"); !ok || meta != "StreamTitle='Song';" {
+		t.Errorf("expected short metadata, got %q (ok=%v)", meta, ok)
+	}
+
+	if _, ok := s.RenderMetadata("missing"); ok {
+		t.Error("expected unknown format to report !ok")
+	}
+}
+
+func TestStation_PeakAndUniqueClients(t *testing.T) {
+	s := New(Config{ID: "test", MetaInt: 16384}, nil, nil, nil)
+
+	a := &Client{ID: "a", Addr: "10.0.0.1:1000"}
+	b := &Client{ID: "b", Addr: "10.0.0.1:1001"}
+	c := &Client{ID: "c", Addr: "10.0.0.2:1000"}
+
+	s.Subscribe(a)
+	s.Subscribe(b)
+	s.Subscribe(c)
+
+	if unique := s.UniqueClientCount(); unique != 2 {
+		t.Errorf("expected 2 unique hosts, got %d", unique)
+	}
+
+	s.Unsubscribe(a)
+	s.Unsubscribe(b)
+
+	if peak := s.PeakClientCount(); peak != 3 {
+		t.Errorf("expected peak 3 after disconnects, got %d", peak)
+	}
+}
+
+func TestStation_InternalClientsArentListeners(t *testing.T) {
+	s := New(Config{ID: "test", MetaInt: 16384}, nil, nil, ring.New(1024))
+
+	rec := &Client{ID: "recorder", Addr: "internal", Internal: true}
+	recCh := s.Subscribe(rec)
+	listener := &Client{ID: "a", Addr: "10.0.0.1:1000"}
+	s.Subscribe(listener)
+
+	if n := s.ClientCount(); n != 1 {
+		t.Errorf("expected 1 listener, got %d", n)
+	}
+	if n := s.UniqueClientCount(); n != 1 {
+		t.Errorf("expected 1 unique host, got %d", n)
+	}
+	if n := s.PeakClientCount(); n != 1 {
+		t.Errorf("expected peak 1, got %d", n)
+	}
+	if clients := s.Clients(); len(clients) != 1 || clients[0].ID != "a" {
+		t.Errorf("expected only the listener in Clients, got %+v", clients)
+	}
+
+	// Internal clients still receive audio
+	s.distribute([]byte("audio"))
+	if chunk := <-recCh; string(chunk) != "audio" {
+		t.Errorf("expected internal client to get audio, got %q", chunk)
+	}
+
+	s.Unsubscribe(rec)
+	s.Unsubscribe(listener)
+	if n := s.ClientCount(); n != 0 {
+		t.Errorf("expected no listeners, got %d", n)
+	}
+}
+
+func TestStation_DefaultTitleSeed(t *testing.T) {
+	cfg := Config{
+		ID:           "test",
+		MetaInt:      16384,
+		DefaultTitle: "Test Radio",
+	}
+
+	s := New(cfg, nil, &mockMetadataProvider{meta: "StreamTitle='Live';"}, nil)
+
+	if meta := s.CurrentMetadata(); meta != "StreamTitle='Test Radio';" {
+		t.Errorf("expected seeded metadata, got %q", meta)
+	}
+
+	if !s.Provisional() {
+		t.Error("expected metadata to be provisional before first fetch")
+	}
+
+	s.pollMetadata()
+
+	if meta := s.CurrentMetadata(); meta != "StreamTitle='Live';" {
+		t.Errorf("expected fetched metadata, got %q", meta)
+	}
+
+	if s.Provisional() {
+		t.Error("expected metadata to be final after first fetch")
+	}
+}
+
+// flakySource panics on its first Connect and then serves data
+type flakySource struct {
+	connects atomic.Int32
+}
+
+func (f *flakySource) Connect(ctx context.Context) (io.ReadCloser, error) {
+	if f.connects.Add(1) == 1 {
+		panic("boom")
+	}
+	return io.NopCloser(bytes.NewReader([]byte("recovered audio"))), nil
+}
+
+func TestStation_SourceReaderRecoversFromPanic(t *testing.T) {
+	src := &flakySource{}
+	buffer := ring.New(1024)
+
+	cfg := Config{
+		ID:               "test",
+		MetaInt:          16384,
+		ChunkBusCap:      32,
+		ReconnectInitial: 10 * time.Millisecond,
+	}
+
+	s := New(cfg, src, nil, buffer)
+	s.Start()
+	defer s.Shutdown()
+
+	time.Sleep(100 * time.Millisecond)
+
+	if src.connects.Load() < 2 {
+		t.Fatalf("expected reconnect after panic, got %d connects", src.connects.Load())
+	}
+
+	if !bytes.Contains(buffer.Snapshot(), []byte("recovered audio")) {
+		t.Error("expected audio from the reconnected source")
+	}
+}
+
+// rejectingSource fails every Connect with err
+type rejectingSource struct {
+	err      error
+	connects atomic.Int32
+}
+
+func (r *rejectingSource) Connect(ctx context.Context) (io.ReadCloser, error) {
+	r.connects.Add(1)
+	return nil, r.err
+}
+
+type statusErr struct{ permanent bool }
+
+func (e statusErr) Error() string   { return "status" }
+func (e statusErr) Permanent() bool { return e.permanent }
+
+func TestStation_PermanentSourceErrorBacksOff(t *testing.T) {
+	for _, permanent := range []bool{false, true} {
+		src := &rejectingSource{err: statusErr{permanent}}
+		s := New(Config{
+			ID:               "test",
+			ReconnectInitial: 10 * time.Millisecond,
+			ReconnectMax:     10 * time.Millisecond,
+			RejectedBackoff:  time.Hour,
+		}, src, nil, ring.New(1024))
+		s.Start()
+
+		time.Sleep(100 * time.Millisecond)
+		s.Shutdown()
+
+		connects := src.connects.Load()
+		if permanent && connects != 1 {
+			t.Errorf("expected a single connect before the rejected backoff, got %d", connects)
+		}
+		if !permanent && connects < 3 {
+			t.Errorf("expected transient errors to retry quickly, got %d connects", connects)
+		}
+	}
+}
+
+func TestStation_SourceError(t *testing.T) {
+	src := &rejectingSource{err: errors.New("token refresh: endpoint down")}
+	s := New(Config{ID: "test", ReconnectInitial: time.Hour}, src, nil, ring.New(1024))
+	if s.SourceError() != "" {
+		t.Error("expected no source error before connecting")
+	}
+
+	s.Start()
+	defer s.Shutdown()
+	deadline := time.Now().Add(time.Second)
+	for s.SourceError() == "" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if got := s.SourceError(); got != "token refresh: endpoint down" {
+		t.Errorf("SourceError = %q", got)
+	}
+	if got := s.SourceErrorKind(); got != "connect failed" {
+		t.Errorf("SourceErrorKind = %q", got)
+	}
+	if s.SourceHealthy() {
+		t.Error("expected unhealthy source")
+	}
+}
+
+// numberedSource serves "conn-N" on its Nth connection, up to max connections
+type numberedSource struct {
+	connects atomic.Int32
+	max      int32
+}
+
+func (n *numberedSource) Connect(ctx context.Context) (io.ReadCloser, error) {
+	c := n.connects.Add(1)
+	if c > n.max {
+		return nil, errors.New("done")
+	}
+	return io.NopCloser(strings.NewReader(fmt.Sprintf("conn-%d;", c))), nil
+}
+
+func TestStation_ClearOnReconnect(t *testing.T) {
+	for _, clear := range []bool{false, true} {
+		buffer := ring.New(1024)
+		s := New(Config{
+			ID:               "test",
+			ReconnectInitial: 5 * time.Millisecond,
+			ReconnectMax:     5 * time.Millisecond,
+			ClearOnReconnect: clear,
+		}, &numberedSource{max: 2}, nil, buffer)
+		s.Start()
+		time.Sleep(100 * time.Millisecond)
+		s.Shutdown()
+
+		got := string(buffer.Snapshot())
+		want := "conn-1;conn-2;"
+		if clear {
+			want = "conn-2;"
+		}
+		if got != want {
+			t.Errorf("clear=%v: expected ring %q, got %q", clear, want, got)
+		}
+	}
+}
+
+func TestStation_SuperviseRestartsAfterPanic(t *testing.T) {
+	s := New(Config{ID: "test"}, nil, nil, nil)
+
+	runs := 0
+	s.supervise("test", func() {
+		runs++
+		if runs == 1 {
+			panic("boom")
+		}
+	})
+
+	if runs != 2 {
+		t.Errorf("expected fn to be restarted once, got %d runs", runs)
+	}
+}
+
+// stalledSource connects successfully but never produces audio
+type stalledSource struct {
+	connects atomic.Int32
+}
+
+func (s *stalledSource) Connect(ctx context.Context) (io.ReadCloser, error) {
+	s.connects.Add(1)
+	return io.NopCloser(&endlessReader{ctx: ctx, stall: true}), nil
+}
+
+func TestStation_WatchdogRestartsStalledReader(t *testing.T) {
+	src := &stalledSource{}
+
+	cfg := Config{
+		ID:               "test",
+		MetaInt:          16384,
+		ChunkBusCap:      32,
+		ReconnectInitial: 10 * time.Millisecond,
+		StallTimeout:     50 * time.Millisecond,
+	}
+
+	s := New(cfg, src, nil, ring.New(1024))
+	s.Start()
+	defer s.Shutdown()
+
+	time.Sleep(200 * time.Millisecond)
+
+	if src.connects.Load() < 2 {
+		t.Errorf("expected watchdog to restart the reader, got %d connects", src.connects.Load())
+	}
+}
+
+func TestStation_CurrentMetadataBlock(t *testing.T) {
+	s := New(Config{ID: "test", MetaInt: 16384}, nil, nil, nil)
+
+	empty := s.CurrentMetadataBlock()
+	if !bytes.Equal(empty, icy.BuildBlock("StreamTitle='';")) {
+		t.Errorf("expected empty StreamTitle block, got %q", empty)
+	}
+
+	s.UpdateMetadata("StreamTitle='Song';")
+	first := s.CurrentMetadataBlock()
+	second := s.CurrentMetadataBlock()
+
+	if !bytes.Equal(first, icy.BuildBlock("StreamTitle='Song';")) {
+		t.Errorf("unexpected block %q", first)
+	}
+
+	if &first[0] != &second[0] {
+		t.Error("expected cached block to be reused while metadata is unchanged")
+	}
+
+	s.UpdateMetadata("StreamTitle='Next';")
+	if bytes.Equal(s.CurrentMetadataBlock(), first) {
+		t.Error("expected block to be rebuilt after metadata change")
+	}
+}
+
+const benchClients = 1000
+
+// BenchmarkMetadataBlock_PerClient encodes the block for every client at
+// every metaint boundary, as the stream handler used to.
+func BenchmarkMetadataBlock_PerClient(b *testing.B) {
+	s := New(Config{ID: "bench", MetaInt: 16384}, nil, nil, nil)
+	s.UpdateMetadata("StreamTitle='Benchmark Artist - Benchmark Title (Album)';")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for c := 0; c < benchClients; c++ {
+			_ = icy.BuildBlock(s.CurrentMetadata())
+		}
+	}
+}
+
+// BenchmarkMetadataBlock_Cached reuses the station's pre-encoded block.
+func BenchmarkMetadataBlock_Cached(b *testing.B) {
+	s := New(Config{ID: "bench", MetaInt: 16384}, nil, nil, nil)
+	s.UpdateMetadata("StreamTitle='Benchmark Artist - Benchmark Title (Album)';")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for c := 0; c < benchClients; c++ {
+			_ = s.CurrentMetadataBlock()
+		}
+	}
+}
+
+type countingMetadataProvider struct {
+	structuredMetadataProvider
+	calls atomic.Int32
+}
+
+func (m *countingMetadataProvider) FetchFields(ctx context.Context) (string, map[string]string, error) {
+	m.calls.Add(1)
+	return m.structuredMetadataProvider.FetchFields(ctx)
+}
+
+func TestStation_MergedProviders(t *testing.T) {
+	primary := &structuredMetadataProvider{
+		mockMetadataProvider: mockMetadataProvider{meta: "StreamTitle='Artist - Song';"},
+		fields:               map[string]string{"title": "Song", "artwork": ""},
+	}
+	artwork := &structuredMetadataProvider{
+		fields: map[string]string{"title": "Other", "artwork": "http://img/cover.jpg"},
+	}
+
+	cfg := Config{
+		ID:        "test",
+		MetaInt:   16384,
+		Providers: []Provider{{Name: "artwork", Source: artwork}},
+	}
+
+	s := New(cfg, nil, primary, nil)
+	s.pollMetadata()
+
+	if meta := s.CurrentMetadata(); meta != "StreamTitle='Artist - Song';" {
+		t.Errorf("expected title from primary, got %q", meta)
+	}
+
+	fields := s.Fields()
+	if fields["title"] != "Song" {
+		t.Errorf("expected primary title field to win, got %q", fields["title"])
+	}
+	if fields["artwork"] != "http://img/cover.jpg" {
+		t.Errorf("expected artwork filled by extra provider, got %q", fields["artwork"])
+	}
+
+	for _, p := range s.ProviderStatus() {
+		if p.LastFetchAt == nil {
+			t.Errorf("provider %s: expected last fetch time", p.Name)
+		}
+	}
+}
+
+func TestStation_ProviderIntervals(t *testing.T) {
+	primary := &countingMetadataProvider{}
+	slow := &countingMetadataProvider{}
+
+	cfg := Config{
+		ID:           "test",
+		MetaInt:      16384,
+		PollInterval: 10 * time.Millisecond,
+		ChunkBusCap:  1,
+		Providers:    []Provider{{Name: "slow", Source: slow, PollInterval: time.Hour}},
+	}
+
+	s := New(cfg, &mockSource{}, primary, ring.New(1024))
+	s.Start()
+	time.Sleep(100 * time.Millisecond)
+	s.Shutdown()
+
+	if n := primary.calls.Load(); n < 3 {
+		t.Errorf("expected primary polled repeatedly, got %d", n)
+	}
+	if n := slow.calls.Load(); n != 1 {
+		t.Errorf("expected slow provider polled once, got %d", n)
+	}
+}
+
+func TestStation_PacedSource(t *testing.T) {
+	// 256 kbps = 32000 bytes/s; the endless source could deliver ~1MB/s
+	cfg := Config{
+		ID:          "test",
+		MetaInt:     16384,
+		ChunkBusCap: 32,
+		PaceKbps:    256,
+	}
+
+	s := New(cfg, endlessSource{}, nil, ring.New(65536))
+	ch := s.Subscribe(&Client{ID: "counter"})
+
+	var received atomic.Int64
+	go func() {
+		for chunk := range ch {
+			received.Add(int64(len(chunk)))
+		}
+	}()
+
+	s.Start()
+	time.Sleep(500 * time.Millisecond)
+	s.Shutdown()
+
+	// ~16000 bytes expected in 500ms; allow generous scheduling slack
+	if n := received.Load(); n < 10000 || n > 25000 {
+		t.Errorf("expected roughly 16000 bytes at the paced rate, got %d", n)
+	}
+}
+
+func TestStation_PaceLongRunning(t *testing.T) {
+	s := New(Config{ID: "test", PaceKbps: 256}, nil, nil, ring.New(1024))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// 4 GiB at 256 kbps is due ~37 hours from start, so pace must wait
+	// (and give up on the cancelled context) rather than run ahead
+	if s.pace(ctx, time.Now(), 4<<30) {
+		t.Error("expected pace to wait for bytes far past 1 GB")
+	}
+	if !s.pace(ctx, time.Now().Add(-38*time.Hour), 4<<30) {
+		t.Error("expected bytes already due not to wait")
+	}
+}
+
+func TestStation_InjectsICY(t *testing.T) {
+	tests := []struct {
+		cfg  Config
+		want bool
+	}{
+		{Config{ID: "mp3"}, true},
+		{Config{ID: "ogg", ContentType: "audio/ogg"}, false},
+		{Config{ID: "webm", ContentType: "audio/webm"}, false},
+		{Config{ID: "forced", ContentType: "audio/mpeg", ICYPassthrough: true}, false},
+	}
+
+	for _, tt := range tests {
+		if got := New(tt.cfg, nil, nil, nil).InjectsICY(); got != tt.want {
+			t.Errorf("%s: expected InjectsICY %v, got %v", tt.cfg.ID, tt.want, got)
+		}
+	}
+}
+
+func TestStation_UnhealthyTitle(t *testing.T) {
+	cfg := Config{
+		ID:             "test",
+		MetaInt:        16384,
+		UnhealthyTitle: "Reconnecting...",
+		DefaultTitle:   "Test Radio",
+	}
+
+	s := New(cfg, nil, nil, nil)
+
+	// Before the first connect attempt finishes the source isn't failing yet
+	if meta := s.CurrentMetadata(); meta != "StreamTitle='Test Radio';" {
+		t.Errorf("expected default title while starting, got %q", meta)
+	}
+
+	s.SetSourceHealthy(true)
+	s.UpdateMetadata("StreamTitle='Song';")
+
+	if meta := s.CurrentMetadata(); meta != "StreamTitle='Song';" {
+		t.Errorf("expected normal metadata while healthy, got %q", meta)
+	}
+
+	s.SetSourceHealthy(false)
+
+	if meta := s.CurrentMetadata(); meta != "StreamTitle='Reconnecting...';" {
+		t.Errorf("expected unhealthy title, got %q", meta)
+	}
+
+	if block := s.CurrentMetadataBlock(); !bytes.Equal(block, icy.BuildBlock("StreamTitle='Reconnecting...';")) {
+		t.Errorf("expected unhealthy ICY block, got %q", block)
+	}
+
+	// Updates during the outage are kept and shown once health returns
+	s.UpdateMetadata("StreamTitle='Next Song';")
+	s.SetSourceHealthy(true)
+
+	if meta := s.CurrentMetadata(); meta != "StreamTitle='Next Song';" {
+		t.Errorf("expected latest metadata after recovery, got %q", meta)
+	}
+
+	// A first connect that fails shows the unhealthy title straight away
+	s = New(cfg, nil, nil, nil)
+	s.SetSourceHealthy(false)
+	if meta := s.CurrentMetadata(); meta != "StreamTitle='Reconnecting...';" {
+		t.Errorf("expected unhealthy title after a failed first connect, got %q", meta)
+	}
+}
+
+func TestStation_ResetPeak(t *testing.T) {
+	s := New(Config{ID: "test", MetaInt: 16384}, nil, nil, nil)
+
+	clients := []*Client{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	for _, c := range clients {
+		s.Subscribe(c)
+	}
+	s.Unsubscribe(clients[0])
+	s.Unsubscribe(clients[1])
+
+	if peak := s.DailyPeakClientCount(); peak != 3 {
+		t.Errorf("expected daily peak 3, got %d", peak)
+	}
+
+	s.ResetPeak()
+
+	if peak := s.PeakClientCount(); peak != 1 {
+		t.Errorf("expected peak reset to current count 1, got %d", peak)
+	}
+
+	// The daily peak is reported separately and survives the reset
+	if peak := s.DailyPeakClientCount(); peak != 3 {
+		t.Errorf("expected daily peak 3 after reset, got %d", peak)
+	}
+}
+
+func TestStation_ZeroPollInterval(t *testing.T) {
+	meta := &countingMetadataProvider{
+		structuredMetadataProvider: structuredMetadataProvider{
+			mockMetadataProvider: mockMetadataProvider{meta: "StreamTitle='Song';"},
+		},
+	}
+
+	cfg := Config{
+		ID:          "test",
+		MetaInt:     16384,
+		ChunkBusCap: 1,
+	}
+
+	s := New(cfg, &mockSource{}, meta, ring.New(1024))
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer s.Shutdown()
+
+	time.Sleep(50 * time.Millisecond)
+
+	if n := meta.calls.Load(); n != 1 {
+		t.Errorf("expected a single initial fetch, got %d", n)
+	}
+
+	if got := s.CurrentMetadata(); got != "StreamTitle='Song';" {
+		t.Errorf("expected initial metadata, got %q", got)
+	}
+}
+
+type failingMetadataProvider struct {
+	failures int32 // calls that fail before succeeding
+	calls    atomic.Int32
+}
+
+func (m *failingMetadataProvider) Fetch(ctx context.Context) (string, error) {
+	if m.calls.Add(1) <= m.failures {
+		return "", fmt.Errorf("upstream not ready")
+	}
+	return "StreamTitle='Ready';", nil
+}
+
+func TestStation_InitialFetchRetry(t *testing.T) {
+	defer func(d time.Duration) { initialFetchBackoff = d }(initialFetchBackoff)
+	initialFetchBackoff = 5 * time.Millisecond
+
+	tests := []struct {
+		name      string
+		failures  int32
+		wantCalls int32
+		wantMeta  string
+	}{
+		{"recovers after two failures", 2, 3, "StreamTitle='Ready';"},
+		{"gives up after bounded attempts", 100, int32(initialFetchAttempts), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := &failingMetadataProvider{failures: tt.failures}
+			cfg := Config{
+				ID:           "test",
+				MetaInt:      16384,
+				PollInterval: time.Hour,
+				ChunkBusCap:  1,
+			}
+
+			s := New(cfg, &mockSource{}, meta, ring.New(1024))
+			s.Start()
+			time.Sleep(150 * time.Millisecond)
+			s.Shutdown()
+
+			if n := meta.calls.Load(); n != tt.wantCalls {
+				t.Errorf("expected %d fetches, got %d", tt.wantCalls, n)
+			}
+
+			if got := s.CurrentMetadata(); got != tt.wantMeta {
+				t.Errorf("expected metadata %q, got %q", tt.wantMeta, got)
+			}
+		})
+	}
+}
+
+func TestStation_UniqueClientIDs(t *testing.T) {
+	a := New(Config{ID: "a", MetaInt: 16384}, nil, nil, nil)
+	b := New(Config{ID: "b", MetaInt: 16384}, nil, nil, nil)
+
+	const perStation = 500
+	clients := make([]*Client, 0, 2*perStation)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, s := range []*Station{a, b} {
+		for i := 0; i < perStation; i++ {
+			wg.Add(1)
+			go func(s *Station) {
+				defer wg.Done()
+				c := &Client{}
+				s.Subscribe(c)
+				s.Unsubscribe(c)
+
+				mu.Lock()
+				clients = append(clients, c)
+				mu.Unlock()
+			}(s)
+		}
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool, len(clients))
+	for _, c := range clients {
+		if c.ID == "" {
+			t.Fatal("expected Subscribe to assign an ID")
+		}
+		if seen[c.ID] {
+			t.Fatalf("duplicate client ID %s", c.ID)
+		}
+		seen[c.ID] = true
+	}
+
+	// Caller-supplied IDs are kept
+	named := &Client{ID: "recorder"}
+	a.Subscribe(named)
+	if named.ID != "recorder" {
+		t.Errorf("expected supplied ID to be kept, got %s", named.ID)
+	}
+}
+
+type recordingObserver struct {
+	events []string
+}
+
+func (o *recordingObserver) OnSourceUp(id string)      { o.events = append(o.events, "up:"+id) }
+func (o *recordingObserver) OnSourceDown(id string)    { o.events = append(o.events, "down:"+id) }
+func (o *recordingObserver) OnMetadataStale(id string) { o.events = append(o.events, "stale:"+id) }
+
+func TestStation_ObserverTransitions(t *testing.T) {
+	obs := &recordingObserver{}
+	s := New(Config{ID: "test", Observer: obs}, nil, nil, nil)
+
+	s.SetSourceHealthy(false) // already down: no transition
+	s.SetSourceHealthy(true)
+	s.SetSourceHealthy(true)
+	s.SetSourceHealthy(false)
+	s.SetSourceHealthy(true)
+
+	want := []string{"up:test", "down:test", "up:test"}
+	if !slices.Equal(obs.events, want) {
+		t.Errorf("expected %v, got %v", want, obs.events)
+	}
+}
+
+func TestStation_ReconnectJitter(t *testing.T) {
+	s := New(Config{ID: "test", ReconnectJitter: 0.5}, nil, nil, nil)
+
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 50; i++ {
+		d := s.jitter(10 * time.Second)
+		if d < 5*time.Second || d > 10*time.Second {
+			t.Fatalf("jittered delay %s outside [5s, 10s]", d)
+		}
+		seen[d] = true
+	}
+	if len(seen) < 2 {
+		t.Error("expected jittered delays to vary across attempts")
+	}
+
+	if d := New(Config{ID: "plain"}, nil, nil, nil).jitter(10 * time.Second); d != 10*time.Second {
+		t.Errorf("expected no jitter by default, got %s", d)
+	}
+}
+
+func TestStation_MetadataStale(t *testing.T) {
+	obs := &recordingObserver{}
+	s := New(Config{ID: "test", Observer: obs, StaleAfter: time.Minute}, nil, nil, nil)
+
+	// Never stale while the source is down
+	s.checkStale(time.Now().Add(time.Hour))
+	if s.MetadataStale() {
+		t.Fatal("expected no staleness while the source is down")
+	}
+
+	s.SetSourceHealthy(true)
+	s.UpdateMetadata("StreamTitle='Song';")
+
+	s.checkStale(time.Now().Add(30 * time.Second))
+	if s.MetadataStale() {
+		t.Fatal("expected fresh metadata within stale_after")
+	}
+
+	s.checkStale(time.Now().Add(2 * time.Minute))
+	s.checkStale(time.Now().Add(3 * time.Minute))
+	if !s.MetadataStale() {
+		t.Fatal("expected stale metadata after stale_after")
+	}
+
+	// A change clears the flag and re-arms the notification
+	s.UpdateMetadata("StreamTitle='Next';")
+	s.checkStale(time.Now())
+	if s.MetadataStale() {
+		t.Error("expected a metadata change to clear the stale flag")
+	}
+	s.checkStale(time.Now().Add(2 * time.Minute))
+
+	want := []string{"up:test", "stale:test", "stale:test"}
+	if !slices.Equal(obs.events, want) {
+		t.Errorf("expected %v, got %v", want, obs.events)
+	}
+}
+
+func TestStation_MetadataStaleOnAirHours(t *testing.T) {
+	obs := &recordingObserver{}
+	s := New(Config{
+		ID:         "test",
+		Observer:   obs,
+		StaleAfter: time.Hour,
+		OnAirHours: &Hours{Ranges: []TimeRange{{Start: 6 * time.Hour, End: 22 * time.Hour}}},
+	}, nil, nil, nil)
+
+	s.SetSourceHealthy(true)
+	s.UpdateMetadata("StreamTitle='Overnight';")
+
+	// A title unchanged through the night, well past stale_after
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 2)
+	s.checkStale(day.Add(5 * time.Hour))
+	if s.MetadataStale() {
+		t.Fatal("expected no staleness off air")
+	}
+
+	// Going on air starts the clock afresh
+	s.checkStale(day.Add(6*time.Hour + 30*time.Minute))
+	s.checkStale(day.Add(7 * time.Hour))
+	if s.MetadataStale() {
+		t.Fatal("expected no staleness within stale_after of going on air")
+	}
+	s.checkStale(day.Add(7*time.Hour + 31*time.Minute))
+	if !s.MetadataStale() {
+		t.Fatal("expected stale metadata on air after stale_after")
+	}
+
+	s.checkStale(day.Add(23 * time.Hour))
+	if s.MetadataStale() {
+		t.Error("expected the flag cleared off air")
+	}
+
+	want := []string{"up:test", "stale:test"}
+	if !slices.Equal(obs.events, want) {
+		t.Errorf("expected %v, got %v", want, obs.events)
+	}
+}
+
+func TestHours_Contains(t *testing.T) {
+	h := &Hours{Ranges: []TimeRange{
+		{Start: 22 * time.Hour, End: 2 * time.Hour, Days: []time.Weekday{time.Friday}},
+	}}
+	friday := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		{friday.Add(23 * time.Hour), true},
+		{friday.Add(25 * time.Hour), true}, // Saturday morning, started Friday
+		{friday.Add(21 * time.Hour), false},
+		{friday.Add(1 * time.Hour), false}, // started Thursday
+	}
+	for _, tt := range tests {
+		if got := h.Contains(tt.at); got != tt.want {
+			t.Errorf("Contains(%s) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+
+	if !(*Hours)(nil).Contains(friday) {
+		t.Error("expected nil hours to contain every time")
+	}
+}
+
+// panickyProvider panics on its first fetch, as a parser might on a
+// payload shape it does not expect
+type panickyProvider struct {
+	calls atomic.Int32
+}
+
+func (p *panickyProvider) Fetch(ctx context.Context) (string, error) {
+	if p.calls.Add(1) == 1 {
+		var fields map[string]interface{}
+		_ = fields["now"].(map[string]interface{})["title"]
+	}
+	return "StreamTitle='Song';", nil
+}
+
+func TestStation_ProviderPanicIsRecovered(t *testing.T) {
+	prov := &panickyProvider{}
+	s := New(Config{ID: "test", PollInterval: time.Hour}, nil, prov, nil)
+
+	if s.pollProvider(s.providers[0]) {
+		t.Fatal("expected panicking fetch to count as a failure")
+	}
+
+	status := s.ProviderStatus()[0]
+	if status.Panics != 1 || !strings.Contains(status.LastError, "panic") {
+		t.Errorf("expected one recorded panic, got %+v", status)
+	}
+
+	if !s.pollProvider(s.providers[0]) || s.CurrentMetadata() != "StreamTitle='Song';" {
+		t.Errorf("expected the next poll to succeed, got %q", s.CurrentMetadata())
+	}
+}
+
+func TestStation_ScheduledTitle(t *testing.T) {
+	// Mon-Fri 22:00-06:00, wrapping past midnight
+	sched := &Schedule{
+		Location: time.FixedZone("CST", -6*3600),
+		Titles: []ScheduledTitle{{
+			Title: "Overnight Mix",
+			Start: 22 * time.Hour,
+			End:   6 * time.Hour,
+			Days:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		}},
+	}
+	s := New(Config{ID: "test", Schedule: sched}, nil, nil, nil)
+	s.UpdateMetadata("StreamTitle='Feed Song';")
+
+	cases := []struct {
+		local string // in the schedule's zone
+		want  string
+	}{
+		{"2024-05-06 21:59", "StreamTitle='Feed Song';"},     // Monday evening, before start
+		{"2024-05-06 22:00", "StreamTitle='Overnight Mix';"}, // Monday 22:00
+		{"2024-05-07 05:59", "StreamTitle='Overnight Mix';"}, // Tuesday morning, started Monday
+		{"2024-05-07 06:00", "StreamTitle='Feed Song';"},
+		{"2024-05-12 02:00", "StreamTitle='Feed Song';"},     // Sunday morning, started Saturday
+		{"2024-05-11 01:00", "StreamTitle='Overnight Mix';"}, // Saturday morning, started Friday
+	}
+
+	for _, tc := range cases {
+		at, _ := time.ParseInLocation("2006-01-02 15:04", tc.local, sched.Location)
+		s.now = func() time.Time { return at.UTC() }
+
+		if got := s.CurrentMetadata(); got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.local, tc.want, got)
+		}
+		if block := s.CurrentMetadataBlock(); !bytes.Equal(block, icy.BuildBlock(tc.want)) {
+			t.Errorf("%s: ICY block does not match metadata", tc.local)
+		}
+	}
+}
+
+func TestStation_MetadataChangeTracking(t *testing.T) {
+	s := New(Config{ID: "test", DefaultTitle: "Default"}, nil, nil, nil)
+
+	if s.MetadataChangeCount() != 0 || s.LastMetadataChange() != nil {
+		t.Fatal("expected no changes before the first fetch")
+	}
+
+	s.UpdateMetadata("StreamTitle='A';")
+	first := s.LastMetadataChange()
+	s.UpdateMetadata("StreamTitle='A';") // same value re-polled
+	if n := s.MetadataChangeCount(); n != 1 {
+		t.Errorf("expected 1 change after repeated polls, got %d", n)
+	}
+	if s.LastMetadataChange() != first {
+		t.Error("expected last change time to stay put when the value repeats")
+	}
+
+	s.UpdateMetadata("StreamTitle='B';")
+	if n := s.MetadataChangeCount(); n != 2 {
+		t.Errorf("expected 2 changes, got %d", n)
+	}
+}
+
+func TestStation_DetectedBitrate(t *testing.T) {
+	meter := audio.NewBitrateMeter()
+	s := New(Config{ID: "test", BitrateHint: 192, Bitrate: meter}, nil, nil, nil)
+
+	if s.Bitrate() != 192 || s.DetectedBitrate() != 0 {
+		t.Fatalf("expected hint before detection, got %d", s.Bitrate())
+	}
+
+	// One MPEG-1 Layer III 128 kbps frame
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+	meter.Write(frame)
+
+	if s.Bitrate() != 128 || s.BitrateHint() != 192 {
+		t.Errorf("expected detected 128 over hint 192, got %d (hint %d)", s.Bitrate(), s.BitrateHint())
+	}
+}
+
+func TestStation_SubscribeBurst(t *testing.T) {
+	s := New(Config{ID: "test", ChunkBusCap: 4}, nil, nil, ring.New(1024))
+	s.distribute([]byte("0123456789"))
+
+	burst, ch := s.SubscribeBurst(&Client{}, 4)
+	if string(burst) != "6789" {
+		t.Errorf("expected burst of the newest 4 bytes, got %q", burst)
+	}
+
+	// Chunks distributed after subscribing are not part of the burst
+	s.distribute([]byte("abc"))
+	if got := <-ch; string(got) != "abc" {
+		t.Errorf("expected live chunk abc, got %q", got)
+	}
+
+	if burst, _ := s.SubscribeBurst(&Client{}, 0); burst != nil {
+		t.Errorf("expected no burst when disabled, got %q", burst)
+	}
+}
+
+func TestStation_SlowerMetadataPollsWhileSourceDown(t *testing.T) {
+	meta := &countingMetadataProvider{}
+	s := New(Config{
+		ID:               "test",
+		PollInterval:     10 * time.Millisecond,
+		DownPollInterval: time.Hour,
+		ReconnectInitial: time.Hour,
+	}, &rejectingSource{err: errors.New("off air")}, meta, ring.New(1024))
+	s.Start()
+	defer s.Shutdown()
+
+	// Only the initial fetch happens during the outage
+	time.Sleep(100 * time.Millisecond)
+	if calls := meta.calls.Load(); calls != 1 {
+		t.Fatalf("expected 1 fetch while the source is down, got %d", calls)
+	}
+
+	// Recovery wakes the poller and restores the normal cadence
+	s.SetSourceHealthy(true)
+	time.Sleep(100 * time.Millisecond)
+	if calls := meta.calls.Load(); calls < 4 {
+		t.Errorf("expected polling to resume after recovery, got %d fetches", calls)
+	}
+}
+
+func TestStation_ICYFields(t *testing.T) {
+	s := New(Config{ID: "test", ICYFields: []icy.Field{
+		{Name: "StationName", Value: "Test FM"},
+		{Name: "Show", Value: "Live {Uncut}"},
+		{Name: "Artist", Value: "{artist}"},
+	}}, nil, nil, nil)
+
+	// Placeholders aren't sent raw before any fields are known, but
+	// literal braces are just text
+	s.UpdateMetadata("StreamTitle='Song';")
+	if got := parseICYBlock(t, s.CurrentMetadataBlock()); !slices.Equal(got, []string{
+		"StreamTitle=Song", "StationName=Test FM", "Show=Live {Uncut}",
+	}) {
+		t.Errorf("got %q", got)
+	}
+
+	s.UpdateMetadataFields("StreamTitle='Blondie - Atomic';", map[string]string{"artist": "Blondie", "title": "Atomic"})
+	block := s.CurrentMetadataBlock()
+	if got := parseICYBlock(t, block); !slices.Equal(got, []string{
+		"StreamTitle=Blondie - Atomic", "StationName=Test FM", "Show=Live {Uncut}", "Artist=Blondie",
+	}) {
+		t.Errorf("got %q", got)
+	}
+
+	// Players that only know StreamTitle read up to the first "';"
+	text := string(block[1:])
+	start := strings.Index(text, "StreamTitle='") + len("StreamTitle='")
+	if title := text[start : start+strings.Index(text[start:], "';")]; title != "Blondie - Atomic" {
+		t.Errorf("naive StreamTitle parse got %q", title)
+	}
+
+	// The HTTP endpoints keep the bare title
+	if got := s.CurrentMetadata(); got != "StreamTitle='Blondie - Atomic';" {
+		t.Errorf("CurrentMetadata = %q", got)
+	}
+}
+
+// parseICYBlock strictly decodes a metadata block into Key=value pairs,
+// failing on anything that isn't a run of Key='value'; fields
+func parseICYBlock(t *testing.T, block []byte) []string {
+	t.Helper()
+	if len(block) != 1+int(block[0])*16 {
+		t.Fatalf("block length %d doesn't match length byte %d", len(block), block[0])
+	}
+	text := strings.TrimRight(string(block[1:]), "\x00")
+
+	var pairs []string
+	for text != "" {
+		key, rest, ok := strings.Cut(text, "='")
+		if !ok || key == "" {
+			t.Fatalf("malformed field in %q", text)
+		}
+		value, rest, ok := strings.Cut(rest, "';")
+		if !ok {
+			t.Fatalf("unterminated value in %q", text)
+		}
+		pairs = append(pairs, key+"="+value)
+		text = rest
+	}
+	if len(pairs) == 0 || !strings.HasPrefix(pairs[0], "StreamTitle=") {
+		t.Fatalf("StreamTitle must come first, got %q", pairs)
+	}
+	return pairs
+}
+
+// emptyReader returns (0, nil) for its first empty reads, then data
+type emptyReader struct {
+	empty int32
+	reads atomic.Int32
+	data  []byte
+}
+
+func (e *emptyReader) Read(p []byte) (int, error) {
+	if e.reads.Add(1) <= e.empty {
+		return 0, nil
+	}
+	if len(e.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, e.data)
+	e.data = e.data[n:]
+	return n, nil
+}
+
+func (e *emptyReader) Close() error { return nil }
+
+type readerSource struct{ r io.ReadCloser }
+
+func (rs *readerSource) Connect(ctx context.Context) (io.ReadCloser, error) {
+	return rs.r, nil
+}
+
+func TestStation_EmptyReadsBackOff(t *testing.T) {
+	// A source stuck on empty reads must not spin the reader loop
+	stuck := &emptyReader{empty: math.MaxInt32}
+	s := New(Config{ID: "test", ChunkBusCap: 32, ReconnectInitial: time.Hour}, &readerSource{stuck}, nil, ring.New(1024))
+	s.Start()
+	time.Sleep(200 * time.Millisecond)
+	s.Shutdown()
+	if reads := stuck.reads.Load(); reads > 50 {
+		t.Errorf("expected empty reads to back off, got %d reads in 200ms", reads)
+	}
+
+	// Data after a run of empty reads still gets through
+	src := &emptyReader{empty: 5, data: []byte("late audio")}
+	buffer := ring.New(1024)
+	s = New(Config{ID: "test", ChunkBusCap: 32, ReconnectInitial: time.Hour}, &readerSource{src}, nil, buffer)
+	s.Start()
+	defer s.Shutdown()
+
+	deadline := time.Now().Add(time.Second)
+	for !bytes.Contains(buffer.Snapshot(), []byte("late audio")) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected audio after empty reads")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestEmptyReadBackoff(t *testing.T) {
+	if got := emptyReadBackoff(1); got != time.Millisecond {
+		t.Errorf("first backoff = %s", got)
+	}
+	if got := emptyReadBackoff(4); got != 8*time.Millisecond {
+		t.Errorf("fourth backoff = %s", got)
+	}
+	if got := emptyReadBackoff(1000); got != 100*time.Millisecond {
+		t.Errorf("backoff should cap at 100ms, got %s", got)
+	}
+}
+
+// blockingReader blocks Read until closed, then lingers before returning
+type blockingReader struct {
+	closed   chan struct{}
+	once     sync.Once
+	returned atomic.Bool
+}
+
+func (b *blockingReader) Read(p []byte) (int, error) {
+	<-b.closed
+	time.Sleep(50 * time.Millisecond)
+	b.returned.Store(true)
+	return 0, io.ErrClosedPipe
+}
+
+func (b *blockingReader) Close() error {
+	b.once.Do(func() { close(b.closed) })
+	return nil
+}
+
+func TestStation_ShutdownWaitsForGoroutines(t *testing.T) {
+	reader := &blockingReader{closed: make(chan struct{})}
+	s := New(Config{ID: "test", ChunkBusCap: 32}, &readerSource{reader}, nil, ring.New(1024))
+	s.Start()
+
+	deadline := time.Now().Add(time.Second)
+	for !s.SourceHealthy() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The stream ignores ctx, so only closing it unblocks the reader
+	s.Shutdown()
+	if !reader.returned.Load() {
+		t.Error("Shutdown returned before the source reader exited")
+	}
+}
+
+func TestStation_Restore(t *testing.T) {
+	s := New(Config{ID: "test", DefaultTitle: "Default"}, nil, nil, nil)
+	if _, _, ok := s.LastKnownMetadata(); ok {
+		t.Error("expected no last-known metadata before a fetch")
+	}
+
+	s.Restore("StreamTitle='Saved';", map[string]string{"title": "Saved"})
+	if got := s.CurrentMetadata(); got != "StreamTitle='Saved';" {
+		t.Errorf("CurrentMetadata = %q, want restored title", got)
+	}
+	if !s.Provisional() || s.Fields()["title"] != "Saved" {
+		t.Error("expected restored metadata to stay provisional with its fields")
+	}
+
+	s.UpdateMetadata("StreamTitle='Live';")
+	s.Restore("StreamTitle='Saved';", nil)
+	meta, _, ok := s.LastKnownMetadata()
+	if !ok || meta != "StreamTitle='Live';" {
+		t.Errorf("expected fetched metadata to win over a late Restore, got %q", meta)
+	}
+}
+
+func TestStation_FanoutWorkersDeliverInOrder(t *testing.T) {
+	s := New(Config{ID: "test", FanoutWorkers: 4}, nil, nil, ring.New(1<<16))
+
+	clients := make([]<-chan []byte, 4*minFanoutShard)
+	for i := range clients {
+		clients[i] = s.Subscribe(&Client{})
+	}
+
+	for i := 0; i < clientChanCap; i++ {
+		s.distribute([]byte{byte(i)})
+	}
+	for i, ch := range clients {
+		for want := 0; want < clientChanCap; want++ {
+			if got := <-ch; got[0] != byte(want) {
+				t.Fatalf("client %d: chunk %d out of order, got %d", i, want, got[0])
+			}
+		}
+	}
+
+	// Full clients drop chunks rather than blocking the fan-out
+	for i := 0; i < clientChanCap+1; i++ {
+		s.distribute([]byte{1})
+	}
+	if len(clients[0]) != clientChanCap {
+		t.Errorf("expected a full channel, got %d chunks", len(clients[0]))
+	}
+}
+
+func TestStation_UnsubscribeDuringFanout(t *testing.T) {
+	s := New(Config{ID: "test", FanoutWorkers: 2}, nil, nil, ring.New(1<<16))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			s.distribute([]byte("audio"))
+		}
+	}()
+
+	// Channels closing while chunks are being sent must not panic
+	for i := 0; i < 200; i++ {
+		c := &Client{}
+		s.Subscribe(c)
+		s.Unsubscribe(c)
+	}
+	<-done
+
+	// Hold sendMu so the fan-out stops between its snapshot and the sends,
+	// then try to remove and close the client the way Unsubscribe does. The
+	// fan-out must still hold clientsMu, or the close lands first and its
+	// send panics.
+	c := &Client{}
+	s.Subscribe(c)
+	s.sendMu.Lock()
+	panicked := make(chan interface{}, 1)
+	done = make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() { panicked <- recover() }()
+		s.distribute([]byte("audio"))
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	if s.clientsMu.TryLock() {
+		ch := c.ch
+		delete(s.clients, c)
+		c.ch = nil
+		s.clientsMu.Unlock()
+		close(ch)
+	}
+	s.sendMu.Unlock()
+	<-done
+	if r := <-panicked; r != nil {
+		t.Errorf("fan-out sent to a channel closed after its snapshot: %v", r)
+	}
+	s.Unsubscribe(c)
+}
+
+// benchFanOut subscribes n clients whose channels are drained in the
+// background, then times distribute while other clients churn
+func benchFanOut(b *testing.B, n int, distribute func(s *Station, chunk []byte), workers int) {
+	s := New(Config{ID: "bench", FanoutWorkers: workers}, nil, nil, ring.New(1<<16))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for i := 0; i < n; i++ {
+		c := &Client{}
+		ch := s.Subscribe(c)
+		defer s.Unsubscribe(c) // ends the drain goroutine
+		go func() {
+			for range ch {
+			}
+		}()
+	}
+	go func() {
+		for ctx.Err() == nil {
+			c := &Client{}
+			s.Subscribe(c)
+			s.Unsubscribe(c)
+		}
+	}()
+
+	chunk := make([]byte, 8192)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		distribute(s, chunk)
+	}
+}
+
+// lockHeldDistribute is the fan-out as it was before snapshotting: every
+// send happens under clientsMu
+func lockHeldDistribute(s *Station, chunk []byte) {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+
+	s.buffer.Write(chunk)
+	for client := range s.clients {
+		if client.ch != nil {
+			s.clientFill.Observe(float64(len(client.ch)))
+			select {
+			case client.ch <- chunk:
+			default:
+			}
+		}
+	}
+}
+
+func BenchmarkFanOut_LockHeld(b *testing.B) {
+	benchFanOut(b, 5000, lockHeldDistribute, 1)
+}
+
+func BenchmarkFanOut_Snapshot(b *testing.B) {
+	for _, workers := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			benchFanOut(b, 5000, (*Station).distribute, workers)
+		})
+	}
+}
+
+func TestStation_VariantsFollowMetadata(t *testing.T) {
+	low := New(Config{ID: "test/low", MetaInt: 16384, DefaultTitle: "Loading"}, nil, nil, nil)
+	primary := &structuredMetadataProvider{
+		mockMetadataProvider: mockMetadataProvider{meta: "StreamTitle='Artist - Song';"},
+		fields:               map[string]string{"title": "Song"},
+	}
+	s := New(Config{ID: "test", MetaInt: 16384, Variants: map[string]*Station{"low": low}}, nil, primary, nil)
+
+	if s.Variant("low") != low || s.Variant("high") != nil {
+		t.Fatal("expected only the low variant")
+	}
+	if names := s.VariantNames(); len(names) != 1 || names[0] != "low" {
+		t.Errorf("unexpected variant names %v", names)
+	}
+
+	s.pollMetadata()
+	if meta := low.CurrentMetadata(); meta != "StreamTitle='Artist - Song';" {
+		t.Errorf("expected variant to follow the parent's title, got %q", meta)
+	}
+	if low.Fields()["title"] != "Song" {
+		t.Errorf("expected variant to share fields, got %v", low.Fields())
+	}
+	if low.Provisional() {
+		t.Error("expected variant to stop being provisional once the parent fetched")
+	}
+}
+
+func TestStation_SniffContentType(t *testing.T) {
+	ogg := io.NopCloser(strings.NewReader("OggS\x00\x02\x00\x00\x00\x00\x00\x00\x00\x00"))
+	s := New(Config{ID: "test", ContentType: "audio/mpeg", SniffContent: true, ChunkBusCap: 32, ReconnectInitial: time.Hour}, &readerSource{ogg}, nil, ring.New(1024))
+	if s.ContentType() != "audio/mpeg" || !s.InjectsICY() {
+		t.Fatal("expected the configured type before any audio")
+	}
+
+	s.Start()
+	defer s.Shutdown()
+
+	deadline := time.Now().Add(time.Second)
+	for s.ContentType() != "audio/ogg" {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected sniffed audio/ogg, got %s", s.ContentType())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if s.InjectsICY() {
+		t.Error("expected no ICY injection once the stream is known to be Ogg")
+	}
+
+	// A later connection with inconclusive bytes falls back to the config
+	s.sniffContentType([]byte("not audio"))
+	if s.ContentType() != "audio/mpeg" || !s.InjectsICY() {
+		t.Errorf("expected fallback to audio/mpeg, got %s", s.ContentType())
+	}
+}
+
+func TestStation_SniffKeepsConfiguredTypeOfSameCodec(t *testing.T) {
+	// Two ADTS frame headers: 0xFFF1 sync, AAC LC at 44.1 kHz, 372 bytes
+	frame := make([]byte, 372)
+	copy(frame, []byte{0xFF, 0xF1, 0x50, 0x80, 372 >> 3, (372&0x07)<<5 | 0x1F, 0xFC})
+	adts := append(slices.Clone(frame), frame...)
+
+	s := New(Config{ID: "test", ContentType: "audio/aacp", SniffContent: true}, nil, nil, ring.New(1024))
+	s.sniffContentType(adts)
+	if got := s.ContentType(); got != "audio/aacp" {
+		t.Errorf("expected the configured HE-AAC type kept for ADTS audio, got %s", got)
+	}
+
+	s.sniffContentType([]byte("OggS\x00\x02\x00\x00"))
+	if got := s.ContentType(); got != "audio/ogg" {
+		t.Errorf("expected another codec family to override, got %s", got)
+	}
+}
+
+func TestStation_MetersFollowSniffedType(t *testing.T) {
+	s := New(Config{
+		ID:          "test",
+		ContentType: "audio/mpeg",
+		Levels:      audio.NewLevelMeter(time.Second),
+		Bitrate:     audio.NewBitrateMeter(),
+	}, nil, nil, ring.New(1024))
+
+	if m := s.metersFor("audio/mpeg"); m.levels == nil || m.bitrate == nil {
+		t.Error("expected levels and bitrate detection for MP3")
+	}
+	if m := s.metersFor("audio/aac"); m.levels != nil || m.bitrate == nil {
+		t.Error("expected only bitrate detection for AAC")
+	}
+	if m := s.metersFor("audio/ogg"); m.levels != nil || m.bitrate != nil {
+		t.Error("expected neither meter for Ogg")
+	}
+}
+
+func TestStation_ShutdownDisconnectsClients(t *testing.T) {
+	s := New(Config{ID: "test", ChunkBusCap: 32}, endlessSource{}, nil, ring.New(16384))
+	s.Start()
+
+	a, b := &Client{}, &Client{}
+	chA, chB := s.Subscribe(a), s.Subscribe(b)
+
+	s.Shutdown()
+
+	for _, ch := range []<-chan []byte{chA, chB} {
+		// Drain anything delivered before shutdown, then expect the close
+		for range ch {
+		}
+	}
+	if n := s.ClientCount(); n != 0 {
+		t.Errorf("expected no clients after shutdown, got %d", n)
+	}
+
+	// Handlers still unsubscribe on their way out
+	s.Unsubscribe(a)
+	s.Unsubscribe(b)
+
+	// A handler that looked the station up just before removal doesn't hang
+	late := s.Subscribe(&Client{})
+	if _, ok := <-late; ok {
+		t.Error("expected a closed channel when subscribing to a stopped station")
+	}
+	if n := s.ClientCount(); n != 0 {
+		t.Errorf("expected late subscriber not to be registered, got %d clients", n)
+	}
+}
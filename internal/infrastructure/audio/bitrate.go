@@ -0,0 +1,56 @@
+// ABOUTME: Bitrate detection from MP3 and AAC ADTS frame headers
+// ABOUTME: Averages bytes over a window of audio time so VBR streams settle
+package audio
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// bitrateWindow is the audio time averaged per estimate
+const bitrateWindow = 5 * time.Second
+
+// BitrateMeter detects a stream's bitrate from its frame headers
+type BitrateMeter struct {
+	mu       sync.Mutex
+	frames   frameScanner
+	bytes    int
+	duration time.Duration
+	kbps     int
+}
+
+func NewBitrateMeter() *BitrateMeter {
+	return &BitrateMeter{}
+}
+
+// Write feeds stream bytes to the meter; it never fails
+func (m *BitrateMeter) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.frames.write(p, func(h FrameHeader, _ []byte) {
+		m.bytes += h.Length
+		m.duration += time.Duration(h.Samples) * time.Second / time.Duration(h.SampleRate)
+
+		// Constant bitrate MP3 is known from the first frame; everything
+		// else waits for a full window
+		if m.kbps == 0 && !h.AAC && m.duration < bitrateWindow {
+			m.kbps = h.Bitrate
+		}
+		if m.duration < bitrateWindow {
+			return
+		}
+
+		m.kbps = int(math.Round(float64(m.bytes*8) / m.duration.Seconds() / 1000))
+		m.bytes, m.duration = 0, 0
+	})
+	return len(p), nil
+}
+
+// Kbps returns the detected bitrate, 0 until one is known
+func (m *BitrateMeter) Kbps() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.kbps
+}
@@ -0,0 +1,68 @@
+// ABOUTME: Tests for ADTS parsing and bitrate detection
+// ABOUTME: Covers constant-rate MP3 and window-averaged VBR AAC streams
+package audio
+
+import "testing"
+
+// adtsFrame builds an AAC ADTS frame of length bytes at 44.1 kHz stereo
+func adtsFrame(length int) []byte {
+	frame := make([]byte, length)
+	frame[0] = 0xFF
+	frame[1] = 0xF1                         // MPEG-4, no CRC
+	frame[2] = 0x50                         // AAC LC, 44.1 kHz, channel config high bit 0
+	frame[3] = 0x80 | byte(length>>11)&0x03 // stereo
+	frame[4] = byte(length >> 3)
+	frame[5] = byte(length&0x07)<<5 | 0x1F
+	frame[6] = 0xFC
+	return frame
+}
+
+func TestParseFrameHeader_ADTS(t *testing.T) {
+	h, ok := ParseFrameHeader(adtsFrame(372))
+	if !ok {
+		t.Fatal("expected valid ADTS header")
+	}
+	if !h.AAC || h.SampleRate != 44100 || h.Channels != 2 || h.Length != 372 || h.Samples != 1024 {
+		t.Errorf("unexpected header %+v", h)
+	}
+	// 372 bytes per 1024 samples at 44.1 kHz is ~128 kbps
+	if h.Bitrate != 128 {
+		t.Errorf("expected 128 kbps, got %d", h.Bitrate)
+	}
+}
+
+func TestBitrateMeter_MP3(t *testing.T) {
+	m := NewBitrateMeter()
+	if m.Kbps() != 0 {
+		t.Fatal("expected unknown bitrate before audio")
+	}
+
+	m.Write(mp3Frame(150, 100)[:200])
+	m.Write(mp3Frame(150, 100)[200:])
+	if kbps := m.Kbps(); kbps != 128 {
+		t.Errorf("expected 128 kbps from the first frame, got %d", kbps)
+	}
+}
+
+func TestBitrateMeter_AACWindowAverage(t *testing.T) {
+	m := NewBitrateMeter()
+
+	// Alternate frame sizes like VBR; average is ~96 kbps
+	var stream []byte
+	for i := 0; i < 300; i++ {
+		size := 200
+		if i%2 == 1 {
+			size = 358
+		}
+		stream = append(stream, adtsFrame(size)...)
+	}
+	for len(stream) > 0 {
+		n := min(4096, len(stream))
+		m.Write(stream[:n])
+		stream = stream[n:]
+	}
+
+	if kbps := m.Kbps(); kbps < 94 || kbps > 98 {
+		t.Errorf("expected about 96 kbps, got %d", kbps)
+	}
+}
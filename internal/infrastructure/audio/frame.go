@@ -0,0 +1,219 @@
+// ABOUTME: MPEG audio, AAC ADTS frame header and Layer III side-info parsing
+// ABOUTME: Reads only the few bytes needed to walk frames, size them and find gains
+package audio
+
+// FrameHeader describes one MPEG audio or AAC ADTS frame
+type FrameHeader struct {
+	AAC        bool
+	Version    int // 1 = MPEG-1, 2 = MPEG-2, 25 = MPEG-2.5, 4 = MPEG-4 (AAC)
+	Layer      int // 1, 2 or 3; 0 for AAC
+	CRC        bool
+	Bitrate    int // kbps; for AAC, this frame's effective rate
+	SampleRate int // Hz
+	Padding    bool
+	Channels   int
+	Length     int // total frame length in bytes, header included
+	Samples    int // samples per channel in the frame
+}
+
+var bitrates = map[[2]int][15]int{
+	{1, 1}: {0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+	{1, 2}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+	{1, 3}: {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	{2, 1}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+	{2, 2}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	{2, 3}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+}
+
+var sampleRates = map[int][3]int{
+	1:  {44100, 48000, 32000},
+	2:  {22050, 24000, 16000},
+	25: {11025, 12000, 8000},
+}
+
+var adtsSampleRates = [...]int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+
+// ParseFrameHeader decodes a 4-byte MPEG audio or 7-byte ADTS frame
+// header. Free-format and reserved values are rejected since their frame
+// length is unknown.
+func ParseFrameHeader(b []byte) (FrameHeader, bool) {
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return FrameHeader{}, false
+	}
+	if b[1]&0xF6 == 0xF0 {
+		return parseADTSHeader(b)
+	}
+
+	var h FrameHeader
+	switch (b[1] >> 3) & 0x03 {
+	case 0:
+		h.Version = 25
+	case 2:
+		h.Version = 2
+	case 3:
+		h.Version = 1
+	default:
+		return FrameHeader{}, false
+	}
+
+	layer := int((b[1] >> 1) & 0x03)
+	if layer == 0 {
+		return FrameHeader{}, false
+	}
+	h.Layer = 4 - layer
+	h.CRC = b[1]&0x01 == 0
+
+	brIndex := int(b[2] >> 4)
+	srIndex := int((b[2] >> 2) & 0x03)
+	if brIndex == 0 || brIndex == 15 || srIndex == 3 {
+		return FrameHeader{}, false
+	}
+
+	table := h.Version
+	if table == 25 {
+		table = 2
+	}
+	h.Bitrate = bitrates[[2]int{table, h.Layer}][brIndex]
+	h.SampleRate = sampleRates[h.Version][srIndex]
+	h.Padding = b[2]&0x02 != 0
+
+	h.Channels = 2
+	if b[3]>>6 == 3 {
+		h.Channels = 1
+	}
+
+	pad := 0
+	if h.Padding {
+		pad = 1
+	}
+	switch {
+	case h.Layer == 1:
+		h.Samples = 384
+		h.Length = (12*h.Bitrate*1000/h.SampleRate + pad) * 4
+	case h.Layer == 2 || h.Version == 1:
+		h.Samples = 1152
+		h.Length = 144*h.Bitrate*1000/h.SampleRate + pad
+	default:
+		h.Samples = 576
+		h.Length = 72*h.Bitrate*1000/h.SampleRate + pad
+	}
+
+	return h, true
+}
+
+// parseADTSHeader decodes an AAC ADTS header (layer bits 00 after the sync)
+func parseADTSHeader(b []byte) (FrameHeader, bool) {
+	if len(b) < 7 {
+		return FrameHeader{}, false
+	}
+
+	srIndex := int((b[2] >> 2) & 0x0F)
+	if srIndex >= len(adtsSampleRates) {
+		return FrameHeader{}, false
+	}
+
+	h := FrameHeader{
+		AAC:        true,
+		Version:    4,
+		CRC:        b[1]&0x01 == 0,
+		SampleRate: adtsSampleRates[srIndex],
+		Channels:   int((b[2]&0x01)<<2 | b[3]>>6),
+		Length:     int(b[3]&0x03)<<11 | int(b[4])<<3 | int(b[5]>>5),
+		Samples:    1024 * (int(b[6]&0x03) + 1),
+	}
+	if b[1]&0x08 != 0 {
+		h.Version = 2
+	}
+	if h.Length < 7 {
+		return FrameHeader{}, false
+	}
+	h.Bitrate = h.Length * 8 * h.SampleRate / h.Samples / 1000
+	return h, true
+}
+
+// sideInfoLen is the Layer III side-info size in bytes
+func (h FrameHeader) sideInfoLen() int {
+	switch {
+	case h.Version == 1 && h.Channels == 1:
+		return 17
+	case h.Version == 1:
+		return 32
+	case h.Channels == 1:
+		return 9
+	default:
+		return 17
+	}
+}
+
+// granule holds the side-info fields used for level estimation
+type granule struct {
+	bigValues  int
+	globalGain int
+}
+
+// parseSideInfo extracts per-granule, per-channel gains from a Layer III
+// frame. frame must hold at least the header, CRC and side info.
+func parseSideInfo(h FrameHeader, frame []byte) ([]granule, bool) {
+	off := 4
+	if h.CRC {
+		off += 2
+	}
+	if h.Layer != 3 || len(frame) < off+h.sideInfoLen() {
+		return nil, false
+	}
+
+	r := bitReader{data: frame[off : off+h.sideInfoLen()]}
+	granules := 2
+	if h.Version == 1 {
+		r.skip(9) // main_data_begin
+		if h.Channels == 1 {
+			r.skip(5)
+		} else {
+			r.skip(3)
+		}
+		r.skip(4 * h.Channels) // scfsi
+	} else {
+		granules = 1
+		r.skip(8)
+		r.skip(h.Channels) // private bits
+	}
+
+	out := make([]granule, 0, granules*h.Channels)
+	for gr := 0; gr < granules; gr++ {
+		for ch := 0; ch < h.Channels; ch++ {
+			r.skip(12) // part2_3_length
+			g := granule{bigValues: r.read(9), globalGain: r.read(8)}
+			if h.Version == 1 {
+				r.skip(4 + 1 + 22 + 3) // scalefac_compress .. count1table_select
+			} else {
+				r.skip(9 + 1 + 22 + 2)
+			}
+			out = append(out, g)
+		}
+	}
+	return out, true
+}
+
+// bitReader reads big-endian bit fields
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) read(n int) int {
+	v := 0
+	for i := 0; i < n; i++ {
+		byteIdx := r.pos / 8
+		bit := 0
+		if byteIdx < len(r.data) {
+			bit = int(r.data[byteIdx]>>(7-r.pos%8)) & 1
+		}
+		v = v<<1 | bit
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) skip(n int) {
+	r.pos += n
+}
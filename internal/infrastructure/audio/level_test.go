@@ -0,0 +1,112 @@
+// ABOUTME: Tests for MPEG frame parsing and level estimation
+// ABOUTME: Builds synthetic Layer III frames with known global gains
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// bitWriter packs big-endian bit fields for synthetic side info
+type bitWriter struct {
+	data []byte
+	pos  int
+}
+
+func (w *bitWriter) write(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if w.pos/8 >= len(w.data) {
+			w.data = append(w.data, 0)
+		}
+		if (v>>i)&1 == 1 {
+			w.data[w.pos/8] |= 1 << (7 - w.pos%8)
+		}
+		w.pos++
+	}
+}
+
+// mp3Frame builds an MPEG-1 Layer III 128 kbps 44.1 kHz stereo frame
+func mp3Frame(globalGain, bigValues int) []byte {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+
+	w := &bitWriter{}
+	w.write(0, 9) // main_data_begin
+	w.write(0, 3) // private bits
+	w.write(0, 8) // scfsi
+	for i := 0; i < 4; i++ {
+		w.write(0, 12)
+		w.write(bigValues, 9)
+		w.write(globalGain, 8)
+		w.write(0, 4+1+22+3)
+	}
+	copy(frame[4:], w.data)
+	return frame
+}
+
+func TestParseFrameHeader(t *testing.T) {
+	h, ok := ParseFrameHeader([]byte{0xFF, 0xFB, 0x90, 0x00})
+	if !ok {
+		t.Fatal("expected valid header")
+	}
+	if h.Version != 1 || h.Layer != 3 || h.Bitrate != 128 || h.SampleRate != 44100 || h.Channels != 2 || h.Length != 417 {
+		t.Errorf("unexpected header %+v", h)
+	}
+
+	if h, ok := ParseFrameHeader([]byte{0xFF, 0xF3, 0x92, 0xC0}); !ok || h.Version != 2 || h.Channels != 1 || h.Samples != 576 || !h.Padding {
+		t.Errorf("unexpected MPEG-2 header %+v ok=%v", h, ok)
+	}
+
+	for _, bad := range [][]byte{
+		{0xFF, 0xFB, 0xF0, 0x00}, // bitrate index 15
+		{0xFF, 0xFB, 0x00, 0x00}, // free format
+		{0xFF, 0xFB, 0x9C, 0x00}, // reserved sample rate
+		{0xFF, 0xE9, 0x90, 0x00}, // reserved version
+		{0x00, 0xFB, 0x90, 0x00},
+	} {
+		if _, ok := ParseFrameHeader(bad); ok {
+			t.Errorf("expected %x to be rejected", bad)
+		}
+	}
+}
+
+func TestLevelMeter_EstimatesFromGain(t *testing.T) {
+	m := NewLevelMeter(250 * time.Millisecond)
+	if _, ok := m.Level(); ok {
+		t.Fatal("expected no level before any audio")
+	}
+
+	// Junk before the first frame and odd chunk sizes across frame edges
+	var stream []byte
+	stream = append(stream, "ID3junk"...)
+	for i := 0; i < 20; i++ {
+		stream = append(stream, mp3Frame(150, 100)...)
+	}
+	for len(stream) > 0 {
+		n := min(333, len(stream))
+		m.Write(stream[:n])
+		stream = stream[n:]
+	}
+
+	lvl, ok := m.Level()
+	if !ok {
+		t.Fatal("expected a level after 20 frames")
+	}
+	// 20 gain steps below full scale at 1.5 dB per step
+	if math.Abs(lvl.RMS-(-30.1)) > 0.5 || math.Abs(lvl.Peak-(-30.1)) > 0.5 {
+		t.Errorf("expected about -30 dBFS, got rms=%.1f peak=%.1f", lvl.RMS, lvl.Peak)
+	}
+}
+
+func TestLevelMeter_Silence(t *testing.T) {
+	m := NewLevelMeter(100 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		m.Write(mp3Frame(170, 0))
+	}
+
+	lvl, ok := m.Level()
+	if !ok || lvl.RMS != SilenceDB || lvl.Peak != SilenceDB {
+		t.Errorf("expected silence, got %+v ok=%v", lvl, ok)
+	}
+}
@@ -0,0 +1,266 @@
+// ABOUTME: In-band Ogg tag reader for Vorbis, Opus and FLAC streams
+// ABOUTME: Walks Ogg pages, reassembles comment packets and keeps the latest tags
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"sync"
+)
+
+// maxCommentPacket bounds a reassembled comment packet; larger ones
+// (usually embedded cover art) are skipped rather than buffered
+const maxCommentPacket = 256 * 1024
+
+// maxPageBytes is the largest possible Ogg page: a 27-byte header, 255
+// lacing values and 255 segments of 255 bytes
+const maxPageBytes = 27 + 255 + 255*255
+
+var oggCapture = []byte("OggS")
+
+// Ogg codecs whose comment headers OggTags understands
+const (
+	codecUnknown = iota
+	codecVorbis
+	codecOpus
+	codecFLAC
+)
+
+// OggTags extracts Vorbis comments (ARTIST=..., TITLE=...) carried in an Ogg
+// stream. Internet radio starts a new logical bitstream, with fresh comment
+// headers, for every track, so the latest comments are the now-playing tags.
+type OggTags struct {
+	mu      sync.Mutex
+	pending []byte
+	streams map[uint32]*oggStream
+	tags    map[string]string
+}
+
+// oggStream is the packet reassembly state of one logical bitstream
+type oggStream struct {
+	joined   bool // first seen mid-stream, so its headers were missed
+	codec    int
+	packets  int // packets completed so far
+	inPacket bool
+	skip     bool
+	packet   []byte
+}
+
+func NewOggTags() *OggTags {
+	return &OggTags{streams: make(map[uint32]*oggStream)}
+}
+
+// Tags returns the latest comments with lowercased keys, or nil before any
+// comment header has been seen
+func (o *OggTags) Tags() map[string]string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.tags
+}
+
+// Write feeds stream bytes; it never fails
+func (o *OggTags) Write(p []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.pending = append(o.pending, p...)
+	for {
+		start := bytes.Index(o.pending, oggCapture)
+		if start < 0 {
+			// Keep a possible partial capture pattern for the next write
+			o.pending = o.pending[max(0, len(o.pending)-3):]
+			return
+		}
+		o.pending = o.pending[start:]
+
+		n, ok := o.page(o.pending)
+		if !ok {
+			if len(o.pending) > maxPageBytes {
+				o.pending = o.pending[1:] // not a real page; resync
+				continue
+			}
+			return
+		}
+		o.pending = o.pending[n:]
+	}
+}
+
+// page handles one complete page at the start of b and returns its length,
+// or false when b doesn't hold a whole page yet
+func (o *OggTags) page(b []byte) (int, bool) {
+	if len(b) < 27 {
+		return 0, false
+	}
+	if b[4] != 0 {
+		// Unknown version: skip the capture pattern and resync
+		return 1, true
+	}
+
+	headerType := b[5]
+	serial := binary.LittleEndian.Uint32(b[14:18])
+	nsegs := int(b[26])
+	if len(b) < 27+nsegs {
+		return 0, false
+	}
+	lacing := b[27 : 27+nsegs]
+
+	size := 27 + nsegs
+	for _, l := range lacing {
+		size += int(l)
+	}
+	if len(b) < size {
+		return 0, false
+	}
+
+	st := o.streams[serial]
+	if headerType&0x02 != 0 || st == nil {
+		// Streams that never signal their end shouldn't accumulate
+		if len(o.streams) >= 16 {
+			clear(o.streams)
+		}
+		st = &oggStream{joined: headerType&0x02 == 0}
+		o.streams[serial] = st
+	}
+	switch {
+	case headerType&0x01 == 0:
+		st.inPacket = false
+	case !st.inPacket:
+		// Continuation of a packet whose start was missed
+		st.inPacket, st.skip, st.packet = true, true, nil
+	}
+
+	off := 27 + nsegs
+	for _, l := range lacing {
+		st.segment(o, b[off:off+int(l)], l < 255)
+		off += int(l)
+	}
+
+	if headerType&0x04 != 0 {
+		delete(o.streams, serial)
+	}
+	return size, true
+}
+
+// segment appends one lacing segment, handling the packet when it ends
+func (st *oggStream) segment(o *OggTags, seg []byte, last bool) {
+	if !st.inPacket {
+		st.inPacket, st.skip, st.packet = true, false, st.packet[:0]
+	}
+
+	if !st.skip {
+		st.packet = append(st.packet, seg...)
+		if len(st.packet) > maxCommentPacket || (len(st.packet) >= 8 && !st.wanted(st.packet)) {
+			st.skip, st.packet = true, nil
+		}
+	}
+
+	if !last {
+		return
+	}
+	st.inPacket = false
+
+	if !st.skip {
+		st.handle(o, st.packet)
+	}
+	st.packets++
+}
+
+// wanted reports whether the packet could be an identification or comment
+// header worth buffering in full
+func (st *oggStream) wanted(p []byte) bool {
+	if st.joined {
+		return false
+	}
+	if st.packets == 0 {
+		return true
+	}
+	// The comment header directly follows identification in every mapping
+	if st.packets > 1 {
+		return false
+	}
+	switch st.codec {
+	case codecVorbis:
+		return bytes.HasPrefix(p, []byte("\x03vorbis"))
+	case codecOpus:
+		return bytes.HasPrefix(p, []byte("OpusTags"))
+	case codecFLAC:
+		return p[0]&0x7F == 4 // VORBIS_COMMENT metadata block
+	}
+	return false
+}
+
+func (st *oggStream) handle(o *OggTags, p []byte) {
+	if st.packets == 0 {
+		switch {
+		case bytes.HasPrefix(p, []byte("\x01vorbis")):
+			st.codec = codecVorbis
+		case bytes.HasPrefix(p, []byte("OpusHead")):
+			st.codec = codecOpus
+		case bytes.HasPrefix(p, []byte("\x7FFLAC")):
+			st.codec = codecFLAC
+		}
+		return
+	}
+
+	var body []byte
+	switch {
+	case st.codec == codecVorbis && bytes.HasPrefix(p, []byte("\x03vorbis")):
+		body = p[7:]
+	case st.codec == codecOpus && bytes.HasPrefix(p, []byte("OpusTags")):
+		body = p[8:]
+	case st.codec == codecFLAC && len(p) >= 4 && p[0]&0x7F == 4:
+		body = p[4:]
+	default:
+		return
+	}
+
+	if tags, ok := parseVorbisComments(body); ok {
+		o.tags = tags
+	}
+}
+
+// parseVorbisComments decodes a comment block: a length-prefixed vendor
+// string, then a count of length-prefixed KEY=value entries, all little-endian.
+// Repeated keys are joined with ", ".
+func parseVorbisComments(b []byte) (map[string]string, bool) {
+	next := func() ([]byte, bool) {
+		if len(b) < 4 {
+			return nil, false
+		}
+		n := binary.LittleEndian.Uint32(b)
+		if uint64(n) > uint64(len(b)-4) {
+			return nil, false
+		}
+		field := b[4 : 4+n]
+		b = b[4+n:]
+		return field, true
+	}
+
+	if _, ok := next(); !ok { // vendor
+		return nil, false
+	}
+	if len(b) < 4 {
+		return nil, false
+	}
+	count := binary.LittleEndian.Uint32(b)
+	b = b[4:]
+
+	tags := make(map[string]string)
+	for i := uint32(0); i < count; i++ {
+		entry, ok := next()
+		if !ok {
+			return nil, false
+		}
+		key, value, found := strings.Cut(string(entry), "=")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(key)
+		if prev, dup := tags[key]; dup {
+			value = prev + ", " + value
+		}
+		tags[key] = value
+	}
+	return tags, true
+}
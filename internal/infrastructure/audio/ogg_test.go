@@ -0,0 +1,112 @@
+// ABOUTME: Tests for in-band Ogg tag extraction
+// ABOUTME: Builds Ogg pages by hand to cover Vorbis, Opus, FLAC and chained streams
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// oggPage builds a page holding data with the given header type; data is
+// laced as one packet, left unterminated when open is set
+func oggPage(serial uint32, headerType byte, data []byte, open bool) []byte {
+	var lacing []byte
+	rest := len(data)
+	for rest >= 255 {
+		lacing = append(lacing, 255)
+		rest -= 255
+	}
+	if !open {
+		lacing = append(lacing, byte(rest))
+	}
+
+	page := []byte("OggS")
+	page = append(page, 0, headerType)
+	page = append(page, make([]byte, 8)...) // granule position
+	page = binary.LittleEndian.AppendUint32(page, serial)
+	page = append(page, make([]byte, 8)...) // sequence number, CRC
+	page = append(page, byte(len(lacing)))
+	page = append(page, lacing...)
+	return append(page, data...)
+}
+
+func vorbisComments(entries ...string) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, 4)
+	b = append(b, "test"...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(entries)))
+	for _, e := range entries {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(e)))
+		b = append(b, e...)
+	}
+	return b
+}
+
+// track returns the pages of one logical stream: identification, comment, audio
+func track(serial uint32, id, commentPrefix []byte, entries ...string) []byte {
+	comment := append(append([]byte{}, commentPrefix...), vorbisComments(entries...)...)
+	var out []byte
+	out = append(out, oggPage(serial, 0x02, id, false)...)
+	out = append(out, oggPage(serial, 0x00, comment, false)...)
+	out = append(out, oggPage(serial, 0x04, bytes.Repeat([]byte{0x55}, 300), false)...)
+	return out
+}
+
+func TestOggTags_Vorbis(t *testing.T) {
+	tags := NewOggTags()
+	if tags.Tags() != nil {
+		t.Fatal("expected no tags before any comment header")
+	}
+
+	stream := track(1, []byte("\x01vorbis-id"), []byte("\x03vorbis"), "ARTIST=Nina Simone", "TITLE=Sinnerman", "ARTIST=Trio")
+
+	// Feed in small, unaligned writes
+	for i := 0; i < len(stream); i += 7 {
+		tags.Write(stream[i:min(i+7, len(stream))])
+	}
+
+	got := tags.Tags()
+	if got["artist"] != "Nina Simone, Trio" || got["title"] != "Sinnerman" {
+		t.Errorf("unexpected tags %v", got)
+	}
+}
+
+func TestOggTags_ChainedTracks(t *testing.T) {
+	tags := NewOggTags()
+	tags.Write(track(1, []byte("OpusHead-id"), []byte("OpusTags"), "TITLE=First"))
+	tags.Write([]byte("garbage between streams"))
+	tags.Write(track(2, []byte("\x7fFLAC-info"), []byte{0x84, 0, 0, 0}, "TITLE=Second"))
+
+	if got := tags.Tags()["title"]; got != "Second" {
+		t.Errorf("expected the latest track's title, got %q", got)
+	}
+}
+
+func TestOggTags_CommentSpanningPages(t *testing.T) {
+	comment := append([]byte("\x03vorbis"), vorbisComments("TITLE=Long", "DESCRIPTION="+string(bytes.Repeat([]byte("x"), 600)))...)
+	split := 510 // a multiple of 255 keeps the first page's lacing open
+
+	var stream []byte
+	stream = append(stream, oggPage(7, 0x02, []byte("\x01vorbis-id"), false)...)
+	stream = append(stream, oggPage(7, 0x00, comment[:split], true)...)
+	stream = append(stream, oggPage(7, 0x01, comment[split:], false)...)
+
+	tags := NewOggTags()
+	tags.Write(stream)
+
+	if got := tags.Tags()["title"]; got != "Long" {
+		t.Errorf("expected a comment split across pages to parse, got %q", got)
+	}
+}
+
+func TestOggTags_IgnoresAudioPackets(t *testing.T) {
+	tags := NewOggTags()
+
+	// Joined mid-stream: a packet that looks like a comment header but
+	// isn't in header position must not be parsed
+	tags.Write(oggPage(3, 0x00, append([]byte("OpusTags"), vorbisComments("TITLE=Bogus")...), false))
+
+	if tags.Tags() != nil {
+		t.Errorf("expected no tags from a stream joined mid-way, got %v", tags.Tags())
+	}
+}
@@ -0,0 +1,92 @@
+// ABOUTME: Incremental frame walker over chunked MPEG audio and AAC ADTS streams
+// ABOUTME: Hands each frame's header bytes to a callback and skips frame bodies
+package audio
+
+// maxPending bounds bytes held while searching for a frame header
+const maxPending = 8192
+
+// frameScanner finds frames across arbitrary chunk boundaries. Only the
+// header (and Layer III side info) of each frame is buffered.
+type frameScanner struct {
+	pending []byte // bytes of a partial header
+	skip    int    // remaining bytes of the current frame to pass over
+}
+
+// write feeds p and calls onFrame with each frame's header and the bytes
+// of the frame available, which cover at least headerLen(h)
+func (fs *frameScanner) write(p []byte, onFrame func(h FrameHeader, head []byte)) {
+	if fs.skip > 0 {
+		k := min(fs.skip, len(p))
+		fs.skip -= k
+		p = p[k:]
+	}
+	if len(p) == 0 {
+		return
+	}
+
+	buf := append(fs.pending, p...)
+	for {
+		i := syncIndex(buf)
+		if i < 0 {
+			// Keep a possible partial sync word at the end
+			if len(buf) > 0 && buf[len(buf)-1] == 0xFF {
+				buf = buf[len(buf)-1:]
+			} else {
+				buf = buf[:0]
+			}
+			break
+		}
+		buf = buf[i:]
+
+		if len(buf) < 7 {
+			break
+		}
+		h, ok := ParseFrameHeader(buf)
+		if !ok || h.Length <= headerLen(h) {
+			buf = buf[1:]
+			continue
+		}
+		if len(buf) < headerLen(h) {
+			break
+		}
+
+		onFrame(h, buf)
+
+		if len(buf) >= h.Length {
+			buf = buf[h.Length:]
+			continue
+		}
+		fs.skip = h.Length - len(buf)
+		buf = buf[:0]
+		break
+	}
+
+	if len(buf) > maxPending {
+		buf = buf[len(buf)-maxPending:]
+	}
+	fs.pending = append(fs.pending[:0], buf...)
+}
+
+// headerLen is how many leading frame bytes onFrame needs
+func headerLen(h FrameHeader) int {
+	if h.AAC {
+		return 7
+	}
+	n := 4
+	if h.CRC {
+		n += 2
+	}
+	if h.Layer == 3 {
+		n += h.sideInfoLen()
+	}
+	return n
+}
+
+func syncIndex(b []byte) int {
+	for i := 0; i+1 < len(b); i++ {
+		if b[i] == 0xFF && b[i+1]&0xE0 == 0xE0 {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,31 @@
+// ABOUTME: Builds silent MPEG-1 Layer III frames for synthetic streams
+// ABOUTME: Zeroed side info and main data decode to silence in any MP3 decoder
+package audio
+
+import (
+	"slices"
+	"time"
+)
+
+// SilentFrameDuration is the playback time of one SilentFrame
+// (1152 samples at 48 kHz)
+const SilentFrameDuration = 24 * time.Millisecond
+
+// SilentFrame returns one mono 48 kHz MPEG-1 Layer III frame of silence at
+// kbps, which must be a Layer III bitrate (32, 40, ... 320). At 48 kHz
+// every bitrate gives a whole-byte frame, so no padding is needed to hold
+// the rate exactly.
+func SilentFrame(kbps int) ([]byte, bool) {
+	layer3 := bitrates[[2]int{1, 3}]
+	index := slices.Index(layer3[:], kbps)
+	if index <= 0 {
+		return nil, false
+	}
+
+	frame := make([]byte, 144*kbps*1000/48000)
+	frame[0] = 0xFF
+	frame[1] = 0xFB                  // MPEG-1, Layer III, no CRC
+	frame[2] = byte(index<<4) | 0x04 // bitrate index, 48 kHz, no padding
+	frame[3] = 0xC0                  // mono
+	return frame, true
+}
@@ -0,0 +1,29 @@
+// ABOUTME: Tests for synthetic silent MP3 frames
+// ABOUTME: Checks generated frames parse back with the expected size and rate
+package audio
+
+import "testing"
+
+func TestSilentFrame(t *testing.T) {
+	frame, ok := SilentFrame(64)
+	if !ok {
+		t.Fatal("expected 64 kbps to be valid")
+	}
+
+	h, ok := ParseFrameHeader(frame)
+	if !ok {
+		t.Fatal("expected a parseable frame header")
+	}
+	if h.Version != 1 || h.Layer != 3 || h.Bitrate != 64 || h.SampleRate != 48000 || h.Channels != 1 {
+		t.Errorf("unexpected header %+v", h)
+	}
+	if h.Length != len(frame) {
+		t.Errorf("expected frame length %d, got %d", len(frame), h.Length)
+	}
+
+	for _, kbps := range []int{0, 33, 384} {
+		if _, ok := SilentFrame(kbps); ok {
+			t.Errorf("expected %d kbps to be rejected", kbps)
+		}
+	}
+}
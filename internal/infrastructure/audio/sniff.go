@@ -0,0 +1,75 @@
+// ABOUTME: Content-type detection from the first bytes of an audio stream
+// ABOUTME: Recognizes Ogg and WebM containers, ID3-tagged MP3, MPEG audio and AAC ADTS frames
+package audio
+
+import "bytes"
+
+var (
+	webmMagic = []byte{0x1A, 0x45, 0xDF, 0xA3}
+	id3Magic  = []byte("ID3")
+)
+
+// SniffContentType guesses a stream's MIME type from its first bytes, or
+// returns "" when they are inconclusive. A frame sync found past the start
+// only counts when the next frame header lines up behind it, since a stream
+// joined mid-frame can contain stray sync bits.
+func SniffContentType(b []byte) string {
+	switch {
+	case bytes.HasPrefix(b, oggCapture):
+		return "audio/ogg"
+	case bytes.HasPrefix(b, webmMagic):
+		return "audio/webm"
+	case bytes.HasPrefix(b, id3Magic):
+		return "audio/mpeg"
+	}
+
+	for off := 0; off < len(b); off++ {
+		i := syncIndex(b[off:])
+		if i < 0 {
+			break
+		}
+		off += i
+
+		h, ok := ParseFrameHeader(b[off:])
+		if !ok || h.Length <= headerLen(h) {
+			continue
+		}
+		next := off + h.Length
+		if next >= len(b) {
+			// The first frame fills the sample; trust it only at the very start
+			if off == 0 {
+				return frameContentType(h)
+			}
+			break
+		}
+		if h2, ok := ParseFrameHeader(b[next:]); ok && h2.AAC == h.AAC {
+			return frameContentType(h)
+		}
+	}
+	return ""
+}
+
+// Family groups content types by codec, e.g. "aac" for both audio/aac and
+// audio/aacp, which ADTS framing can't tell apart. Unknown types are their
+// own family.
+func Family(contentType string) string {
+	switch contentType {
+	case "audio/mpeg", "audio/mp3":
+		return "mpeg"
+	case "audio/aac", "audio/aacp", "audio/x-aac":
+		return "aac"
+	case "audio/ogg", "application/ogg":
+		return "ogg"
+	case "audio/webm":
+		return "webm"
+	default:
+		return contentType
+	}
+}
+
+func frameContentType(h FrameHeader) string {
+	if h.AAC {
+		return "audio/aac"
+	}
+	return "audio/mpeg"
+}
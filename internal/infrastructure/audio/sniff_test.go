@@ -0,0 +1,53 @@
+// ABOUTME: Tests for content-type sniffing
+// ABOUTME: Feeds sample headers for each codec, mid-frame joins and noise
+package audio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFamily(t *testing.T) {
+	same := [][2]string{{"audio/aac", "audio/aacp"}, {"audio/mpeg", "audio/mp3"}, {"audio/ogg", "application/ogg"}}
+	for _, pair := range same {
+		if Family(pair[0]) != Family(pair[1]) {
+			t.Errorf("expected %s and %s in one family", pair[0], pair[1])
+		}
+	}
+	if Family("audio/aac") == Family("audio/mpeg") || Family("") == Family("audio/mpeg") {
+		t.Error("expected AAC, MP3 and unknown types in different families")
+	}
+}
+
+func TestSniffContentType(t *testing.T) {
+	mp3 := append(mp3Frame(0, 0), mp3Frame(0, 0)...)
+	aac := append(adtsFrame(372), adtsFrame(372)...)
+
+	// Joining mid-stream lands inside a frame; a stray header-shaped run of
+	// bytes before the real frame must not decide the type
+	midMP3 := append(append([]byte{0x12}, adtsFrame(20)[:9]...), mp3...)
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"mp3 frames", mp3, "audio/mpeg"},
+		{"mp3 with id3 tag", append([]byte("ID3\x04\x00\x00\x00\x00\x00\x00"), mp3...), "audio/mpeg"},
+		{"mp3 joined mid-frame", midMP3, "audio/mpeg"},
+		{"single mp3 frame at start", mp3Frame(0, 0)[:100], "audio/mpeg"},
+		{"adts frames", aac, "audio/aac"},
+		{"adts joined mid-frame", append(bytes.Repeat([]byte{0x42}, 50), aac...), "audio/aac"},
+		{"ogg", []byte("OggS\x00\x02\x00\x00"), "audio/ogg"},
+		{"webm", []byte{0x1A, 0x45, 0xDF, 0xA3, 0x9F, 0x42, 0x86}, "audio/webm"},
+		{"noise", bytes.Repeat([]byte{0x42, 0x13}, 512), ""},
+		{"lone sync mid-stream", append(bytes.Repeat([]byte{0x42}, 50), mp3Frame(0, 0)[:100]...), ""},
+		{"empty", nil, ""},
+	}
+
+	for _, tt := range tests {
+		if got := SniffContentType(tt.data); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// ABOUTME: Lightweight {token} templating for config values
+// ABOUTME: Expands {env:NAME} from the environment and {name} from supplied variables
+package expand
+
+import (
+	"os"
+	"strings"
+)
+
+// String replaces {env:NAME} with the environment variable NAME and {key}
+// with vars[key]. Unknown tokens are left untouched so literal braces in
+// values survive.
+func String(s string, vars map[string]string) string {
+	if !strings.Contains(s, "{") {
+		return s
+	}
+
+	var b strings.Builder
+	rest := scan(s, func(literal, token string) {
+		b.WriteString(literal)
+		if value, ok := lookup(token, vars); ok {
+			b.WriteString(value)
+		} else {
+			b.WriteString("{" + token + "}")
+		}
+	})
+	b.WriteString(rest)
+
+	return b.String()
+}
+
+// Tokens returns the names of the {token}s in s, in order, as String sees them
+func Tokens(s string) []string {
+	var tokens []string
+	scan(s, func(_, token string) {
+		tokens = append(tokens, token)
+	})
+	return tokens
+}
+
+// scan calls fn with the text before each {token} and the token's name,
+// returning the text after the last one
+func scan(s string, fn func(literal, token string)) string {
+	for {
+		start := strings.IndexByte(s, '{')
+		if start < 0 {
+			return s
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return s
+		}
+		end += start
+		// A { without a } of its own is literal text, so "a { b {artist}"
+		// still expands {artist}
+		start = strings.LastIndexByte(s[:end], '{')
+
+		fn(s[:start], s[start+1:end])
+		s = s[end+1:]
+	}
+}
+
+// Headers returns a copy of headers with every value expanded
+func Headers(headers map[string]string, vars map[string]string) map[string]string {
+	out := make(map[string]string, len(headers))
+	for k, v := range headers {
+		out[k] = String(v, vars)
+	}
+	return out
+}
+
+func lookup(token string, vars map[string]string) (string, bool) {
+	if name, ok := strings.CutPrefix(token, "env:"); ok {
+		return os.LookupEnv(name)
+	}
+	value, ok := vars[token]
+	return value, ok
+}
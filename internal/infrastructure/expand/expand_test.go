@@ -0,0 +1,62 @@
+// ABOUTME: Tests for {token} templating
+// ABOUTME: Verifies env and variable expansion and literal passthrough
+package expand
+
+import "testing"
+
+func TestString(t *testing.T) {
+	t.Setenv("EXPAND_TEST_TOKEN", "secret")
+
+	vars := map[string]string{"client_ip": "10.0.0.1"}
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{"Bearer {env:EXPAND_TEST_TOKEN}", "Bearer secret"},
+		{"{client_ip}", "10.0.0.1"},
+		{"{unknown} stays", "{unknown} stays"},
+		{"{env:EXPAND_TEST_MISSING}", "{env:EXPAND_TEST_MISSING}"},
+		{"unterminated {env:X", "unterminated {env:X"},
+		{"a { b {client_ip}", "a { b 10.0.0.1"},
+		{"{{client_ip}}", "{10.0.0.1}"},
+	}
+
+	for _, tt := range tests {
+		if got := String(tt.in, vars); got != tt.want {
+			t.Errorf("String(%q): expected %q, got %q", tt.in, tt.want, got)
+		}
+	}
+}
+
+func TestTokens(t *testing.T) {
+	got := Tokens("Live {Uncut} with { {artist} and {env:X} {unterminated")
+	want := []string{"Uncut", "artist", "env:X"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	}
+	if Tokens("plain") != nil {
+		t.Error("expected no tokens in plain text")
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	t.Setenv("EXPAND_TEST_TOKEN", "secret")
+
+	in := map[string]string{"Authorization": "Bearer {env:EXPAND_TEST_TOKEN}"}
+	out := Headers(in, nil)
+
+	if out["Authorization"] != "Bearer secret" {
+		t.Errorf("expected expanded header, got %q", out["Authorization"])
+	}
+
+	if in["Authorization"] != "Bearer {env:EXPAND_TEST_TOKEN}" {
+		t.Error("expected input headers to be left unmodified")
+	}
+}
@@ -0,0 +1,207 @@
+// ABOUTME: Lifecycle hooks fired on station source up/down transitions
+// ABOUTME: Queues events and runs webhooks or commands off the streaming path
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/redact"
+)
+
+// Event names passed to webhooks and commands
+const (
+	EventSourceUp      = "source_up"
+	EventSourceDown    = "source_down"
+	EventMetadataStale = "metadata_stale"
+)
+
+// queueCap bounds each hook's pending events; further events for that hook
+// are dropped and logged
+const queueCap = 64
+
+// maxDrainBytes bounds how much of a webhook reply is read and discarded
+const maxDrainBytes = 64 << 10
+
+// defaultTimeout bounds a single webhook request or command run
+const defaultTimeout = 10 * time.Second
+
+// Hook is one webhook or command, fired for Events (all events when empty)
+type Hook struct {
+	Events  []string
+	Webhook string   // POSTed a JSON event body
+	Command []string // run with ICYPROXY_EVENT and ICYPROXY_STATION set
+	Timeout time.Duration
+}
+
+type event struct {
+	Name    string    `json:"event"`
+	Station string    `json:"station"`
+	Time    time.Time `json:"time"`
+}
+
+// Dispatcher implements domain.StationObserver. Notifications only enqueue,
+// so stations never block on slow hooks. Each hook has its own queue and
+// worker: it sees events in order, and a slow or hanging hook only holds up
+// its own deliveries.
+type Dispatcher struct {
+	hooks  []Hook
+	queues []chan event // one per hook
+	client *http.Client
+
+	stop     chan struct{} // closed by Stop
+	stopOnce sync.Once
+}
+
+func NewDispatcher(hooks []Hook) *Dispatcher {
+	queues := make([]chan event, len(hooks))
+	for i := range hooks {
+		if hooks[i].Timeout <= 0 {
+			hooks[i].Timeout = defaultTimeout
+		}
+		queues[i] = make(chan event, queueCap)
+	}
+	return &Dispatcher{
+		hooks:  hooks,
+		queues: queues,
+		client: &http.Client{},
+		stop:   make(chan struct{}),
+	}
+}
+
+// Stop makes Run deliver the events already queued and return, so events
+// raised while shutting down still go out. Cancelling Run's context instead
+// abandons them, along with any delivery in flight.
+func (d *Dispatcher) Stop() {
+	d.stopOnce.Do(func() { close(d.stop) })
+}
+
+func (d *Dispatcher) OnSourceUp(stationID string) {
+	d.enqueue(EventSourceUp, stationID)
+}
+
+func (d *Dispatcher) OnSourceDown(stationID string) {
+	d.enqueue(EventSourceDown, stationID)
+}
+
+func (d *Dispatcher) OnMetadataStale(stationID string) {
+	d.enqueue(EventMetadataStale, stationID)
+}
+
+func (d *Dispatcher) enqueue(name, stationID string) {
+	ev := event{Name: name, Station: stationID, Time: time.Now().UTC()}
+	for i, h := range d.hooks {
+		if len(h.Events) > 0 && !slices.Contains(h.Events, name) {
+			continue
+		}
+		select {
+		case d.queues[i] <- ev:
+		default:
+			log.Printf("station %s: hook %d queue full, dropping %s event", stationID, i+1, name)
+		}
+	}
+}
+
+// Run delivers queued events, one worker per hook, until ctx is cancelled
+// or Stop is called
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := range d.hooks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.work(ctx, i)
+		}()
+	}
+	wg.Wait()
+}
+
+// work delivers hook i's events in order
+func (d *Dispatcher) work(ctx context.Context, i int) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-d.queues[i]:
+			d.deliver(ctx, i, ev)
+		case <-d.stop:
+			for {
+				select {
+				case ev := <-d.queues[i]:
+					d.deliver(ctx, i, ev)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, i int, ev event) {
+	if err := d.fire(ctx, d.hooks[i], ev); err != nil {
+		log.Printf("station %s: %s hook %d failed: %v", ev.Station, ev.Name, i+1, err)
+	}
+}
+
+// fire runs h's webhook and then its command, each within h.Timeout
+func (d *Dispatcher) fire(ctx context.Context, h Hook, ev event) error {
+	if h.Webhook != "" {
+		if err := d.post(ctx, h, ev); err != nil {
+			return err
+		}
+	}
+	if len(h.Command) > 0 {
+		return run(ctx, h, ev)
+	}
+	return nil
+}
+
+func (d *Dispatcher) post(ctx context.Context, h Hook, ev event) error {
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout)
+	defer cancel()
+
+	body, _ := json.Marshal(ev)
+	req, err := http.NewRequestWithContext(ctx, "POST", h.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook: %s", redact.Error(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		// Webhook URLs often carry a token
+		return fmt.Errorf("webhook: %s", redact.Error(err))
+	}
+	// Drain the reply so the connection can be reused for the next event
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func run(ctx context.Context, h Hook, ev event) error {
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...)
+	cmd.Env = append(os.Environ(),
+		"ICYPROXY_EVENT="+ev.Name,
+		"ICYPROXY_STATION="+ev.Station,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("command %s: %w: %s", h.Command[0], err, bytes.TrimSpace(out))
+	}
+	return nil
+}
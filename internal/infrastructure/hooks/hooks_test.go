@@ -0,0 +1,160 @@
+// ABOUTME: Tests for source lifecycle hooks
+// ABOUTME: Verifies webhook delivery, event filtering, and command execution
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDispatcher_Webhook(t *testing.T) {
+	got := make(chan event, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev event
+		json.NewDecoder(r.Body).Decode(&ev)
+		got <- ev
+	}))
+	defer srv.Close()
+
+	d := NewDispatcher([]Hook{{Webhook: srv.URL, Events: []string{EventSourceDown}}})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	d.OnSourceUp("fip")
+	d.OnSourceDown("fip")
+
+	select {
+	case ev := <-got:
+		if ev.Name != EventSourceDown || ev.Station != "fip" {
+			t.Errorf("unexpected event %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook not called")
+	}
+
+	select {
+	case ev := <-got:
+		t.Errorf("filtered event delivered: %+v", ev)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDispatcher_Command(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "event")
+	d := NewDispatcher([]Hook{{Command: []string{"sh", "-c", `echo "$ICYPROXY_EVENT $ICYPROXY_STATION" > ` + out}}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	d.OnSourceUp("nts")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, err := os.ReadFile(out); err == nil && len(data) > 0 {
+			if string(data) != "source_up nts\n" {
+				t.Errorf("unexpected command output %q", data)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("command not run")
+}
+
+func TestDispatcher_SlowHookDoesNotDelayOthers(t *testing.T) {
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hung.Close()
+	defer close(release)
+
+	got := make(chan event, 4)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev event
+		json.NewDecoder(r.Body).Decode(&ev)
+		got <- ev
+	}))
+	defer fast.Close()
+
+	d := NewDispatcher([]Hook{{Webhook: hung.URL}, {Webhook: fast.URL}})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	d.OnSourceDown("fip")
+	d.OnSourceUp("fip")
+
+	for _, want := range []string{EventSourceDown, EventSourceUp} {
+		select {
+		case ev := <-got:
+			if ev.Name != want {
+				t.Errorf("expected %s in order, got %s", want, ev.Name)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("a hanging webhook held up the other hook")
+		}
+	}
+}
+
+func TestDispatcher_StopDeliversQueued(t *testing.T) {
+	var got []string
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev event
+		json.NewDecoder(r.Body).Decode(&ev)
+		mu.Lock()
+		got = append(got, ev.Name)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	d := NewDispatcher([]Hook{{Webhook: srv.URL}})
+	d.OnSourceUp("fip")
+	d.OnSourceDown("fip")
+	d.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		d.Run(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run kept going after Stop")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 2 || got[0] != EventSourceUp || got[1] != EventSourceDown {
+		t.Errorf("expected both queued events delivered in order, got %v", got)
+	}
+}
+
+func TestDispatcher_QueueFullDoesNotBlock(t *testing.T) {
+	d := NewDispatcher([]Hook{{Webhook: "http://127.0.0.1:1/"}})
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < queueCap*2; i++ {
+			d.OnSourceDown("fip")
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("notifications blocked without a running dispatcher")
+	}
+}
@@ -0,0 +1,371 @@
+// ABOUTME: Admin endpoints guarded by the server admin token
+// ABOUTME: Exposes operational debug views such as provider status and config
+package http
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+	"github.com/harper/radio-metadata-proxy/internal/domain/station"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/record"
+	"gopkg.in/yaml.v3"
+)
+
+// AdminAuth requires "Authorization: Bearer <token>" on every request.
+// An empty token disables the wrapped endpoints entirely.
+func AdminAuth(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			writeError(w, http.StatusForbidden, codeForbidden, "admin endpoints disabled")
+			return
+		}
+
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="icyproxy-admin"`)
+			writeError(w, http.StatusUnauthorized, codeUnauthorized, "unauthorized")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// splitAdminStationPath splits /admin/stations/{id}/{action} into its parts
+func splitAdminStationPath(p string) (stationID, action string, ok bool) {
+	parts := strings.FieldsFunc(p, func(r rune) bool { return r == '/' })
+	if len(parts) != 4 || parts[0] != "admin" || parts[1] != "stations" {
+		return "", "", false
+	}
+	return parts[2], parts[3], true
+}
+
+// AdminStationRouter dispatches /admin/stations/{id}/{action} requests by action
+type AdminStationRouter struct {
+	routes map[string]http.Handler
+}
+
+func NewAdminStationRouter(routes map[string]http.Handler) *AdminStationRouter {
+	return &AdminStationRouter{routes: routes}
+}
+
+func (rt *AdminStationRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, action, ok := splitAdminStationPath(r.URL.Path)
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+
+	h, found := rt.routes[action]
+	if !found {
+		writeNotFound(w)
+		return
+	}
+
+	h.ServeHTTP(w, r)
+}
+
+// ResetPeakHandler restarts a station's peak listener count (POST only)
+type ResetPeakHandler struct {
+	mgr *manager.Manager
+}
+
+func NewResetPeakHandler(mgr *manager.Manager) *ResetPeakHandler {
+	return &ResetPeakHandler{mgr: mgr}
+}
+
+func (h *ResetPeakHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	stationID, _, ok := splitAdminStationPath(r.URL.Path)
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+
+	st := h.mgr.Get(stationID)
+	if st == nil {
+		writeStationMissing(w, h.mgr, stationID)
+		return
+	}
+
+	st.ResetPeak()
+
+	writeJSON(w, http.StatusOK, map[string]int{"peakClients": st.PeakClientCount()})
+}
+
+// RecordHandler starts (POST), stops (DELETE) or reports (GET) a station's
+// on-disk recording
+type RecordHandler struct {
+	mgr *manager.Manager
+}
+
+func NewRecordHandler(mgr *manager.Manager) *RecordHandler {
+	return &RecordHandler{mgr: mgr}
+}
+
+func (h *RecordHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	stationID, _, ok := splitAdminStationPath(r.URL.Path)
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+
+	st := h.mgr.Get(stationID)
+	if st == nil {
+		writeStationMissing(w, h.mgr, stationID)
+		return
+	}
+
+	var (
+		status record.Status
+		err    error
+	)
+	switch r.Method {
+	case http.MethodPost:
+		status, err = h.mgr.Recorder().Start(st)
+	case http.MethodDelete:
+		status, err = h.mgr.Recorder().Stop(st.ID())
+	case http.MethodGet:
+		var found bool
+		if status, found = h.mgr.Recorder().Status(st.ID()); !found {
+			err = record.ErrNotRecording
+		}
+	default:
+		writeMethodNotAllowed(w, "GET, POST, DELETE")
+		return
+	}
+
+	switch {
+	case errors.Is(err, record.ErrDisabled):
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, err.Error())
+		return
+	case errors.Is(err, record.ErrAlreadyRecording):
+		writeError(w, http.StatusConflict, codeConflict, err.Error())
+		return
+	case errors.Is(err, record.ErrNotRecording):
+		writeError(w, http.StatusNotFound, codeNotFound, err.Error())
+		return
+	case err != nil:
+		log.Printf("station %s: recording: %v", st.ID(), err)
+		writeError(w, http.StatusInternalServerError, codeInternal, "recording failed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+// RefreshMetadataHandler fetches a station's metadata immediately instead of
+// at the next poll and returns the result (POST only)
+type RefreshMetadataHandler struct {
+	mgr *manager.Manager
+}
+
+func NewRefreshMetadataHandler(mgr *manager.Manager) *RefreshMetadataHandler {
+	return &RefreshMetadataHandler{mgr: mgr}
+}
+
+func (h *RefreshMetadataHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	stationID, _, ok := splitAdminStationPath(r.URL.Path)
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+
+	st := h.mgr.Get(stationID)
+	if st == nil {
+		writeStationMissing(w, h.mgr, stationID)
+		return
+	}
+
+	err := st.RefreshMetadata(r.Context())
+	switch {
+	case errors.Is(err, station.ErrNoMetadata):
+		writeError(w, http.StatusConflict, codeConflict, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusBadGateway, codeUpstream, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"metadata": st.CurrentMetadata(),
+		"title":    displayTitle(st),
+	})
+}
+
+// ProvidersHandler lists each station's metadata providers with their
+// poll interval and last successful fetch
+type ProvidersHandler struct {
+	mgr *manager.Manager
+}
+
+func NewProvidersHandler(mgr *manager.Manager) *ProvidersHandler {
+	return &ProvidersHandler{mgr: mgr}
+}
+
+func (h *ProvidersHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	type providerInfo struct {
+		Name        string  `json:"name"`
+		PollMs      int64   `json:"pollMs"`
+		LastFetchAt *string `json:"lastFetchAt"`
+		LastError   string  `json:"lastError,omitempty"`
+		Panics      int64   `json:"panics,omitempty"`
+	}
+
+	result := make(map[string][]providerInfo)
+	for _, st := range h.mgr.List() {
+		infos := make([]providerInfo, 0)
+		for _, p := range st.ProviderStatus() {
+			var lastFetchAt *string
+			if p.LastFetchAt != nil {
+				s := p.LastFetchAt.Format("2006-01-02T15:04:05Z07:00")
+				lastFetchAt = &s
+			}
+
+			infos = append(infos, providerInfo{
+				Name:        p.Name,
+				PollMs:      p.PollInterval.Milliseconds(),
+				LastFetchAt: lastFetchAt,
+				LastError:   p.LastError,
+				Panics:      p.Panics,
+			})
+		}
+		result[st.ID()] = infos
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// SourcesHandler lists each station's upstream connection: the active
+// mirror and its URL, credentials redacted, the latest HTTP status and the
+// full connect error. These stay off the public /stations view since paths
+// and error text can carry secrets and internal addresses.
+type SourcesHandler struct {
+	mgr *manager.Manager
+}
+
+func NewSourcesHandler(mgr *manager.Manager) *SourcesHandler {
+	return &SourcesHandler{mgr: mgr}
+}
+
+type sourceInfo struct {
+	URL      string                `json:"url,omitempty"`
+	Mirror   *int                  `json:"mirror,omitempty"`
+	Status   int                   `json:"status,omitempty"`
+	Error    string                `json:"error,omitempty"`
+	Healthy  bool                  `json:"healthy"`
+	Variants map[string]sourceInfo `json:"variants,omitempty"`
+}
+
+func newSourceInfo(st *station.Station) sourceInfo {
+	return sourceInfo{
+		URL:     st.SourceURL(),
+		Mirror:  sourceMirror(st),
+		Status:  st.SourceStatus(),
+		Error:   st.SourceError(),
+		Healthy: st.SourceHealthy(),
+	}
+}
+
+func (h *SourcesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	result := make(map[string]sourceInfo)
+	for _, st := range h.mgr.List() {
+		info := newSourceInfo(st)
+		for _, name := range st.VariantNames() {
+			if info.Variants == nil {
+				info.Variants = make(map[string]sourceInfo)
+			}
+			info.Variants[name] = newSourceInfo(st.Variant(name))
+		}
+		result[st.ID()] = info
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ClientsHandler lists a station's connected clients, including whether
+// each requested ICY metadata and at what interval
+type ClientsHandler struct {
+	mgr *manager.Manager
+}
+
+func NewClientsHandler(mgr *manager.Manager) *ClientsHandler {
+	return &ClientsHandler{mgr: mgr}
+}
+
+func (h *ClientsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	stationID, endpoint, _, ok := splitStationPath(r.URL.Path)
+	if !ok || endpoint != "clients" {
+		writeNotFound(w)
+		return
+	}
+
+	st := h.mgr.Get(stationID)
+	if st == nil {
+		writeStationMissing(w, h.mgr, stationID)
+		return
+	}
+
+	type clientInfo struct {
+		ID          string `json:"id"`
+		Addr        string `json:"addr"`
+		UserAgent   string `json:"userAgent,omitempty"`
+		ConnectedAt string `json:"connectedAt"`
+		ICYMetadata bool   `json:"icyMetadata"`
+		MetaInt     int    `json:"metaint"`
+	}
+
+	result := make([]clientInfo, 0)
+	for _, c := range st.Clients() {
+		result = append(result, clientInfo{
+			ID:          c.ID,
+			Addr:        c.Addr,
+			UserAgent:   c.UserAgent,
+			ConnectedAt: c.ConnectedAt.Format("2006-01-02T15:04:05Z07:00"),
+			ICYMetadata: c.ICYMetadata,
+			MetaInt:     c.MetaInt,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ConfigHandler returns the effective configuration, defaults applied and
+// secrets redacted, as JSON keyed like the YAML file
+type ConfigHandler struct {
+	mgr *manager.Manager
+}
+
+func NewConfigHandler(mgr *manager.Manager) *ConfigHandler {
+	return &ConfigHandler{mgr: mgr}
+}
+
+func (h *ConfigHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Round-trip through YAML so keys match the config file
+	data, err := yaml.Marshal(h.mgr.Config().Redacted())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to encode config")
+		return
+	}
+
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to encode config")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, doc)
+}
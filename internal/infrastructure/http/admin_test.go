@@ -0,0 +1,424 @@
+// ABOUTME: Tests for admin endpoints and token auth
+// ABOUTME: Verifies the bearer token guard and provider status view
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/config"
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+	"github.com/harper/radio-metadata-proxy/internal/domain/station"
+)
+
+func TestAdminAuth(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		want   int
+	}{
+		{"disabled without token", "", "Bearer anything", http.StatusForbidden},
+		{"missing header", "secret", "", http.StatusUnauthorized},
+		{"wrong token", "secret", "Bearer nope", http.StatusUnauthorized},
+		{"valid token", "secret", "Bearer secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin/providers", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			AdminAuth(tt.token, ok).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestProvidersHandler(t *testing.T) {
+	cfg := singleStationConfig()
+	cfg.Stations[0].Metadata.Providers = []config.ProviderConfig{
+		{Name: "artwork", URL: "http://example.com/art", PollMs: 60000},
+		{URL: "http://example.com/extra"},
+	}
+
+	mgr, _ := manager.NewFromConfig(cfg)
+	handler := NewProvidersHandler(mgr)
+
+	req := httptest.NewRequest("GET", "/admin/providers", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var result map[string][]struct {
+		Name        string  `json:"name"`
+		PollMs      int64   `json:"pollMs"`
+		LastFetchAt *string `json:"lastFetchAt"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	providers := result["test_station"]
+	if len(providers) != 3 {
+		t.Fatalf("expected 3 providers, got %d", len(providers))
+	}
+
+	want := []struct {
+		name   string
+		pollMs int64
+	}{
+		{"primary", 3000},
+		{"artwork", 60000},
+		{"provider-2", 3000},
+	}
+	for i, w := range want {
+		if providers[i].Name != w.name || providers[i].PollMs != w.pollMs {
+			t.Errorf("provider %d: expected %s/%d, got %s/%d", i, w.name, w.pollMs, providers[i].Name, providers[i].PollMs)
+		}
+		if providers[i].LastFetchAt != nil {
+			t.Errorf("provider %d: expected no fetch before start", i)
+		}
+	}
+}
+
+func TestConfigHandler(t *testing.T) {
+	cfg := singleStationConfig()
+	cfg.Server.AdminToken = "secret"
+	cfg.Stations[0].Source.RequestHeaders = map[string]string{"X-Api-Key": "abc"}
+
+	mgr, _ := manager.NewFromConfig(cfg)
+	handler := NewConfigHandler(mgr)
+
+	req := httptest.NewRequest("GET", "/admin/config", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var doc struct {
+		Server struct {
+			AdminToken string `json:"admin_token"`
+		} `json:"server"`
+		Stations []struct {
+			Source struct {
+				Type           string            `json:"type"`
+				RequestHeaders map[string]string `json:"request_headers"`
+			} `json:"source"`
+		} `json:"stations"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if doc.Server.AdminToken != "[redacted]" {
+		t.Errorf("expected redacted admin token, got %q", doc.Server.AdminToken)
+	}
+
+	if len(doc.Stations) != 1 {
+		t.Fatalf("expected 1 station, got %d", len(doc.Stations))
+	}
+
+	src := doc.Stations[0].Source
+	if src.Type != "http" {
+		t.Errorf("expected defaulted source type, got %q", src.Type)
+	}
+	if src.RequestHeaders["X-Api-Key"] != "[redacted]" {
+		t.Errorf("expected redacted header, got %q", src.RequestHeaders["X-Api-Key"])
+	}
+}
+
+func TestClientsHandler(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	stream := NewStreamHandler(mgr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, icy := range []string{"1", ""} {
+		req := httptest.NewRequest("GET", "/test_station/stream", nil).WithContext(ctx)
+		req.Header.Set("User-Agent", "player/"+icy)
+		if icy != "" {
+			req.Header.Set("Icy-MetaData", icy)
+		}
+		go stream.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for mgr.Get("test_station").ClientCount() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	rec := httptest.NewRecorder()
+	NewClientsHandler(mgr).ServeHTTP(rec, httptest.NewRequest("GET", "/test_station/clients", nil))
+
+	var clients []struct {
+		UserAgent   string `json:"userAgent"`
+		ICYMetadata bool   `json:"icyMetadata"`
+		MetaInt     int    `json:"metaint"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&clients); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if len(clients) != 2 {
+		t.Fatalf("expected 2 clients, got %d", len(clients))
+	}
+
+	for _, c := range clients {
+		switch c.UserAgent {
+		case "player/1":
+			if !c.ICYMetadata || c.MetaInt != 16384 {
+				t.Errorf("expected metadata client with metaint 16384, got %+v", c)
+			}
+		case "player/":
+			if c.ICYMetadata || c.MetaInt != 0 {
+				t.Errorf("expected client without metadata, got %+v", c)
+			}
+		default:
+			t.Errorf("unexpected client %+v", c)
+		}
+	}
+}
+
+func TestClientsHandler_UnknownStation(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+
+	rec := httptest.NewRecorder()
+	NewClientsHandler(mgr).ServeHTTP(rec, httptest.NewRequest("GET", "/nope/clients", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestResetPeakHandler(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	st := mgr.Get("test_station")
+
+	a, b := &station.Client{ID: "a"}, &station.Client{ID: "b"}
+	st.Subscribe(a)
+	st.Subscribe(b)
+	st.Unsubscribe(b)
+
+	router := NewAdminStationRouter(map[string]http.Handler{
+		"reset-peak": NewResetPeakHandler(mgr),
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/stations/test_station/reset-peak", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for GET, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/admin/stations/nope/reset-peak", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown station, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/admin/stations/test_station/reset-peak", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	if peak := st.PeakClientCount(); peak != 1 {
+		t.Errorf("expected peak reset to 1, got %d", peak)
+	}
+}
+
+func TestRecordHandler(t *testing.T) {
+	router := func(mgr *manager.Manager) http.Handler {
+		return NewAdminStationRouter(map[string]http.Handler{"record": NewRecordHandler(mgr)})
+	}
+
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	rec := httptest.NewRecorder()
+	router(mgr).ServeHTTP(rec, httptest.NewRequest("POST", "/admin/stations/test_station/record", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without recording.dir, got %d", rec.Code)
+	}
+
+	cfg := singleStationConfig()
+	cfg.Recording.Dir = t.TempDir()
+	mgr, _ = manager.NewFromConfig(cfg)
+	h := router(mgr)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/admin/stations/test_station/record", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 on start, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/admin/stations/test_station/record", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 when already recording, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("DELETE", "/admin/stations/test_station/record", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 on stop, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("DELETE", "/admin/stations/test_station/record", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when not recording, got %d", rec.Code)
+	}
+}
+
+func TestRefreshMetadataHandler(t *testing.T) {
+	var title atomic.Value
+	title.Store("First")
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"artist": "A", "title": title.Load().(string)})
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{Stations: []config.StationConfig{
+		{
+			ID:     "live",
+			ICY:    config.ICYConfig{BitrateHintKbps: 32},
+			Source: config.SourceConfig{Type: "tone"},
+			Metadata: config.MetadataConfig{
+				URL:    upstream.URL,
+				PollMs: 3600000,
+				Build:  config.BuildConfig{Format: "StreamTitle='{artist} - {title}';"},
+			},
+		},
+		{ID: "quiet", ICY: config.ICYConfig{BitrateHintKbps: 32}, Source: config.SourceConfig{Type: "tone"}},
+	}}
+	mgr, err := manager.NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	mgr.Start()
+	defer mgr.Shutdown()
+
+	st := mgr.Get("live")
+	deadline := time.Now().Add(2 * time.Second)
+	for st.Provisional() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	router := NewAdminStationRouter(map[string]http.Handler{
+		"refresh-metadata": NewRefreshMetadataHandler(mgr),
+	})
+
+	// The next poll is an hour away, so only a refresh picks this up
+	title.Store("Second")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/admin/stations/live/refresh-metadata", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Metadata string `json:"metadata"`
+		Title    string `json:"title"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Metadata != "StreamTitle='A - Second';" || resp.Title != "A - Second" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	for _, tt := range []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/admin/stations/live/refresh-metadata", http.StatusMethodNotAllowed},
+		{"POST", "/admin/stations/nope/refresh-metadata", http.StatusNotFound},
+		{"POST", "/admin/stations/quiet/refresh-metadata", http.StatusConflict},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.want, rec.Code)
+		}
+	}
+
+	// A failing upstream is reported rather than hidden
+	upstream.Close()
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/admin/stations/live/refresh-metadata", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("expected 502 for a failed fetch, got %d", rec.Code)
+	}
+}
+
+func TestSourcesHandler(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+
+	cfg := singleStationConfig()
+	cfg.Stations[0].Source.URL = upstream.URL + "/live?authtoken=SECRET123"
+	cfg.Stations[0].Metadata = config.MetadataConfig{}
+	refused := cfg.Stations[0]
+	refused.ID = "refused"
+	refused.Source.URL = "http://127.0.0.1:1/live?authtoken=SECRET123"
+	cfg.Stations = append(cfg.Stations, refused)
+
+	mgr, err := manager.NewFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer mgr.Shutdown()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for (mgr.Get("test_station").SourceError() == "" || mgr.Get("refused").SourceError() == "") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The public list only names the mirror by position and the error by kind
+	rec := httptest.NewRecorder()
+	NewStationsHandler(mgr).ServeHTTP(rec, httptest.NewRequest("GET", "/stations", nil))
+	body := rec.Body.String()
+	if strings.Contains(body, "SECRET123") || strings.Contains(body, "/live") || strings.Contains(body, "127.0.0.1") {
+		t.Errorf("expected no source URL or address in /stations, got %s", body)
+	}
+	for _, want := range []string{`"sourceMirror":0`, `"sourceError":"http 503"`, `"sourceError":"connect failed"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s in /stations, got %s", want, body)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	NewSourcesHandler(mgr).ServeHTTP(rec, httptest.NewRequest("GET", "/admin/sources", nil))
+
+	var result map[string]struct {
+		URL    string `json:"url"`
+		Status int    `json:"status"`
+		Error  string `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	src := result["test_station"]
+	if src.URL != upstream.URL+"/live?authtoken=xxxxx" || src.Status != http.StatusServiceUnavailable || src.Error != "unexpected status: 503" {
+		t.Errorf("expected redacted URL, status and error, got %+v", src)
+	}
+	src = result["refused"]
+	if strings.Contains(src.Error, "SECRET123") || !strings.Contains(src.Error, "authtoken=xxxxx") {
+		t.Errorf("expected the full error with the URL redacted, got %q", src.Error)
+	}
+}
@@ -0,0 +1,37 @@
+// ABOUTME: Per-route Cache-Control defaults and override middleware
+// ABOUTME: Lets caching relays tune headers on cover and JSON endpoints
+package http
+
+import "net/http"
+
+// DefaultCacheControl is the Cache-Control each route sends unless overridden
+var DefaultCacheControl = map[string]string{
+	"stream":     "no-store",
+	"meta":       "no-cache",
+	"cover":      "no-cache",
+	"stations":   "no-cache",
+	"nowplaying": "no-cache",
+	"levels":     "no-store",
+}
+
+// CacheControl resolves the header value for route. An override present in
+// overrides wins even when empty, which removes the header.
+func CacheControl(route string, overrides map[string]string) string {
+	if v, ok := overrides[route]; ok {
+		return v
+	}
+	return DefaultCacheControl[route]
+}
+
+// WithCacheControl sets Cache-Control on responses from next; an empty
+// value leaves the header unset
+func WithCacheControl(value string, next http.Handler) http.Handler {
+	if value == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", value)
+		next.ServeHTTP(w, r)
+	})
+}
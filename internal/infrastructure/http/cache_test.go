@@ -0,0 +1,76 @@
+// ABOUTME: Tests for Cache-Control resolution and stream connection headers
+// ABOUTME: Verifies overrides, removal, and HTTP/2 handling
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+)
+
+func TestCacheControl(t *testing.T) {
+	overrides := map[string]string{
+		"cover": "public, max-age=60",
+		"meta":  "",
+	}
+
+	if got := CacheControl("cover", overrides); got != "public, max-age=60" {
+		t.Errorf("expected override, got %q", got)
+	}
+
+	if got := CacheControl("meta", overrides); got != "" {
+		t.Errorf("expected removed header, got %q", got)
+	}
+
+	if got := CacheControl("stream", overrides); got != "no-store" {
+		t.Errorf("expected default, got %q", got)
+	}
+}
+
+func TestWithCacheControl(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	rec := httptest.NewRecorder()
+	WithCacheControl("no-store", ok).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("expected no-store, got %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	WithCacheControl("", ok).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if _, set := rec.Header()["Cache-Control"]; set {
+		t.Error("expected no Cache-Control header")
+	}
+}
+
+func TestStreamHandler_ConnectionClose(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+
+	tests := []struct {
+		name    string
+		enabled bool
+		proto   int
+		want    string
+	}{
+		{"http1 default", true, 1, "close"},
+		{"http1 disabled", false, 1, ""},
+		{"http2 never", true, 2, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewStreamHandler(mgr).WithConnectionClose(tt.enabled)
+
+			req := httptest.NewRequest("GET", "/test_station/stream", nil)
+			req.ProtoMajor = tt.proto
+			rec := streamFor(handler, req, 20*time.Millisecond)
+
+			if got := rec.Header().Get("Connection"); got != tt.want {
+				t.Errorf("expected Connection %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// ABOUTME: Adapters between station chunk channels and io streams
+// ABOUTME: Used to pipe station audio through external transcoders
+package http
+
+import (
+	"context"
+	"io"
+)
+
+// chunkReader exposes a station chunk channel as an io.Reader
+type chunkReader struct {
+	chunks <-chan []byte
+	buf    []byte
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		chunk, ok := <-c.chunks
+		if !ok {
+			return 0, io.EOF
+		}
+		c.buf = chunk
+	}
+
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+// readChunks pumps r into a channel of chunks, closing it when r is exhausted
+// or ctx is done
+func readChunks(ctx context.Context, r io.Reader) <-chan []byte {
+	out := make(chan []byte, 64)
+
+	go func() {
+		defer close(out)
+		buf := make([]byte, 8192)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				chunk := make([]byte, n)
+				copy(chunk, buf[:n])
+				select {
+				case out <- chunk:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,197 @@
+// ABOUTME: Cover art endpoint redirecting to, or proxying, the current artwork
+// ABOUTME: Proxied fetches are bounded in size and time and must return an image
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+)
+
+// Defaults for WithProxy when given non-positive limits
+const (
+	defaultCoverMaxBytes = 5 << 20
+	defaultCoverTimeout  = 5 * time.Second
+)
+
+// CoverHandler redirects to (or serves) the current artwork URL for a station.
+type CoverHandler struct {
+	mgr *manager.Manager
+
+	proxy     bool
+	maxBytes  int64
+	client    *http.Client
+	allowDial func(netip.AddrPort) bool // checked on every connection the proxy opens
+}
+
+func NewCoverHandler(mgr *manager.Manager) *CoverHandler {
+	return &CoverHandler{mgr: mgr}
+}
+
+// WithProxy fetches artwork server-side instead of redirecting, so players
+// never contact the artwork host. Bodies over maxBytes, fetches slower than
+// timeout, responses other than JPEG, PNG, WebP or GIF, and hosts on
+// loopback, private, link-local, CGNAT, NAT64 or multicast addresses are
+// refused with 502.
+func (h *CoverHandler) WithProxy(maxBytes int64, timeout time.Duration) *CoverHandler {
+	if maxBytes <= 0 {
+		maxBytes = defaultCoverMaxBytes
+	}
+	if timeout <= 0 {
+		timeout = defaultCoverTimeout
+	}
+	h.proxy = true
+	h.maxBytes = maxBytes
+	h.allowDial = func(ap netip.AddrPort) bool { return publicAddr(ap.Addr()) }
+
+	// Artwork URLs come from upstream metadata, so the address is checked
+	// as each connection is dialed: after DNS resolution and on redirects.
+	// No HTTP proxy is used, since it would be the address checked.
+	dialer := &net.Dialer{Timeout: timeout, Control: h.checkDial}
+	h.client = &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+	}
+	return h
+}
+
+func (h *CoverHandler) checkDial(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !h.allowDial(netip.AddrPortFrom(ap.Addr().Unmap(), ap.Port())) {
+		return fmt.Errorf("artwork host %s is not a public address", ap.Addr())
+	}
+	return nil
+}
+
+// internalPrefixes are non-public ranges netip has no predicate for
+var internalPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),          // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"),      // carrier-grade NAT
+	netip.MustParsePrefix("255.255.255.255/32"), // limited broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),       // NAT64, which can reach internal IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"),     // local-use NAT64
+}
+
+// publicAddr reports whether addr may be fetched on behalf of metadata,
+// refusing the proxy's own and internal networks
+func publicAddr(addr netip.Addr) bool {
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsMulticast() {
+		return false
+	}
+	for _, p := range internalPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// coverTypes are the artwork content types the proxy serves. SVG in
+// particular is refused: served from the proxy's origin, its scripts would
+// run there.
+var coverTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+	"image/gif":  true,
+}
+
+func (h *CoverHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	stationID, endpoint, ext, ok := splitStationPath(r.URL.Path)
+	if !ok || endpoint != "cover" || ext != "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	st := h.mgr.Get(stationID)
+	if st == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	meta := st.CurrentMetadata()
+	// Parse Artwork='...'; from the ICY string
+	art := extractKV(meta, "Artwork")
+	if art == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Only web URLs, never file:// or javascript: from upstream metadata
+	if u, err := url.Parse(art); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		log.Printf("station %s: ignoring artwork URL %q", st.ID(), art)
+		http.NotFound(w, r)
+		return
+	}
+
+	if !h.proxy {
+		http.Redirect(w, r, art, http.StatusFound)
+		return
+	}
+
+	data, contentType, err := h.fetch(r.Context(), art)
+	if err != nil {
+		log.Printf("station %s: cover fetch: %v", st.ID(), err)
+		http.Error(w, "artwork unavailable", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Write(data)
+}
+
+// fetch downloads artwork, buffering it whole so an oversized or truncated
+// body is refused before anything reaches the client
+func (h *CoverHandler) fetch(ctx context.Context, art string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", art, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("upstream returned status %d", resp.StatusCode)
+	}
+
+	raw := resp.Header.Get("Content-Type")
+	contentType, _, err := mime.ParseMediaType(raw)
+	if err != nil || !coverTypes[contentType] {
+		return nil, "", fmt.Errorf("upstream returned %q, not a JPEG, PNG, WebP or GIF image", raw)
+	}
+	if resp.ContentLength > h.maxBytes {
+		return nil, "", fmt.Errorf("artwork is %d bytes, over the %d byte limit", resp.ContentLength, h.maxBytes)
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(resp.Body, h.maxBytes+1)); err != nil {
+		return nil, "", err
+	}
+	if int64(buf.Len()) > h.maxBytes {
+		return nil, "", fmt.Errorf("artwork exceeds the %d byte limit", h.maxBytes)
+	}
+	return buf.Bytes(), contentType, nil
+}
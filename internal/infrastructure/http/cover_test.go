@@ -0,0 +1,177 @@
+// ABOUTME: Tests for the cover art endpoint
+// ABOUTME: Verifies redirects, scheme checks and the guards on proxied fetches
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+)
+
+// coverManager returns a manager whose station advertises artwork at art
+func coverManager(t *testing.T, art string) *manager.Manager {
+	t.Helper()
+	mgr, err := manager.NewFromConfig(singleStationConfig())
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	mgr.Get("test_station").UpdateMetadata("StreamTitle='Song';Artwork='" + art + "';")
+	return mgr
+}
+
+func TestCoverHandler_Redirect(t *testing.T) {
+	handler := NewCoverHandler(coverManager(t, "https://img.example.com/a.jpg"))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/test_station/cover", nil))
+
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://img.example.com/a.jpg" {
+		t.Errorf("expected redirect to artwork, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestCoverHandler_RejectsNonWebSchemes(t *testing.T) {
+	for _, art := range []string{"file:///etc/passwd", "javascript:alert(1)", "/relative.jpg"} {
+		for _, handler := range []*CoverHandler{
+			NewCoverHandler(coverManager(t, art)),
+			NewCoverHandler(coverManager(t, art)).WithProxy(0, 0),
+		} {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", "/test_station/cover", nil))
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("%s (proxy %v): expected 404, got %d", art, handler.proxy, rec.Code)
+			}
+		}
+	}
+}
+
+func TestCoverHandler_Proxy(t *testing.T) {
+	image := bytes.Repeat([]byte{0x89}, 1024)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(image)
+		case "/huge.png":
+			// No Content-Length, so only the read limit can catch it
+			w.Header().Set("Content-Type", "image/png")
+			for i := 0; i < 8; i++ {
+				w.Write(image)
+				w.(http.Flusher).Flush()
+			}
+		case "/ok.jpg":
+			w.Header().Set("Content-Type", "IMAGE/JPEG; charset=binary")
+			w.Write(image)
+		case "/script.svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+			w.Write([]byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`))
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		case "/slow.png":
+			time.Sleep(200 * time.Millisecond)
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(image)
+		}
+	}))
+	defer upstream.Close()
+
+	cases := map[string]int{
+		"/ok.png":     http.StatusOK,
+		"/ok.jpg":     http.StatusOK,
+		"/huge.png":   http.StatusBadGateway,
+		"/script.svg": http.StatusBadGateway,
+		"/page.html":  http.StatusBadGateway,
+		"/slow.png":   http.StatusBadGateway,
+	}
+	for path, want := range cases {
+		handler := NewCoverHandler(coverManager(t, upstream.URL+path)).WithProxy(4096, 100*time.Millisecond)
+		handler.allowDial = func(netip.AddrPort) bool { return true }
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/test_station/cover", nil))
+
+		if rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", path, want, rec.Code)
+		}
+		if want == http.StatusOK {
+			wantType := map[string]string{"/ok.png": "image/png", "/ok.jpg": "image/jpeg"}[path]
+			if ct := rec.Header().Get("Content-Type"); ct != wantType {
+				t.Errorf("%s: expected %s, got %q", path, wantType, ct)
+			}
+			if csp := rec.Header().Get("Content-Security-Policy"); csp != "sandbox" {
+				t.Errorf("%s: expected a sandbox CSP, got %q", path, csp)
+			}
+			if !bytes.Equal(rec.Body.Bytes(), image) {
+				t.Errorf("%s: expected artwork body, got %d bytes", path, rec.Body.Len())
+			}
+		}
+	}
+}
+
+func TestCoverHandler_ProxyRefusesInternalHosts(t *testing.T) {
+	var internalHits atomic.Int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		internalHits.Add(1)
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("secret"))
+	}))
+	defer internal.Close()
+	redirector := httptest.NewServer(http.RedirectHandler(internal.URL+"/art.png", http.StatusFound))
+	defer redirector.Close()
+
+	for _, art := range []string{internal.URL + "/art.png", redirector.URL + "/art.png"} {
+		handler := NewCoverHandler(coverManager(t, art)).WithProxy(0, time.Second)
+		if art == redirector.URL+"/art.png" {
+			// Let the first hop through so only the redirect target is checked
+			allowed := netip.MustParseAddrPort(redirector.Listener.Addr().String())
+			handler.allowDial = func(ap netip.AddrPort) bool { return ap == allowed }
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/test_station/cover", nil))
+		if rec.Code != http.StatusBadGateway {
+			t.Errorf("%s: expected 502, got %d", art, rec.Code)
+		}
+	}
+	if n := internalHits.Load(); n != 0 {
+		t.Errorf("expected the internal host never contacted, got %d requests", n)
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1":    true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"0.0.0.0":         false,
+		"0.1.2.3":         false,
+		"100.64.0.1":      false,
+		"100.127.255.254": false,
+		"100.128.0.1":     true,
+		"224.0.0.1":       false,
+		"239.255.255.250": false,
+		"255.255.255.255": false,
+		"ff02::1":         false,
+		"ff05::2":         false,
+		"64:ff9b::a00:1":  false,
+		"64:ff9b:1::1":    false,
+	} {
+		if got := publicAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("publicAddr(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// ABOUTME: Admin-only metadata debugging endpoint
+// ABOUTME: Shows each provider's raw upstream response next to what was built from it
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+)
+
+// DebugMetadataHandler serves /{station}/debug/metadata: a one-off fetch
+// from every metadata provider, reporting the raw upstream body, the parsed
+// fields and the formatted string. The station's metadata is not updated.
+type DebugMetadataHandler struct {
+	mgr *manager.Manager
+}
+
+func NewDebugMetadataHandler(mgr *manager.Manager) *DebugMetadataHandler {
+	return &DebugMetadataHandler{mgr: mgr}
+}
+
+func (h *DebugMetadataHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.FieldsFunc(r.URL.Path, func(c rune) bool { return c == '/' })
+	if len(parts) != 3 || parts[1] != "debug" || parts[2] != "metadata" {
+		writeNotFound(w)
+		return
+	}
+
+	st := h.mgr.Get(parts[0])
+	if st == nil {
+		writeStationMissing(w, h.mgr, parts[0])
+		return
+	}
+
+	type providerInfo struct {
+		Name          string            `json:"name"`
+		Status        int               `json:"status,omitempty"`
+		Body          string            `json:"body,omitempty"`
+		BodyTruncated bool              `json:"bodyTruncated,omitempty"`
+		Fields        map[string]string `json:"fields,omitempty"`
+		Metadata      string            `json:"metadata"`
+		Error         string            `json:"error,omitempty"`
+	}
+
+	type response struct {
+		Station   string         `json:"station"`
+		Current   string         `json:"current"`
+		Providers []providerInfo `json:"providers"`
+	}
+
+	resp := response{Station: st.ID(), Current: st.CurrentMetadata(), Providers: make([]providerInfo, 0)}
+	for _, d := range st.DebugMetadata(r.Context()) {
+		resp.Providers = append(resp.Providers, providerInfo{
+			Name:          d.Name,
+			Status:        d.Status,
+			Body:          d.Body,
+			BodyTruncated: d.BodyTruncated,
+			Fields:        d.Fields,
+			Metadata:      d.Metadata,
+			Error:         d.Error,
+		})
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, resp)
+}
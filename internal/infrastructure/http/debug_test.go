@@ -0,0 +1,82 @@
+// ABOUTME: Tests for the metadata debugging endpoint
+// ABOUTME: Verifies raw body, parsed fields and formatted output are reported
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/config"
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+)
+
+func TestDebugMetadataHandler(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"now": {"artist": "Nina Simone", "song": "Sinnerman"}}`))
+	}))
+	defer upstream.Close()
+
+	cfg := singleStationConfig()
+	cfg.Stations[0].Metadata = config.MetadataConfig{
+		URL:    upstream.URL,
+		PollMs: 5000,
+		Build: config.BuildConfig{
+			Format:           "StreamTitle='{artist} - {title}';",
+			FallbackKeyOrder: []string{"now.artist", "now.title"},
+		},
+	}
+	mgr, err := manager.NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	h := NewDebugMetadataHandler(mgr)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/test_station/debug/metadata", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var resp struct {
+		Station   string `json:"station"`
+		Providers []struct {
+			Name     string            `json:"name"`
+			Status   int               `json:"status"`
+			Body     string            `json:"body"`
+			Fields   map[string]string `json:"fields"`
+			Metadata string            `json:"metadata"`
+		} `json:"providers"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp.Providers) != 1 {
+		t.Fatalf("expected one provider, got %d", len(resp.Providers))
+	}
+	p := resp.Providers[0]
+
+	if p.Status != http.StatusOK || p.Body == "" {
+		t.Errorf("expected the raw 200 body, got status %d body %q", p.Status, p.Body)
+	}
+	// The misconfigured title path shows up as an empty field
+	if p.Fields["artist"] != "Nina Simone" || p.Fields["title"] != "" {
+		t.Errorf("unexpected fields %v", p.Fields)
+	}
+	if p.Metadata != "StreamTitle='Nina Simone - ';" {
+		t.Errorf("unexpected metadata %q", p.Metadata)
+	}
+
+	// The one-off fetch doesn't change what listeners see
+	if current := mgr.Get("test_station").CurrentMetadata(); current == p.Metadata {
+		t.Error("expected debug fetch not to update the station")
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/nope/debug/metadata", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown station, got %d", rec.Code)
+	}
+}
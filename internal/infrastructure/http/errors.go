@@ -0,0 +1,84 @@
+// ABOUTME: JSON error envelope and response helpers for the JSON endpoints
+// ABOUTME: Errors are written as {"error": {"code": "...", "message": "..."}}
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+)
+
+// Machine-readable codes used in error envelopes
+const (
+	codeNotFound         = "not_found"
+	codeStationNotFound  = "station_not_found"
+	codeBadRequest       = "bad_request"
+	codeUnauthorized     = "unauthorized"
+	codeForbidden        = "forbidden"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeConflict         = "conflict"
+	codeUnavailable      = "unavailable"
+	codeUpstream         = "upstream_error"
+	codeInternal         = "internal_error"
+)
+
+type errorBody struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// writeError writes a JSON error envelope with the given status
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	var body errorBody
+	body.Error.Code = code
+	body.Error.Message = message
+
+	data, _ := json.Marshal(body)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+func writeNotFound(w http.ResponseWriter) {
+	writeError(w, http.StatusNotFound, codeNotFound, "not found")
+}
+
+func writeStationNotFound(w http.ResponseWriter, stationID string) {
+	writeError(w, http.StatusNotFound, codeStationNotFound, fmt.Sprintf("station %q not found", stationID))
+}
+
+// writeStationMissing reports a station that Get didn't return: 503 when it
+// is configured but disabled, else 404
+func writeStationMissing(w http.ResponseWriter, mgr *manager.Manager, stationID string) {
+	if mgr.Disabled(stationID) {
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, fmt.Sprintf("station %q is disabled", stationID))
+		return
+	}
+	writeStationNotFound(w, stationID)
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "method not allowed")
+}
+
+// writeJSON encodes v before writing anything, so an encoding failure
+// becomes a 500 envelope rather than a truncated body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("encode response: %v", err)
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to encode response")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
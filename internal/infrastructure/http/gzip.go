@@ -0,0 +1,120 @@
+// ABOUTME: Content-negotiated gzip middleware for JSON and text endpoints
+// ABOUTME: Never compresses audio streams or server-sent events
+package http
+
+import (
+	"compress/gzip"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// compressibleTypes are the content type prefixes worth compressing
+var compressibleTypes = []string{
+	"application/json",
+	"application/javascript",
+	"application/openmetrics-text",
+	"text/plain",
+	"text/html",
+	"text/xml",
+}
+
+// Gzip compresses responses for clients that accept it. Stream routes are
+// passed through untouched, and other responses are only compressed when
+// their Content-Type is known to be compressible, so audio and
+// text/event-stream are never buffered behind a gzip writer.
+func Gzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || isStreamPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.Close()
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		next.ServeHTTP(gw, r)
+	})
+}
+
+func isStreamPath(p string) bool {
+	if _, endpoint, _, ok := splitStationPath(p); ok {
+		return endpoint == "stream"
+	}
+
+	// Bare /stream (or /stream.mp3, ...) on the default station
+	name := strings.Trim(p, "/")
+	if ext := path.Ext(name); audioExtensions[ext] != nil {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return name == "stream"
+}
+
+func compressible(contentType string) bool {
+	for _, t := range compressibleTypes {
+		if strings.HasPrefix(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter decides whether to compress once headers are known
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+
+	h := g.Header()
+	if status != http.StatusNoContent && status != http.StatusNotModified &&
+		h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+	}
+
+	g.ResponseWriter.WriteHeader(status)
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !g.wroteHeader {
+		if g.Header().Get("Content-Type") == "" {
+			g.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		g.WriteHeader(http.StatusOK)
+	}
+
+	if g.gz != nil {
+		return g.gz.Write(p)
+	}
+	return g.ResponseWriter.Write(p)
+}
+
+func (g *gzipResponseWriter) Flush() {
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (g *gzipResponseWriter) Close() error {
+	if g.gz != nil {
+		return g.gz.Close()
+	}
+	return nil
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
@@ -0,0 +1,89 @@
+// ABOUTME: Tests for gzip middleware
+// ABOUTME: Verifies JSON is compressed while audio and SSE are not
+package http
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+)
+
+func TestGzip_CompressesJSON(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	handler := Gzip(NewStationsHandler(mgr))
+
+	req := httptest.NewRequest("GET", "/stations", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if enc := rec.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", enc)
+	}
+
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+
+	var stations []map[string]interface{}
+	if err := json.NewDecoder(zr).Decode(&stations); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if len(stations) != 1 {
+		t.Errorf("expected 1 station, got %d", len(stations))
+	}
+}
+
+func TestGzip_SkipsStream(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	handler := Gzip(NewStreamHandler(mgr))
+
+	req := httptest.NewRequest("GET", "/test_station/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := streamFor(handler, req, 50*time.Millisecond)
+
+	if enc := rec.Header().Get("Content-Encoding"); enc != "" {
+		t.Errorf("expected no encoding on audio stream, got %q", enc)
+	}
+}
+
+func TestGzip_SkipsEventStream(t *testing.T) {
+	handler := Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: hello\n\n")
+	}))
+
+	req := httptest.NewRequest("GET", "/test_station/events", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if enc := rec.Header().Get("Content-Encoding"); enc != "" {
+		t.Errorf("expected no encoding on SSE, got %q", enc)
+	}
+
+	if rec.Body.String() != "data: hello\n\n" {
+		t.Errorf("unexpected body %q", rec.Body.String())
+	}
+}
+
+func TestGzip_NoAcceptEncoding(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	handler := Gzip(NewStationsHandler(mgr))
+
+	req := httptest.NewRequest("GET", "/stations", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if enc := rec.Header().Get("Content-Encoding"); enc != "" {
+		t.Errorf("expected identity encoding, got %q", enc)
+	}
+}
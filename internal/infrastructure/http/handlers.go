@@ -3,48 +3,172 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
 	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 
+	"github.com/harper/radio-metadata-proxy/internal/application/config"
 	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+	"github.com/harper/radio-metadata-proxy/internal/domain"
 	"github.com/harper/radio-metadata-proxy/internal/domain/station"
 	"github.com/harper/radio-metadata-proxy/internal/infrastructure/icy"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/logging"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/metrics"
 )
 
 type StreamHandler struct {
-	mgr *manager.Manager
+	mgr             *manager.Manager
+	transcoder      domain.Transcoder
+	bitrates        []int
+	connectionClose bool
+	lowLatency      bool
+	connectLog      *logging.Sampler
 }
 
 func NewStreamHandler(mgr *manager.Manager) *StreamHandler {
-	return &StreamHandler{mgr: mgr}
+	return &StreamHandler{mgr: mgr, connectionClose: true}
+}
+
+// WithConnectionClose controls the Connection: close header on HTTP/1.x
+// streams. It is never sent over HTTP/2, where it is a forbidden hop-by-hop header.
+func (h *StreamHandler) WithConnectionClose(enabled bool) *StreamHandler {
+	h.connectionClose = enabled
+	return h
+}
+
+// WithLowLatency flushes every burst slice as it is written rather than
+// the whole burst at the end, so players get their first audio sooner at
+// the cost of more, smaller packets. Live chunks are always flushed.
+func (h *StreamHandler) WithLowLatency(enabled bool) *StreamHandler {
+	h.lowLatency = enabled
+	return h
+}
+
+// WithConnectSampling logs only one in every n client connect/disconnect
+// pairs, keeping busy stations' logs readable. Errors are never sampled.
+func (h *StreamHandler) WithConnectSampling(n int) *StreamHandler {
+	h.connectLog = logging.NewSampler(n)
+	return h
+}
+
+// WithTranscoder enables ?bitrate= requests for the given target bitrates (kbps).
+func (h *StreamHandler) WithTranscoder(t domain.Transcoder, bitrates []int) *StreamHandler {
+	h.transcoder = t
+	h.bitrates = bitrates
+	return h
 }
 
 func (h *StreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Extract station ID from path: /{station}/stream
-	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	if len(parts) != 2 || parts[1] != "stream" {
-		http.NotFound(w, r)
+	// Extract station ID from path: /{station}/stream[.mp3|.aac|.ogg]
+	stationID, endpoint, ext, ok := splitStationPath(r.URL.Path)
+	if !ok || endpoint != "stream" {
+		writeNotFound(w)
 		return
 	}
 
-	stationID := parts[0]
 	st := h.mgr.Get(stationID)
 	if st == nil {
-		http.NotFound(w, r)
+		writeStationMissing(w, h.mgr, stationID)
+		return
+	}
+
+	if name := r.URL.Query().Get("variant"); name != "" {
+		v := st.Variant(name)
+		if v == nil {
+			writeError(w, http.StatusNotFound, codeNotFound, fmt.Sprintf("station %q has no variant %q", st.ID(), name))
+			return
+		}
+		st = v
+	}
+
+	// An extension alias must match the station's codec
+	if ext != "" && !slices.Contains(audioExtensions[ext], st.ContentType()) {
+		writeError(w, http.StatusNotFound, codeNotFound, fmt.Sprintf("station %q does not serve %s", st.ID(), ext))
+		return
+	}
+
+	// Resolve requested output bitrate; 0 means passthrough
+	bitrate, err := h.targetBitrate(r, st.Bitrate())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	// Check if client wants ICY metadata; container formats never get it
+	wantsMetadata := wantsICYMetadata(r) && st.InjectsICY()
+
+	// New listeners are refused while draining; existing streams continue
+	if h.mgr.Draining() {
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "server shutting down")
+		return
+	}
+
+	// Enforce the server-wide connection cap
+	if !h.mgr.AcquireConnection() {
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "server at capacity")
 		return
 	}
+	defer h.mgr.ReleaseConnection()
 
-	// Check if client wants ICY metadata
-	wantsMetadata := r.Header.Get("Icy-MetaData") == "1"
+	// Subscribe to station chunks
+	client := &station.Client{
+		Addr:        r.RemoteAddr,
+		UserAgent:   r.UserAgent(),
+		ConnectedAt: time.Now(),
+		ICYMetadata: wantsMetadata,
+	}
+	if wantsMetadata {
+		client.MetaInt = st.MetaInt()
+	}
+	// Transcoded streams start live; the burst is source-bitrate audio
+	burstBytes := st.BurstBytes()
+	if bitrate > 0 {
+		burstBytes = 0
+	}
+	burst, chunks := st.SubscribeBurst(client, burstBytes)
+	defer st.Unsubscribe(client)
+	if h.connectLog.Sample() {
+		log.Printf("station %s: client %s connected from %s", st.ID(), client.ID, client.Addr)
+		defer log.Printf("station %s: client %s disconnected", st.ID(), client.ID)
+	}
 
-	// Set ICY headers
-	w.Header().Set("Content-Type", "audio/mpeg")
+	if bitrate > 0 {
+		out, err := h.transcoder.Transcode(r.Context(), &chunkReader{chunks: chunks}, bitrate)
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, codeUnavailable, "transcoder unavailable")
+			return
+		}
+		defer out.Close()
+		chunks = readChunks(r.Context(), out)
+	}
+
+	// Stream headers go out only with the 200, so ICY clients never see
+	// icy-metaint on a refusal and try to parse its JSON body as audio
+	icyBr := st.Bitrate()
+	if bitrate > 0 {
+		icyBr = bitrate
+	}
+	w.Header().Set("Content-Type", st.ContentType())
 	w.Header().Set("icy-name", st.ICYName())
-	w.Header().Set("icy-br", fmt.Sprintf("%d", st.BitrateHint()))
-	w.Header().Set("Cache-Control", "no-store")
-	w.Header().Set("Connection", "close")
+	w.Header().Set("icy-br", fmt.Sprintf("%d", icyBr))
+	// HTTP/1.0 clients never get chunked encoding: with no Content-Length,
+	// net/http sends the raw body and ends it by closing the connection, so
+	// Connection: close is always accurate for them
+	if r.ProtoMajor == 1 && (h.connectionClose || r.ProtoMinor == 0) {
+		w.Header().Set("Connection", "close")
+	}
 
 	// Only send metaint if client wants metadata
 	if wantsMetadata {
@@ -53,74 +177,51 @@ func (h *StreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusOK)
 
-	// Subscribe to station chunks
-	client := &station.Client{ID: fmt.Sprintf("http-%p", r)}
-	chunks := st.Subscribe(client)
-	defer st.Unsubscribe(client)
-
 	// Stream with ICY metadata injection
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		return
 	}
 
-	var metaInt int
-	var bytesUntilMeta int
-
+	// Every audio byte goes through one ICY writer so metadata blocks stay
+	// exactly metaint bytes apart from the start of the body. The metadata
+	// block is shared and pre-encoded, rebuilt only when metadata changes.
+	metaInt := 0
 	if wantsMetadata {
 		metaInt = st.MetaInt()
-		bytesUntilMeta = metaInt
+	}
+	out := icy.NewWriter(w, metaInt, st.CurrentMetadataBlock)
+	if st.SendsInitialMeta() {
+		if err := out.WriteInitialBlock(); err != nil {
+			return
+		}
 	}
 
+	// A client that drops mid-burst must not reach the live loop
+	var burstOut io.Writer = out
+	if h.lowLatency {
+		burstOut = &flushingWriter{w: out, flusher: flusher}
+	}
+	if err := writeBurst(r.Context(), burstOut, burst); err != nil {
+		return
+	}
+	flusher.Flush()
+
 	for {
 		select {
 		case <-r.Context().Done():
 			return
+		case <-h.mgr.Closing():
+			// Server shutdown: end the response cleanly instead of being cut off
+			flusher.Flush()
+			return
 		case chunk, ok := <-chunks:
 			if !ok {
 				return
 			}
 
-			// Write chunk in pieces, injecting metadata at intervals
-			for len(chunk) > 0 {
-				if wantsMetadata {
-					// Write up to next metadata point
-					toWrite := len(chunk)
-					if toWrite > bytesUntilMeta {
-						toWrite = bytesUntilMeta
-					}
-
-					n, err := w.Write(chunk[:toWrite])
-					if err != nil {
-						return
-					}
-
-					chunk = chunk[n:]
-					bytesUntilMeta -= n
-
-					// Inject metadata if needed
-					if bytesUntilMeta == 0 {
-						meta := st.CurrentMetadata()
-						if meta == "" {
-							meta = "StreamTitle='';"
-						}
-
-						// Always send metadata at intervals (ICY spec requires it)
-						metaBlock := icy.BuildBlock(meta)
-						if _, err := w.Write(metaBlock); err != nil {
-							return
-						}
-
-						bytesUntilMeta = metaInt
-					}
-				} else {
-					// No metadata - just stream audio directly
-					n, err := w.Write(chunk)
-					if err != nil {
-						return
-					}
-					chunk = chunk[n:]
-				}
+			if _, err := out.Write(chunk); err != nil {
+				return
 			}
 
 			flusher.Flush()
@@ -128,6 +229,43 @@ func (h *StreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// wantsICYMetadata reports whether the client asked for interleaved metadata.
+// Any non-zero integer counts, tolerating surrounding whitespace. A truthy
+// ?nometa query parameter overrides the header for players that send
+// Icy-MetaData: 1 but can't parse the blocks.
+func wantsICYMetadata(r *http.Request) bool {
+	if nometa, err := strconv.ParseBool(r.URL.Query().Get("nometa")); err == nil && nometa {
+		return false
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(r.Header.Get("Icy-MetaData")))
+	return err == nil && n != 0
+}
+
+// targetBitrate validates the ?bitrate= query parameter. It returns 0 when the
+// source should be passed through unchanged.
+func (h *StreamHandler) targetBitrate(r *http.Request, sourceKbps int) (int, error) {
+	raw := r.URL.Query().Get("bitrate")
+	if raw == "" {
+		return 0, nil
+	}
+
+	kbps, err := strconv.Atoi(raw)
+	if err != nil || kbps <= 0 {
+		return 0, fmt.Errorf("invalid bitrate %q", raw)
+	}
+
+	if kbps == sourceKbps {
+		return 0, nil
+	}
+
+	if h.transcoder == nil || !slices.Contains(h.bitrates, kbps) {
+		return 0, fmt.Errorf("bitrate %d not available", kbps)
+	}
+
+	return kbps, nil
+}
+
 type MetaHandler struct {
 	mgr *manager.Manager
 }
@@ -137,41 +275,157 @@ func NewMetaHandler(mgr *manager.Manager) *MetaHandler {
 }
 
 func (h *MetaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	if len(parts) != 2 || parts[1] != "meta" {
-		http.NotFound(w, r)
+	stationID, endpoint, ext, ok := splitStationPath(r.URL.Path)
+	if !ok || (endpoint != "meta" && endpoint != "meta.txt") || ext != "" {
+		writeNotFound(w)
 		return
 	}
 
-	stationID := parts[0]
 	st := h.mgr.Get(stationID)
 	if st == nil {
-		http.NotFound(w, r)
+		writeStationMissing(w, h.mgr, stationID)
 		return
 	}
 
 	type response struct {
-		Current      string  `json:"current"`
-		UpdatedAt    *string `json:"updated_at,omitempty"`
-		SourceHealthy bool   `json:"sourceHealthy"`
+		Current       string      `json:"current"`
+		UpdatedAt     interface{} `json:"updated_at,omitempty"`
+		SourceHealthy bool        `json:"sourceHealthy"`
+		Provisional   bool        `json:"provisional"`
 	}
 
-	var updatedAt *string
-	if t := st.LastMetadataUpdate(); t != nil {
-		s := t.Format("2006-01-02T15:04:05Z07:00")
-		updatedAt = &s
+	updatedAt := formatMetaTime(st.LastMetadataUpdate(), h.mgr.Config().Server.MetaTimeFormat)
+
+	current, ok := st.RenderMetadata(r.URL.Query().Get("format"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, codeBadRequest, fmt.Sprintf("unknown format %q", r.URL.Query().Get("format")))
+		return
+	}
+
+	current = sanitizeUTF8(st.ID(), current)
+
+	// Plain title for signage and other clients without a JSON parser
+	if endpoint == "meta" {
+		w.Header().Add("Vary", "Accept")
+	}
+	if endpoint == "meta.txt" || prefersPlainText(r.Header.Get("Accept")) {
+		body := []byte(plainTitle(current))
+		etag := metaETag(body)
+		w.Header().Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(body)
+		return
 	}
 
 	resp := response{
-		Current:      st.CurrentMetadata(),
-		UpdatedAt:    updatedAt,
+		Current:       current,
+		UpdatedAt:     updatedAt,
 		SourceHealthy: st.SourceHealthy(),
+		Provisional:   st.Provisional(),
+	}
+
+	// JSONP for legacy embedded players that cannot use CORS
+	if callback := r.URL.Query().Get("callback"); callback != "" {
+		if !jsonpCallbackRe.MatchString(callback) {
+			writeError(w, http.StatusBadRequest, codeBadRequest, "invalid callback")
+			return
+		}
+
+		body, _ := json.Marshal(resp)
+		w.Header().Set("Content-Type", "application/javascript")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		fmt.Fprintf(w, "%s(%s);", callback, body)
+		return
+	}
+
+	// Weak ETag over the rendered body, which covers the current metadata
+	// and updated_at, so frequent pollers get 304s while nothing changes
+	body, _ := json.Marshal(resp)
+	etag := metaETag(body)
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	w.Write(append(body, '\n'))
+}
+
+// prefersPlainText reports whether an Accept header ranks text/plain above
+// application/json. Wildcards don't count, so the default stays JSON.
+func prefersPlainText(accept string) bool {
+	textQ, jsonQ := 0.0, 0.0
+	textFirst := false
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case "text/plain":
+			textFirst = textFirst || jsonQ == 0
+			textQ = q
+		case "application/json":
+			jsonQ = q
+		}
+	}
+	// Ties go to whichever type is listed first
+	return textQ > jsonQ || (textQ > 0 && textQ == jsonQ && textFirst)
+}
+
+// metaETag returns a weak entity tag for a /meta response body.
+func metaETag(body []byte) string {
+	h := fnv.New64a()
+	h.Write(body)
+	return fmt.Sprintf(`W/"%x"`, h.Sum64())
 }
 
+// etagMatches reports whether an If-None-Match header value matches etag,
+// using weak comparison as RFC 9110 requires for If-None-Match.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// utf8Sanitized counts metadata strings that needed invalid UTF-8 replaced,
+// which usually points at a misconfigured source encoding
+var utf8Sanitized atomic.Int64
+
+// sanitizeUTF8 replaces invalid UTF-8 sequences with U+FFFD so JSON output
+// stays well-formed, logging occasionally when it had to intervene.
+func sanitizeUTF8(stationID, s string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+
+	if n := utf8Sanitized.Add(1); n == 1 || n%100 == 0 {
+		log.Printf("station %s: metadata is not valid UTF-8, check source encoding (%d occurrences)", stationID, n)
+	}
+	return strings.ToValidUTF8(s, "\uFFFD")
+}
+
+// jsonpCallbackRe accepts plain or dotted JavaScript identifiers only
+var jsonpCallbackRe = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*(\.[A-Za-z_$][A-Za-z0-9_$]*)*$`)
+
 type StationsHandler struct {
 	mgr *manager.Manager
 }
@@ -181,72 +435,307 @@ func NewStationsHandler(mgr *manager.Manager) *StationsHandler {
 }
 
 func (h *StationsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	type variantInfo struct {
+		Name          string `json:"name"`
+		StreamURL     string `json:"stream_url"`
+		ContentType   string `json:"contentType"`
+		BitrateKbps   int    `json:"bitrateKbps"`
+		Clients       int    `json:"clients"`
+		SourceHealthy bool   `json:"sourceHealthy"`
+	}
 	type stationInfo struct {
 		ID            string `json:"id"`
 		StreamURL     string `json:"stream_url"`
 		MetaURL       string `json:"meta_url"`
 		Clients       int    `json:"clients"`
+		PeakClients   int    `json:"peakClients"`
+		PeakToday     int    `json:"peakClientsToday"`
 		SourceHealthy bool   `json:"sourceHealthy"`
+		SourceMirror  *int   `json:"sourceMirror,omitempty"` // index of the active source mirror
+		SourceStatus  int    `json:"sourceStatus,omitempty"` // HTTP status of the latest upstream response
+		SourceError   string `json:"sourceError,omitempty"`  // kind of the latest connect failure; details are in /admin/sources
+		MetadataStale bool   `json:"metadataStale"`          // title unchanged past metadata.stale_after_ms while on air
+
+		BitrateKbps         int `json:"bitrateKbps"`         // configured bitrate_hint_kbps
+		DetectedBitrateKbps int `json:"detectedBitrateKbps"` // 0 when detection is off or pending
+
+		MetadataChanges    int64       `json:"metadataChanges"`
+		LastMetadataChange interface{} `json:"lastMetadataChange,omitempty"`
+
+		Variants []variantInfo `json:"variants,omitempty"`
 	}
 
 	stations := h.mgr.List()
+	timeFormat := h.mgr.Config().Server.MetaTimeFormat
 	result := make([]stationInfo, 0, len(stations))
+	base := h.mgr.Config().Server.BasePath
 
 	for _, st := range stations {
+		var variants []variantInfo
+		for _, name := range st.VariantNames() {
+			v := st.Variant(name)
+			variants = append(variants, variantInfo{
+				Name:          name,
+				StreamURL:     fmt.Sprintf("%s/%s/stream?variant=%s", base, st.ID(), url.QueryEscape(name)),
+				ContentType:   v.ContentType(),
+				BitrateKbps:   v.Bitrate(),
+				Clients:       v.ClientCount(),
+				SourceHealthy: v.SourceHealthy(),
+			})
+		}
+
 		result = append(result, stationInfo{
 			ID:            st.ID(),
-			StreamURL:     fmt.Sprintf("/%s/stream", st.ID()),
-			MetaURL:       fmt.Sprintf("/%s/meta", st.ID()),
+			StreamURL:     fmt.Sprintf("%s/%s/stream", base, st.ID()),
+			MetaURL:       fmt.Sprintf("%s/%s/meta", base, st.ID()),
 			Clients:       st.ClientCount(),
+			PeakClients:   st.PeakClientCount(),
+			PeakToday:     st.DailyPeakClientCount(),
 			SourceHealthy: st.SourceHealthy(),
+			SourceMirror:  sourceMirror(st),
+			SourceStatus:  st.SourceStatus(),
+			SourceError:   st.SourceErrorKind(),
+			MetadataStale: st.MetadataStale(),
+
+			BitrateKbps:         st.BitrateHint(),
+			DetectedBitrateKbps: st.DetectedBitrate(),
+
+			MetadataChanges:    st.MetadataChangeCount(),
+			LastMetadataChange: formatMetaTime(st.LastMetadataChange(), timeFormat),
+
+			Variants: variants,
 		})
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// sourceMirror returns the active mirror index, or nil when there is none
+// to report
+func sourceMirror(st *station.Station) *int {
+	if i := st.SourceMirror(); i >= 0 {
+		return &i
+	}
+	return nil
+}
+
+// NowPlayingHandler returns current metadata for every station in one response.
+type NowPlayingHandler struct {
+	mgr *manager.Manager
+}
+
+func NewNowPlayingHandler(mgr *manager.Manager) *NowPlayingHandler {
+	return &NowPlayingHandler{mgr: mgr}
+}
+
+func (h *NowPlayingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	type nowPlaying struct {
+		Title         string            `json:"title"`
+		Fields        map[string]string `json:"fields,omitempty"`
+		UpdatedAt     interface{}       `json:"updatedAt,omitempty"`
+		SourceHealthy bool              `json:"sourceHealthy"`
+		Clients       int               `json:"clients"`
+	}
+
+	// Optional ?station=a,b filter
+	var filter map[string]bool
+	if raw := r.URL.Query().Get("station"); raw != "" {
+		filter = make(map[string]bool)
+		for _, id := range strings.Split(raw, ",") {
+			filter[strings.TrimSpace(id)] = true
+		}
+	}
+
+	timeFormat := h.mgr.Config().Server.MetaTimeFormat
+	result := make(map[string]nowPlaying)
+	for _, st := range h.mgr.List() {
+		if filter != nil && !filter[st.ID()] {
+			continue
+		}
+
+		result[st.ID()] = nowPlaying{
+			Title:         displayTitle(st),
+			Fields:        st.Fields(),
+			UpdatedAt:     formatMetaTime(st.LastMetadataUpdate(), timeFormat),
+			SourceHealthy: st.SourceHealthy(),
+			Clients:       st.ClientCount(),
+		}
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// burstSlice bounds each burst write so a disconnect is noticed promptly
+const burstSlice = 16 * 1024
+
+// writeBurst sends the connect burst in slices, stopping at the first write
+// error or when the client's request is cancelled
+func writeBurst(ctx context.Context, w io.Writer, burst []byte) error {
+	for len(burst) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := w.Write(burst[:min(burstSlice, len(burst))])
+		if err != nil {
+			return err
+		}
+		burst = burst[n:]
+	}
+	return nil
+}
+
+// flushingWriter flushes after every successful write
+type flushingWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+}
+
+func (f *flushingWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err == nil {
+		f.flusher.Flush()
+	}
+	return n, err
 }
 
-func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+// formatMetaTime serializes a metadata timestamp as an RFC3339 string or a
+// Unix number. A nil time yields nil so the field is omitted.
+func formatMetaTime(t *time.Time, format string) interface{} {
+	if t == nil {
+		return nil
+	}
+
+	switch format {
+	case config.TimeFormatUnix:
+		return t.Unix()
+	case config.TimeFormatUnixMilli:
+		return t.UnixMilli()
+	default:
+		return t.Format("2006-01-02T15:04:05Z07:00")
+	}
+}
+
+// displayTitle returns the StreamTitle value, or the raw metadata when it
+// is not in ICY key='value'; form
+func displayTitle(st *station.Station) string {
+	return plainTitle(st.CurrentMetadata())
+}
+
+// plainTitle is displayTitle for an already rendered metadata string
+func plainTitle(meta string) string {
+	if title := extractKV(meta, "StreamTitle"); title != "" {
+		return title
+	}
+	if strings.Contains(meta, "StreamTitle='") {
+		return ""
+	}
+	return meta
+}
+
+// HealthzHandler reports process liveness. With ?strict=1 it also reports
+// global connection usage and fails with 503 when the server is at capacity.
+type HealthzHandler struct {
+	mgr *manager.Manager
+}
+
+func NewHealthzHandler(mgr *manager.Manager) *HealthzHandler {
+	return &HealthzHandler{mgr: mgr}
+}
+
+func (h *HealthzHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	type connections struct {
+		Current int `json:"current"`
+		Max     int `json:"max"`
+	}
+
 	type response struct {
-		OK bool `json:"ok"`
+		OK          bool         `json:"ok"`
+		Draining    bool         `json:"draining,omitempty"`
+		Connections *connections `json:"connections,omitempty"`
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response{OK: true})
+	resp := response{OK: true}
+	status := http.StatusOK
+
+	// Fail health checks while draining so load balancers stop sending
+	// listeners; the remaining stream count shows drain progress
+	if h.mgr.Draining() {
+		current, max := h.mgr.Connections()
+		resp.OK, resp.Draining = false, true
+		resp.Connections = &connections{Current: current, Max: max}
+		status = http.StatusServiceUnavailable
+	}
+
+	if r.URL.Query().Get("strict") == "1" && !resp.Draining {
+		current, max := h.mgr.Connections()
+		resp.Connections = &connections{Current: current, Max: max}
+		if max > 0 && current >= max {
+			resp.OK = false
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	writeJSON(w, status, resp)
 }
 
-// CoverHandler redirects to (or serves) the current artwork URL for a station.
-type CoverHandler struct {
+// MetricsHandler exposes per-station metrics in OpenMetrics text format.
+type MetricsHandler struct {
 	mgr *manager.Manager
 }
 
-func NewCoverHandler(mgr *manager.Manager) *CoverHandler {
-	return &CoverHandler{mgr: mgr}
+func NewMetricsHandler(mgr *manager.Manager) *MetricsHandler {
+	return &MetricsHandler{mgr: mgr}
 }
 
-func (h *CoverHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	if len(parts) != 2 || parts[1] != "cover" {
-		http.NotFound(w, r)
-		return
+func (h *MetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	stations := h.mgr.List()
+
+	w.Header().Set("Content-Type", metrics.ContentType)
+	mw := metrics.NewWriter(w)
+
+	mw.Family("icyproxy_clients", "gauge", "Connected stream clients.")
+	for _, st := range stations {
+		mw.Sample("icyproxy_clients", metrics.Labels{"station": st.ID()}, float64(st.ClientCount()))
 	}
 
-	stationID := parts[0]
-	st := h.mgr.Get(stationID)
-	if st == nil {
-		http.NotFound(w, r)
-		return
+	mw.Family("icyproxy_clients_peak", "gauge", "Peak concurrent stream clients since start or the last reset.")
+	for _, st := range stations {
+		mw.Sample("icyproxy_clients_peak", metrics.Labels{"station": st.ID()}, float64(st.PeakClientCount()))
 	}
 
-	meta := st.CurrentMetadata()
-	// Parse Artwork='...'; from the ICY string
-	art := extractKV(meta, "Artwork")
-	if art == "" {
-		http.NotFound(w, r)
-		return
+	mw.Family("icyproxy_clients_peak_daily", "gauge", "Peak concurrent stream clients during the current UTC day.")
+	for _, st := range stations {
+		mw.Sample("icyproxy_clients_peak_daily", metrics.Labels{"station": st.ID()}, float64(st.DailyPeakClientCount()))
+	}
+
+	mw.Family("icyproxy_client_buffer_fill", "histogram", "Chunks queued per client at fan-out time; high values indicate slow clients.")
+	for _, st := range stations {
+		mw.Histogram("icyproxy_client_buffer_fill", metrics.Labels{"station": st.ID()}, st.ClientFill().Snapshot())
 	}
 
-	http.Redirect(w, r, art, http.StatusFound)
+	mw.Family("icyproxy_metadata_changes", "counter", "Times the station's metadata changed value.")
+	for _, st := range stations {
+		mw.Sample("icyproxy_metadata_changes_total", metrics.Labels{"station": st.ID()}, float64(st.MetadataChangeCount()))
+	}
+
+	mw.Family("icyproxy_metadata_last_change_seconds", "gauge", "Unix time of the station's last metadata change.")
+	for _, st := range stations {
+		if t := st.LastMetadataChange(); t != nil {
+			mw.Sample("icyproxy_metadata_last_change_seconds", metrics.Labels{"station": st.ID()}, float64(t.UnixMilli())/1000)
+		}
+	}
+
+	mw.Family("icyproxy_metadata_fetch_panics", "counter", "Metadata fetches that panicked on malformed upstream data and were recovered.")
+	for _, st := range stations {
+		for _, p := range st.ProviderStatus() {
+			mw.Sample("icyproxy_metadata_fetch_panics_total", metrics.Labels{"station": st.ID(), "provider": p.Name}, float64(p.Panics))
+		}
+	}
+
+	mw.Family("icyproxy_meta_utf8_sanitized", "counter", "Metadata responses that contained invalid UTF-8.")
+	mw.Sample("icyproxy_meta_utf8_sanitized_total", nil, float64(utf8Sanitized.Load()))
+
+	mw.EOF()
 }
 
 // extractKV finds Key='value'; in a semicolon-separated ICY string.
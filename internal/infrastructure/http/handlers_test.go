@@ -3,13 +3,28 @@
 package http
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
-	"github.com/harper/radio-metadata-proxy/internal/application/manager"
 	"github.com/harper/radio-metadata-proxy/internal/application/config"
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+	"github.com/harper/radio-metadata-proxy/internal/domain/station"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/icy"
 )
 
 func TestStreamHandler_404(t *testing.T) {
@@ -60,7 +75,12 @@ func TestStreamHandler_Success(t *testing.T) {
 
 	handler := NewStreamHandler(mgr)
 
-	req := httptest.NewRequest("GET", "/test_station/stream", nil)
+	// The stream never ends on its own; bound it with the request context
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	req := httptest.NewRequest("GET", "/test_station/stream", nil).WithContext(ctx)
+	req.Header.Set("Icy-MetaData", "1")
 	rec := httptest.NewRecorder()
 
 	handler.ServeHTTP(rec, req)
@@ -86,6 +106,50 @@ func TestStreamHandler_Success(t *testing.T) {
 	}
 }
 
+func TestStreamHandler_ConcurrentClients(t *testing.T) {
+	cfg := &config.Config{
+		Stations: []config.StationConfig{
+			{
+				ID:  "test_station",
+				ICY: config.ICYConfig{Name: "Test Station", MetaInt: 16384, BitrateHintKbps: 128},
+				Metadata: config.MetadataConfig{
+					URL:    "http://example.com/meta",
+					PollMs: 3000,
+				},
+				Buffering: config.BufferingConfig{RingBytes: 262144},
+			},
+		},
+	}
+
+	mgr, _ := manager.NewFromConfig(cfg)
+	st := mgr.Get("test_station")
+	handler := NewStreamHandler(mgr)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			req := httptest.NewRequest("GET", "/test_station/stream", nil).WithContext(ctx)
+			req.Header.Set("Icy-MetaData", "1")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		st.UpdateMetadata("StreamTitle='Concurrent';")
+	}
+
+	wg.Wait()
+
+	if count := st.ClientCount(); count != 0 {
+		t.Errorf("expected 0 clients after all streams ended, got %d", count)
+	}
+}
+
 func TestMetaHandler_404(t *testing.T) {
 	cfg := &config.Config{
 		Stations: []config.StationConfig{},
@@ -244,11 +308,84 @@ func TestStationsHandler(t *testing.T) {
 	}
 }
 
+func TestHandlers_StationNotFoundEnvelope(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+
+	for path, h := range map[string]http.Handler{
+		"/nope/meta":   NewMetaHandler(mgr),
+		"/nope/stream": NewStreamHandler(mgr),
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: expected JSON error, got %q", path, ct)
+		}
+
+		var body struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("%s: failed to decode error: %v", path, err)
+		}
+		if body.Error.Code != codeStationNotFound || !strings.Contains(body.Error.Message, `"nope"`) {
+			t.Errorf("%s: expected station_not_found naming the station, got %+v", path, body.Error)
+		}
+	}
+}
+
+func TestHandlers_DisabledStation(t *testing.T) {
+	cfg := singleStationConfig()
+	off := false
+	cfg.Stations = append(cfg.Stations, config.StationConfig{ID: "off", Enabled: &off})
+	mgr, err := manager.NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+
+	for path, h := range map[string]http.Handler{
+		"/off/meta":   NewMetaHandler(mgr),
+		"/off/stream": NewStreamHandler(mgr),
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), codeUnavailable) {
+			t.Errorf("%s: expected 503 unavailable, got %d %s", path, rec.Code, rec.Body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	NewStationsHandler(mgr).ServeHTTP(rec, httptest.NewRequest("GET", "/stations", nil))
+	if strings.Contains(rec.Body.String(), `"off"`) {
+		t.Errorf("disabled station listed in /stations: %s", rec.Body)
+	}
+}
+
+func TestWriteJSON_EncodeFailure(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeJSON(rec, http.StatusOK, map[string]interface{}{"bad": func() {}})
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), codeInternal) {
+		t.Errorf("expected internal_error envelope, got %q", rec.Body.String())
+	}
+}
+
 func TestHealthzHandler(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(&config.Config{})
+
 	req := httptest.NewRequest("GET", "/healthz", nil)
 	rec := httptest.NewRecorder()
 
-	HealthzHandler(rec, req)
+	NewHealthzHandler(mgr).ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusOK {
 		t.Errorf("expected 200, got %d", rec.Code)
@@ -270,3 +407,1058 @@ func TestHealthzHandler(t *testing.T) {
 		t.Error("expected ok: true")
 	}
 }
+
+// singleStationConfig returns a config with one unstarted station "test_station"
+func singleStationConfig() *config.Config {
+	return &config.Config{
+		Stations: []config.StationConfig{
+			{
+				ID: "test_station",
+				ICY: config.ICYConfig{
+					Name:            "Test Station",
+					MetaInt:         16384,
+					BitrateHintKbps: 128,
+				},
+				Source: config.SourceConfig{
+					URL:              "http://example.com/stream.mp3",
+					ConnectTimeoutMs: 5000,
+				},
+				Metadata: config.MetadataConfig{
+					URL:    "http://example.com/meta",
+					PollMs: 3000,
+				},
+				Buffering: config.BufferingConfig{
+					RingBytes: 262144,
+				},
+			},
+		},
+	}
+}
+
+// checkNoStreamHeaders fails if a refused stream request got audio headers
+func checkNoStreamHeaders(t *testing.T, rec *httptest.ResponseRecorder) {
+	t.Helper()
+	for name := range rec.Header() {
+		if strings.HasPrefix(strings.ToLower(name), "icy-") || name == "Connection" {
+			t.Errorf("expected no stream headers on a %d, got %s: %s", rec.Code, name, rec.Header().Get(name))
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected a JSON error body, got Content-Type %q", ct)
+	}
+}
+
+// streamFor runs the stream handler until the request context times out
+func streamFor(h http.Handler, req *http.Request, d time.Duration) *httptest.ResponseRecorder {
+	ctx, cancel := context.WithTimeout(req.Context(), d)
+	defer cancel()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req.WithContext(ctx))
+	return rec
+}
+
+// startFileStation starts station sc as "local", looping audioBytes of 0xAB
+// from a file source paced at its bitrate hint. It shuts down with the test.
+func startFileStation(t *testing.T, audioBytes int, sc config.StationConfig) *manager.Manager {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "loop.mp3")
+	if err := os.WriteFile(path, bytes.Repeat([]byte{0xAB}, audioBytes), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sc.ID = "local"
+	sc.Source = config.SourceConfig{Type: "file", Path: path}
+	mgr, err := manager.NewFromConfig(&config.Config{Stations: []config.StationConfig{sc}})
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { mgr.Shutdown() })
+	return mgr
+}
+
+type passthroughTranscoder struct {
+	bitrate int
+}
+
+func (p *passthroughTranscoder) Transcode(ctx context.Context, in io.Reader, bitrateKbps int) (io.ReadCloser, error) {
+	p.bitrate = bitrateKbps
+	return io.NopCloser(in), nil
+}
+
+func TestStreamHandler_Bitrate(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	tc := &passthroughTranscoder{}
+	handler := NewStreamHandler(mgr).WithTranscoder(tc, []int{64, 96})
+
+	tests := []struct {
+		query    string
+		wantCode int
+		wantBr   string
+	}{
+		{"", http.StatusOK, "128"},
+		{"?bitrate=128", http.StatusOK, "128"},
+		{"?bitrate=64", http.StatusOK, "64"},
+		{"?bitrate=32", http.StatusBadRequest, ""},
+		{"?bitrate=abc", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/test_station/stream"+tt.query, nil)
+		rec := streamFor(handler, req, 50*time.Millisecond)
+
+		if rec.Code != tt.wantCode {
+			t.Errorf("%q: expected %d, got %d", tt.query, tt.wantCode, rec.Code)
+			continue
+		}
+
+		if tt.wantBr != "" && rec.Header().Get("icy-br") != tt.wantBr {
+			t.Errorf("%q: expected icy-br %s, got %s", tt.query, tt.wantBr, rec.Header().Get("icy-br"))
+		}
+	}
+
+	if tc.bitrate != 64 {
+		t.Errorf("expected transcoder to be invoked at 64 kbps, got %d", tc.bitrate)
+	}
+}
+
+func TestStreamHandler_InitialMeta(t *testing.T) {
+	cfg := singleStationConfig()
+	cfg.Stations[0].ICY.SendInitialMeta = true
+
+	mgr, _ := manager.NewFromConfig(cfg)
+	mgr.Get("test_station").UpdateMetadata("StreamTitle='Song';")
+	handler := NewStreamHandler(mgr)
+
+	req := httptest.NewRequest("GET", "/test_station/stream", nil)
+	req.Header.Set("Icy-MetaData", "1")
+	rec := streamFor(handler, req, 50*time.Millisecond)
+
+	if !bytes.HasPrefix(rec.Body.Bytes(), icy.BuildBlock("StreamTitle='Song';")) {
+		t.Errorf("expected body to start with the metadata block, got % x", rec.Body.Bytes())
+	}
+
+	// Clients that didn't ask for metadata get audio only
+	req = httptest.NewRequest("GET", "/test_station/stream", nil)
+	rec = streamFor(handler, req, 50*time.Millisecond)
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected no leading block without Icy-MetaData, got % x", rec.Body.Bytes())
+	}
+}
+
+func TestStreamHandler_BitrateWithoutTranscoder(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	handler := NewStreamHandler(mgr)
+
+	req := httptest.NewRequest("GET", "/test_station/stream?bitrate=64", nil)
+	rec := streamFor(handler, req, 50*time.Millisecond)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestMetaHandler_Format(t *testing.T) {
+	cfg := singleStationConfig()
+	cfg.Stations[0].Metadata.Formats = map[string]string{"short": "StreamTitle='{title}';"}
+
+	mgr, _ := manager.NewFromConfig(cfg)
+	mgr.Get("test_station").UpdateMetadataFields(
+		"StreamTitle='Artist - Song';",
+		map[string]string{"artist": "Artist", "title": "Song"},
+	)
+
+	handler := NewMetaHandler(mgr)
+
+	req := httptest.NewRequest("GET", "/test_station/meta?format=short", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var resp struct {
+		Current string `json:"current"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Current != "StreamTitle='Song';" {
+		t.Errorf("expected short format, got %q", resp.Current)
+	}
+
+	req = httptest.NewRequest("GET", "/test_station/meta?format=nope", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown format, got %d", rec.Code)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	mgr.Get("test_station").ClientFill().Observe(3)
+
+	handler := NewMetricsHandler(mgr)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rec.Code)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`icyproxy_clients{station="test_station"} 0`,
+		`icyproxy_client_buffer_fill_bucket{station="test_station",le="4"} 1`,
+		`icyproxy_client_buffer_fill_count{station="test_station"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetaHandler_Provisional(t *testing.T) {
+	cfg := singleStationConfig()
+	cfg.Stations[0].Metadata.DefaultTitle = "Test Station"
+
+	mgr, _ := manager.NewFromConfig(cfg)
+	handler := NewMetaHandler(mgr)
+
+	var resp struct {
+		Current     string `json:"current"`
+		Provisional bool   `json:"provisional"`
+	}
+
+	req := httptest.NewRequest("GET", "/test_station/meta", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	json.NewDecoder(rec.Body).Decode(&resp)
+
+	if resp.Current != "StreamTitle='Test Station';" || !resp.Provisional {
+		t.Errorf("expected provisional seeded title, got %+v", resp)
+	}
+
+	mgr.Get("test_station").UpdateMetadata("StreamTitle='Song';")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	json.NewDecoder(rec.Body).Decode(&resp)
+
+	if resp.Provisional {
+		t.Error("expected provisional false after an update")
+	}
+}
+
+func TestNowPlayingHandler(t *testing.T) {
+	cfg := singleStationConfig()
+	second := cfg.Stations[0]
+	second.ID = "other_station"
+	cfg.Stations = append(cfg.Stations, second)
+
+	mgr, _ := manager.NewFromConfig(cfg)
+	mgr.Get("test_station").UpdateMetadataFields(
+		"StreamTitle='Artist - Song';",
+		map[string]string{"artist": "Artist", "title": "Song"},
+	)
+
+	handler := NewNowPlayingHandler(mgr)
+
+	type nowPlaying struct {
+		Title     string            `json:"title"`
+		Fields    map[string]string `json:"fields"`
+		UpdatedAt *string           `json:"updatedAt"`
+		Clients   int               `json:"clients"`
+	}
+
+	req := httptest.NewRequest("GET", "/nowplaying", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var all map[string]nowPlaying
+	if err := json.NewDecoder(rec.Body).Decode(&all); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(all) != 2 {
+		t.Fatalf("expected 2 stations, got %d", len(all))
+	}
+
+	np := all["test_station"]
+	if np.Title != "Artist - Song" || np.Fields["artist"] != "Artist" || np.UpdatedAt == nil {
+		t.Errorf("unexpected now playing entry %+v", np)
+	}
+
+	req = httptest.NewRequest("GET", "/nowplaying?station=other_station", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var filtered map[string]nowPlaying
+	json.NewDecoder(rec.Body).Decode(&filtered)
+
+	if _, ok := filtered["other_station"]; !ok || len(filtered) != 1 {
+		t.Errorf("expected only other_station, got %v", filtered)
+	}
+}
+
+func TestWantsICYMetadata(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"1", true},
+		{" 1", true},
+		{"1 ", true},
+		{"2", true},
+		{"0", false},
+		{"", false},
+		{"yes", false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/test_station/stream", nil)
+		if tt.value != "" {
+			req.Header["Icy-Metadata"] = []string{tt.value}
+		}
+
+		if got := wantsICYMetadata(req); got != tt.want {
+			t.Errorf("Icy-MetaData %q: expected %v, got %v", tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestWantsICYMetadata_NoMetaOverride(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"?nometa=1", false},
+		{"?nometa=true", false},
+		{"?nometa=0", true},
+		{"?nometa=", true},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/test_station/stream"+tt.query, nil)
+		req.Header.Set("Icy-MetaData", "1")
+
+		if got := wantsICYMetadata(req); got != tt.want {
+			t.Errorf("query %q: expected %v, got %v", tt.query, tt.want, got)
+		}
+	}
+}
+
+func TestStreamHandler_NoMeta(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	handler := NewStreamHandler(mgr)
+
+	req := httptest.NewRequest("GET", "/test_station/stream?nometa=1", nil)
+	req.Header.Set("Icy-MetaData", "1")
+	rec := streamFor(handler, req, 50*time.Millisecond)
+
+	if metaint := rec.Header().Get("icy-metaint"); metaint != "" {
+		t.Errorf("expected no icy-metaint with ?nometa=1, got %q", metaint)
+	}
+}
+
+func TestStreamHandler_MaxConnections(t *testing.T) {
+	cfg := singleStationConfig()
+	cfg.Server.MaxConnections = 1
+
+	mgr, _ := manager.NewFromConfig(cfg)
+	handler := NewStreamHandler(mgr)
+
+	// Hold the only slot with a long-running stream
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest("GET", "/test_station/stream", nil).WithContext(ctx)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for mgr.Get("test_station").ClientCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	req := httptest.NewRequest("GET", "/test_station/stream", nil)
+	req.Header.Set("Icy-MetaData", "1")
+	rec := streamFor(handler, req, 50*time.Millisecond)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 at capacity, got %d", rec.Code)
+	}
+	checkNoStreamHeaders(t, rec)
+
+	// Strict health reports the saturated cap
+	rec = httptest.NewRecorder()
+	NewHealthzHandler(mgr).ServeHTTP(rec, httptest.NewRequest("GET", "/healthz?strict=1", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected strict healthz 503 at capacity, got %d", rec.Code)
+	}
+
+	var health struct {
+		Connections struct {
+			Current int `json:"current"`
+			Max     int `json:"max"`
+		} `json:"connections"`
+	}
+	json.NewDecoder(rec.Body).Decode(&health)
+	if health.Connections.Current != 1 || health.Connections.Max != 1 {
+		t.Errorf("unexpected connections %+v", health.Connections)
+	}
+
+	cancel()
+	<-done
+
+	if current, _ := mgr.Connections(); current != 0 {
+		t.Errorf("expected slot to be released, got %d active", current)
+	}
+}
+
+func TestMetaHandler_JSONP(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	mgr.Get("test_station").UpdateMetadata("StreamTitle='Song';")
+
+	handler := NewMetaHandler(mgr)
+
+	req := httptest.NewRequest("GET", "/test_station/meta?callback=player.onMeta", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/javascript" {
+		t.Errorf("expected application/javascript, got %s", ct)
+	}
+
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, "player.onMeta({") || !strings.HasSuffix(body, "});") {
+		t.Errorf("expected wrapped JSON, got %q", body)
+	}
+
+	for _, bad := range []string{"alert(1)", "fn;evil", "1abc", "a..b"} {
+		req := httptest.NewRequest("GET", "/test_station/meta?callback="+url.QueryEscape(bad), nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("callback %q: expected 400, got %d", bad, rec.Code)
+		}
+	}
+}
+
+func TestMetaHandler_InvalidUTF8(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	mgr.Get("test_station").UpdateMetadata("StreamTitle='Caf\xe9 \xff';")
+
+	before := utf8Sanitized.Load()
+
+	req := httptest.NewRequest("GET", "/test_station/meta", nil)
+	rec := httptest.NewRecorder()
+	NewMetaHandler(mgr).ServeHTTP(rec, req)
+
+	var resp struct {
+		Current string `json:"current"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if want := "StreamTitle='Caf� �';"; resp.Current != want {
+		t.Errorf("expected %q, got %q", want, resp.Current)
+	}
+
+	if utf8Sanitized.Load() != before+1 {
+		t.Error("expected sanitization to be counted")
+	}
+}
+
+func TestStreamHandler_ShutdownEndsStream(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	handler := NewStreamHandler(mgr)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest("GET", "/test_station/stream", nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for mgr.Get("test_station").ClientCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mgr.BeginShutdown()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stream handler did not return after shutdown began")
+	}
+
+	// New streams are refused once shutdown has begun
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/test_station/stream", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 during shutdown, got %d", rec.Code)
+	}
+}
+
+func TestStreamHandler_DrainKeepsExistingStreams(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	handler := NewStreamHandler(mgr)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest("GET", "/test_station/stream", nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for mgr.Get("test_station").ClientCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mgr.BeginDrain()
+
+	// New streams are refused while draining, without ICY headers that
+	// would make a player read the error body as audio
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/test_station/stream", nil)
+	req.Header.Set("Icy-MetaData", "1")
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 while draining, got %d", rec.Code)
+	}
+	checkNoStreamHeaders(t, rec)
+
+	// The existing stream keeps playing until shutdown
+	select {
+	case <-done:
+		t.Fatal("existing stream ended when draining began")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	mgr.BeginShutdown()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stream handler did not return after shutdown began")
+	}
+}
+
+func TestHealthzHandler_Draining(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(&config.Config{})
+	mgr.BeginDrain()
+
+	rec := httptest.NewRecorder()
+	NewHealthzHandler(mgr).ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 while draining, got %d", rec.Code)
+	}
+
+	var resp struct {
+		OK          bool `json:"ok"`
+		Draining    bool `json:"draining"`
+		Connections *struct {
+			Current int `json:"current"`
+		} `json:"connections"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.OK || !resp.Draining || resp.Connections == nil {
+		t.Errorf("expected ok=false, draining=true with connections, got %+v", resp)
+	}
+}
+
+func TestStreamHandler_ContainerPassthrough(t *testing.T) {
+	cfg := singleStationConfig()
+	cfg.Stations[0].Source.ContentType = "audio/webm"
+
+	mgr, _ := manager.NewFromConfig(cfg)
+	handler := NewStreamHandler(mgr)
+
+	req := httptest.NewRequest("GET", "/test_station/stream.webm", nil)
+	req.Header.Set("Icy-MetaData", "1")
+	rec := streamFor(handler, req, 50*time.Millisecond)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "audio/webm" {
+		t.Errorf("expected audio/webm, got %q", ct)
+	}
+
+	if metaint := rec.Header().Get("icy-metaint"); metaint != "" {
+		t.Errorf("expected no ICY metadata for WebM, got metaint %q", metaint)
+	}
+}
+
+func TestFormatMetaTime(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	if got := formatMetaTime(nil, config.TimeFormatUnix); got != nil {
+		t.Errorf("expected nil for missing time, got %v", got)
+	}
+	if got := formatMetaTime(&ts, config.TimeFormatRFC3339); got != "2024-05-01T12:00:00Z" {
+		t.Errorf("rfc3339: got %v", got)
+	}
+	if got := formatMetaTime(&ts, config.TimeFormatUnix); got != ts.Unix() {
+		t.Errorf("unix: got %v", got)
+	}
+	if got := formatMetaTime(&ts, config.TimeFormatUnixMilli); got != ts.UnixMilli() {
+		t.Errorf("unixmilli: got %v", got)
+	}
+}
+
+func TestMetaHandler_UnixTimeFormat(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{MetaTimeFormat: config.TimeFormatUnix},
+		Stations: []config.StationConfig{
+			{ID: "fip", Source: config.SourceConfig{URL: "http://example.com/a.mp3"}},
+		},
+	}
+
+	mgr, err := manager.NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	mgr.Get("fip").UpdateMetadata("Artist - Title")
+
+	rec := httptest.NewRecorder()
+	NewMetaHandler(mgr).ServeHTTP(rec, httptest.NewRequest("GET", "/fip/meta", nil))
+
+	var resp map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if _, ok := resp["updated_at"].(float64); !ok {
+		t.Errorf("expected numeric updated_at, got %T", resp["updated_at"])
+	}
+}
+
+func TestMetaHandler_ConditionalGet(t *testing.T) {
+	cfg := &config.Config{
+		Stations: []config.StationConfig{
+			{ID: "fip", Source: config.SourceConfig{URL: "http://example.com/a.mp3"}},
+		},
+	}
+
+	mgr, err := manager.NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	mgr.Get("fip").UpdateMetadata("Artist - Title")
+	handler := NewMetaHandler(mgr)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/fip/meta", nil))
+	etag := rec.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("expected weak ETag, got %q", etag)
+	}
+
+	req := httptest.NewRequest("GET", "/fip/meta", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for unchanged metadata, got %d", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected empty 304 body, got %q", rec.Body.String())
+	}
+
+	mgr.Get("fip").UpdateMetadata("Other - Song")
+	req = httptest.NewRequest("GET", "/fip/meta", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 after metadata change, got %d", rec.Code)
+	}
+}
+
+func TestMetaHandler_PlainText(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	mgr.Get("test_station").UpdateMetadata("StreamTitle='Artist - Song';")
+	handler := NewMetaHandler(mgr)
+
+	accept := httptest.NewRequest("GET", "/test_station/meta", nil)
+	accept.Header.Set("Accept", "text/plain")
+
+	for name, req := range map[string]*http.Request{
+		"meta.txt": httptest.NewRequest("GET", "/test_station/meta.txt", nil),
+		"accept":   accept,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if ct := rec.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+			t.Errorf("%s: expected text/plain, got %q", name, ct)
+		}
+		if body := rec.Body.String(); body != "Artist - Song" {
+			t.Errorf("%s: expected bare title, got %q", name, body)
+		}
+	}
+
+	// Bare /meta stays JSON for ordinary clients
+	req := httptest.NewRequest("GET", "/test_station/meta", nil)
+	req.Header.Set("Accept", "*/*")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected JSON by default, got %q", ct)
+	}
+}
+
+func TestPrefersPlainText(t *testing.T) {
+	cases := map[string]bool{
+		"":                                   false,
+		"*/*":                                false,
+		"text/plain":                         true,
+		"text/plain, application/json":       true,
+		"application/json, text/plain":       false,
+		"text/plain;q=0.5, application/json": false,
+		"application/json;q=0.1, text/plain": true,
+		"text/plain;q=0":                     false,
+	}
+	for accept, want := range cases {
+		if got := prefersPlainText(accept); got != want {
+			t.Errorf("prefersPlainText(%q) = %v, want %v", accept, got, want)
+		}
+	}
+}
+
+func TestStreamHandler_HTTP2Flushes(t *testing.T) {
+	mgr := startFileStation(t, 64*1024, config.StationConfig{
+		ICY: config.ICYConfig{Name: "Local", MetaInt: 16384, BitrateHintKbps: 128},
+	})
+
+	srv := httptest.NewUnstartedServer(NewStreamHandler(mgr))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/local/stream", nil)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("expected HTTP/2, got %s", resp.Proto)
+	}
+	if c := resp.Header.Get("Connection"); c != "" {
+		t.Errorf("expected no Connection header over HTTP/2, got %q", c)
+	}
+
+	// Audio must arrive while the stream is still open, i.e. it was flushed
+	buf := make([]byte, 4096)
+	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		t.Fatalf("reading flushed audio: %v", err)
+	}
+	if buf[0] != 0xAB {
+		t.Errorf("unexpected audio byte %x", buf[0])
+	}
+}
+
+func TestStreamHandler_HTTP10RawBody(t *testing.T) {
+	mgr := startFileStation(t, 64*1024, config.StationConfig{
+		ICY: config.ICYConfig{Name: "Local", MetaInt: 16384, BitrateHintKbps: 128},
+	})
+
+	srv := httptest.NewServer(NewStreamHandler(mgr).WithConnectionClose(false))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// Legacy players send a bare HTTP/1.0 request and can't decode chunks
+	fmt.Fprint(conn, "GET /local/stream HTTP/1.0\r\nIcy-MetaData: 1\r\n\r\n")
+
+	br := bufio.NewReader(conn)
+	tp := textproto.NewReader(br)
+	status, err := tp.ReadLine()
+	if err != nil {
+		t.Fatalf("status line: %v", err)
+	}
+	if !strings.HasPrefix(status, "HTTP/1.0 200") {
+		t.Fatalf("expected HTTP/1.0 200, got %q", status)
+	}
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("headers: %v", err)
+	}
+	if te := header.Get("Transfer-Encoding"); te != "" {
+		t.Errorf("expected no Transfer-Encoding for HTTP/1.0, got %q", te)
+	}
+	if c := header.Get("Connection"); c != "close" {
+		t.Errorf("expected Connection: close for HTTP/1.0, got %q", c)
+	}
+	if header.Get("Icy-Metaint") != "16384" {
+		t.Errorf("expected icy-metaint, got %q", header.Get("Icy-Metaint"))
+	}
+
+	// The body is the raw audio, not chunk-size lines
+	buf := make([]byte, 4096)
+	if _, err := io.ReadFull(br, buf); err != nil {
+		t.Fatalf("reading audio: %v", err)
+	}
+	if !bytes.Equal(buf, bytes.Repeat([]byte{0xAB}, len(buf))) {
+		t.Errorf("expected raw audio bytes, got % x...", buf[:16])
+	}
+}
+
+// failingResponseWriter accepts limit bytes of body, then errors like a
+// dropped connection
+type failingResponseWriter struct {
+	header  http.Header
+	limit   int
+	written int
+	writes  int
+}
+
+func (f *failingResponseWriter) Header() http.Header { return f.header }
+func (f *failingResponseWriter) WriteHeader(int)     {}
+func (f *failingResponseWriter) Flush()              {}
+
+func (f *failingResponseWriter) Write(p []byte) (int, error) {
+	f.writes++
+	if f.written+len(p) > f.limit {
+		n := f.limit - f.written
+		f.written = f.limit
+		return n, io.ErrClosedPipe
+	}
+	f.written += len(p)
+	return len(p), nil
+}
+
+func TestWriteBurst_StopsOnErrorAndCancel(t *testing.T) {
+	burst := make([]byte, 10*burstSlice)
+
+	w := &failingResponseWriter{header: http.Header{}, limit: 3*burstSlice + 100}
+	if err := writeBurst(context.Background(), w, burst); err == nil {
+		t.Fatal("expected write error")
+	}
+	if w.writes != 4 {
+		t.Errorf("expected writing to stop at the failing slice, got %d writes", w.writes)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w = &failingResponseWriter{header: http.Header{}, limit: len(burst)}
+	if err := writeBurst(ctx, w, burst); err == nil || w.writes != 0 {
+		t.Errorf("expected cancelled context to stop before writing, got err=%v writes=%d", err, w.writes)
+	}
+}
+
+type countingFlusher struct{ flushes int }
+
+func (c *countingFlusher) Flush() { c.flushes++ }
+
+func TestWriteBurst_LowLatencyFlushesEachSlice(t *testing.T) {
+	burst := make([]byte, 10*burstSlice+1)
+	var buf bytes.Buffer
+	f := &countingFlusher{}
+	if err := writeBurst(context.Background(), &flushingWriter{w: &buf, flusher: f}, burst); err != nil {
+		t.Fatalf("writeBurst: %v", err)
+	}
+	if buf.Len() != len(burst) || f.flushes != 11 {
+		t.Errorf("expected %d bytes in 11 flushes, got %d bytes in %d", len(burst), buf.Len(), f.flushes)
+	}
+
+	// A failed write isn't flushed
+	f = &countingFlusher{}
+	w := &failingResponseWriter{header: http.Header{}, limit: burstSlice + 1}
+	if err := writeBurst(context.Background(), &flushingWriter{w: w, flusher: f}, burst); err == nil || f.flushes != 1 {
+		t.Errorf("expected error after 1 flush, got err=%v flushes=%d", err, f.flushes)
+	}
+}
+
+func TestStreamHandler_DisconnectDuringBurst(t *testing.T) {
+	mgr := startFileStation(t, 256*1024, config.StationConfig{
+		ICY:       config.ICYConfig{Name: "Local", MetaInt: 16384, BitrateHintKbps: 16000},
+		Buffering: config.BufferingConfig{RingBytes: 262144, BurstOnConnectBytes: 131072},
+	})
+
+	// Let the ring fill past the burst size (16 Mbit/s pacing): once a probe
+	// listener has seen that much audio, the ring holds it too
+	st := mgr.Get("local")
+	probe := &station.Client{Internal: true}
+	chunks := st.Subscribe(probe)
+	timeout := time.After(5 * time.Second)
+	for seen := 0; seen < 131072; {
+		select {
+		case chunk := <-chunks:
+			seen += len(chunk)
+		case <-timeout:
+			t.Fatalf("ring never filled, saw %d bytes", seen)
+		}
+	}
+	st.Unsubscribe(probe)
+
+	w := &failingResponseWriter{header: http.Header{}, limit: 20000}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		NewStreamHandler(mgr).ServeHTTP(w, httptest.NewRequest("GET", "/local/stream", nil))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler kept running after the client dropped mid-burst")
+	}
+
+	if w.written != 20000 {
+		t.Errorf("expected the burst to be cut at the failure, wrote %d bytes", w.written)
+	}
+	if n := st.ClientCount(); n != 0 {
+		t.Errorf("expected client to be unsubscribed, %d remain", n)
+	}
+}
+
+func TestStreamHandler_Variant(t *testing.T) {
+	cfg := singleStationConfig()
+	cfg.Stations[0].Variants = map[string]config.VariantConfig{
+		"low": {
+			Source: config.SourceConfig{URL: "http://example.com/low.mp3"},
+			ICY:    &config.ICYConfig{MetaInt: 8192, BitrateHintKbps: 64},
+		},
+	}
+	mgr, err := manager.NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	handler := NewStreamHandler(mgr)
+
+	tests := []struct {
+		query    string
+		wantCode int
+		wantBr   string
+	}{
+		{"", http.StatusOK, "128"},
+		{"?variant=low", http.StatusOK, "64"},
+		{"?variant=high", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/test_station/stream"+tt.query, nil)
+		rec := streamFor(handler, req, 50*time.Millisecond)
+		if rec.Code != tt.wantCode {
+			t.Errorf("%q: expected %d, got %d", tt.query, tt.wantCode, rec.Code)
+			continue
+		}
+		if tt.wantBr != "" && rec.Header().Get("icy-br") != tt.wantBr {
+			t.Errorf("%q: expected icy-br %s, got %s", tt.query, tt.wantBr, rec.Header().Get("icy-br"))
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	NewStationsHandler(mgr).ServeHTTP(rec, httptest.NewRequest("GET", "/stations", nil))
+	var stations []struct {
+		ID       string `json:"id"`
+		Variants []struct {
+			Name        string `json:"name"`
+			StreamURL   string `json:"stream_url"`
+			BitrateKbps int    `json:"bitrateKbps"`
+		} `json:"variants"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&stations); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(stations) != 1 || len(stations[0].Variants) != 1 {
+		t.Fatalf("expected one station with one variant, got %+v", stations)
+	}
+	v := stations[0].Variants[0]
+	if v.Name != "low" || v.StreamURL != "/test_station/stream?variant=low" || v.BitrateKbps != 64 {
+		t.Errorf("unexpected variant %+v", v)
+	}
+}
+
+func TestStreamHandler_StationRemovedMidStream(t *testing.T) {
+	toneConfig := func(ids ...string) *config.Config {
+		cfg := &config.Config{}
+		for _, id := range ids {
+			cfg.Stations = append(cfg.Stations, config.StationConfig{
+				ID:     id,
+				ICY:    config.ICYConfig{MetaInt: 8192, BitrateHintKbps: 32},
+				Source: config.SourceConfig{Type: "tone"},
+			})
+		}
+		return cfg
+	}
+
+	mgr, err := manager.NewFromConfig(toneConfig("gone", "kept"))
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	mgr.Start()
+	defer mgr.Shutdown()
+	handler := NewStreamHandler(mgr)
+
+	// Listeners that never hang up on their own
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	const listeners = 3
+	var wg sync.WaitGroup
+	for i := 0; i < listeners; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("GET", "/gone/stream", nil).WithContext(ctx)
+			req.Header.Set("Icy-MetaData", "1")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		}()
+	}
+
+	st := mgr.Get("gone")
+	deadline := time.Now().Add(2 * time.Second)
+	for st.ClientCount() < listeners {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d subscribers, got %d", listeners, st.ClientCount())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := mgr.Reload(toneConfig("kept")); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream handlers still running after their station was removed")
+	}
+
+	if active, _ := mgr.Connections(); active != 0 {
+		t.Errorf("expected connection slots released, got %d active", active)
+	}
+	if n := st.ClientCount(); n != 0 {
+		t.Errorf("expected removed station to have no clients, got %d", n)
+	}
+}
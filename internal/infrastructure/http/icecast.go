@@ -0,0 +1,58 @@
+// ABOUTME: Icecast-style listmounts XML for directory aggregators
+// ABOUTME: Lists every station as a mount with listeners and current song
+package http
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+)
+
+// ListMountsHandler serves all stations in the shape of Icecast's
+// /admin/listmounts response, extended with the per-mount fields from
+// Icecast's stats XML that aggregators read.
+type ListMountsHandler struct {
+	mgr *manager.Manager
+}
+
+func NewListMountsHandler(mgr *manager.Manager) *ListMountsHandler {
+	return &ListMountsHandler{mgr: mgr}
+}
+
+func (h *ListMountsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	type source struct {
+		Mount        string `xml:"mount,attr"`
+		ServerName   string `xml:"server_name"`
+		ServerType   string `xml:"server_type"`
+		Title        string `xml:"title"`
+		Bitrate      int    `xml:"bitrate"`
+		Listeners    int    `xml:"listeners"`
+		ListenerPeak int    `xml:"listener_peak"`
+		ContentType  string `xml:"content-type"`
+	}
+	type icestats struct {
+		XMLName xml.Name `xml:"icestats"`
+		Sources []source `xml:"source"`
+	}
+
+	base := h.mgr.Config().Server.BasePath
+	resp := icestats{Sources: make([]source, 0)}
+	for _, st := range h.mgr.List() {
+		resp.Sources = append(resp.Sources, source{
+			Mount:        fmt.Sprintf("%s/%s/stream", base, st.ID()),
+			ServerName:   st.ICYName(),
+			ServerType:   st.ContentType(),
+			Title:        streamTitle(st),
+			Bitrate:      st.Bitrate(),
+			Listeners:    st.ClientCount(),
+			ListenerPeak: st.PeakClientCount(),
+			ContentType:  st.ContentType(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprint(w, xml.Header)
+	xml.NewEncoder(w).Encode(resp)
+}
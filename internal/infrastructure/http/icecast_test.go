@@ -0,0 +1,49 @@
+// ABOUTME: Tests for the Icecast-style listmounts endpoint
+// ABOUTME: Verifies the XML document lists each station as a mount
+package http
+
+import (
+	"encoding/xml"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+	"github.com/harper/radio-metadata-proxy/internal/domain/station"
+)
+
+func TestListMountsHandler(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	st := mgr.Get("test_station")
+	st.UpdateMetadata("StreamTitle='Artist - Song';")
+	st.Subscribe(&station.Client{ID: "a"})
+
+	rec := httptest.NewRecorder()
+	NewListMountsHandler(mgr).ServeHTTP(rec, httptest.NewRequest("GET", "/listmounts", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/xml" {
+		t.Errorf("expected Content-Type text/xml, got %s", ct)
+	}
+
+	var stats struct {
+		Sources []struct {
+			Mount      string `xml:"mount,attr"`
+			ServerName string `xml:"server_name"`
+			Title      string `xml:"title"`
+			Bitrate    int    `xml:"bitrate"`
+			Listeners  int    `xml:"listeners"`
+		} `xml:"source"`
+	}
+	if err := xml.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if len(stats.Sources) != 1 {
+		t.Fatalf("expected 1 mount, got %d", len(stats.Sources))
+	}
+
+	src := stats.Sources[0]
+	if src.Mount != "/test_station/stream" || src.ServerName != "Test Station" ||
+		src.Title != "Artist - Song" || src.Bitrate != 128 || src.Listeners != 1 {
+		t.Errorf("unexpected mount %+v", src)
+	}
+}
@@ -0,0 +1,52 @@
+// ABOUTME: Audio level readout for VU-meter style players
+// ABOUTME: Serves the station's latest RMS/peak estimate as polling JSON
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+)
+
+type LevelsHandler struct {
+	mgr *manager.Manager
+}
+
+func NewLevelsHandler(mgr *manager.Manager) *LevelsHandler {
+	return &LevelsHandler{mgr: mgr}
+}
+
+func (h *LevelsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	stationID, _, _, ok := splitStationPath(r.URL.Path)
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+
+	st := h.mgr.Get(stationID)
+	if st == nil {
+		writeStationMissing(w, h.mgr, stationID)
+		return
+	}
+	if !st.LevelsEnabled() {
+		writeError(w, http.StatusNotFound, codeNotFound, fmt.Sprintf("levels are not enabled for station %q", st.ID()))
+		return
+	}
+
+	type response struct {
+		RMS       *float64    `json:"rms"`  // dBFS, null until the first window completes
+		Peak      *float64    `json:"peak"` // dBFS
+		WindowMs  int64       `json:"windowMs"`
+		UpdatedAt interface{} `json:"updated_at,omitempty"`
+	}
+
+	lvl, window, measured := st.Levels()
+	resp := response{WindowMs: window.Milliseconds()}
+	if measured {
+		resp.RMS, resp.Peak = &lvl.RMS, &lvl.Peak
+		resp.UpdatedAt = formatMetaTime(&lvl.At, h.mgr.Config().Server.MetaTimeFormat)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
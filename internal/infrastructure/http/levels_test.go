@@ -0,0 +1,47 @@
+// ABOUTME: Tests for the audio level readout endpoint
+// ABOUTME: Verifies 404 when disabled and null levels before measurement
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/config"
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+)
+
+func TestLevelsHandler(t *testing.T) {
+	cfg := singleStationConfig()
+	cfg.Stations = append(cfg.Stations, config.StationConfig{
+		ID:     "metered",
+		Source: config.SourceConfig{URL: "http://example.com/a.mp3"},
+		Levels: config.LevelsConfig{Enabled: true},
+	})
+	mgr, err := manager.NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	h := NewLevelsHandler(mgr)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/test_station/levels", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 with levels disabled, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/metered/levels", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var resp map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp["rms"] != nil || resp["windowMs"] != float64(250) {
+		t.Errorf("expected null rms and 250ms window before audio, got %v", resp)
+	}
+}
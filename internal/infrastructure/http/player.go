@@ -0,0 +1,86 @@
+// ABOUTME: Built-in HTML player page served at the bare /{station}/ path
+// ABOUTME: Self-contained page with an audio element and a polled now-playing title
+package http
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+)
+
+// playerPage uses URLs relative to /{station}/ so it works under any base path
+var playerPage = template.Must(template.New("player").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Name}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 32rem; margin: 3rem auto; padding: 0 1rem; color: #222; }
+h1 { font-size: 1.4rem; margin-bottom: 0.25rem; }
+#title { color: #555; min-height: 1.5em; margin-bottom: 1rem; }
+audio { width: 100%; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<div id="title">{{.Title}}</div>
+<audio controls preload="none" src="stream"><source src="stream" type="{{.ContentType}}"></audio>
+<script>
+(function () {
+  var el = document.getElementById("title");
+  function refresh() {
+    fetch("meta.txt", { cache: "no-store" })
+      .then(function (r) { return r.ok ? r.text() : null; })
+      .then(function (t) { if (t !== null) { el.textContent = t; } })
+      .catch(function () {});
+  }
+  setInterval(refresh, 10000);
+})();
+</script>
+</body>
+</html>
+`))
+
+// PlayerHandler serves a minimal listening page for a station, for sharing
+// a link that non-technical listeners can just open
+type PlayerHandler struct {
+	mgr *manager.Manager
+}
+
+func NewPlayerHandler(mgr *manager.Manager) *PlayerHandler {
+	return &PlayerHandler{mgr: mgr}
+}
+
+func (h *PlayerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	stationID := strings.Trim(r.URL.Path, "/")
+	if stationID == "" || strings.Contains(stationID, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	st := h.mgr.Get(stationID)
+	if st == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	name := st.ICYName()
+	if name == "" {
+		name = st.ID()
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	playerPage.Execute(w, struct {
+		Name        string
+		Title       string
+		ContentType string
+	}{
+		Name:        name,
+		Title:       displayTitle(st),
+		ContentType: st.ContentType(),
+	})
+}
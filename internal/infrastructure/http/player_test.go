@@ -0,0 +1,66 @@
+// ABOUTME: Tests for the built-in HTML player page
+// ABOUTME: Verifies routing of bare station paths and the rendered page
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+)
+
+func TestPlayerHandler(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	mgr.Get("test_station").UpdateMetadata("StreamTitle='Artist - <Song>';")
+	router := NewStationRouter(map[string]http.Handler{"meta": NewMetaHandler(mgr)}).WithIndex(NewPlayerHandler(mgr))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/test_station/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("expected HTML, got %q", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"<title>Test Station</title>", `src="stream"`, `type="audio/mpeg"`, "Artist - &lt;Song&gt;", `fetch("meta.txt"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected page to contain %q", want)
+		}
+	}
+
+	for path, want := range map[string]int{
+		"/nope/":             http.StatusNotFound,
+		"/test_station/meta": http.StatusOK,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", path, want, rec.Code)
+		}
+	}
+
+	// Without WithIndex the bare path stays a 404
+	rec = httptest.NewRecorder()
+	NewStationRouter(nil).ServeHTTP(rec, httptest.NewRequest("GET", "/test_station/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 with the player disabled, got %d", rec.Code)
+	}
+}
+
+func TestPlayerHandler_DefaultStationEndpoints(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	router := NewStationRouter(map[string]http.Handler{"meta": NewMetaHandler(mgr)}).
+		WithDefaultStation("test_station").
+		WithIndex(NewPlayerHandler(mgr))
+
+	// /meta/ is the default station's endpoint, not a station called "meta"
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/meta/", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected /meta/ to reach the meta endpoint, got %d %q", rec.Code, ct)
+	}
+}
@@ -0,0 +1,198 @@
+// ABOUTME: Path routing for per-station endpoints
+// ABOUTME: Splits /{station}/{endpoint} paths and dispatches to station handlers
+package http
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// audioExtensions maps stream URL extensions to the content types they imply.
+// Some players sniff the extension rather than trusting Content-Type.
+var audioExtensions = map[string][]string{
+	".mp3":  {"audio/mpeg"},
+	".aac":  {"audio/aac", "audio/aacp"},
+	".ogg":  {"audio/ogg"},
+	".webm": {"audio/webm"},
+}
+
+// splitStationPath splits /{station}/{endpoint}[.ext] into its parts. Only
+// known audio extensions are stripped into ext. Duplicate and trailing
+// slashes are ignored.
+func splitStationPath(p string) (stationID, endpoint, ext string, ok bool) {
+	parts := strings.FieldsFunc(p, func(r rune) bool { return r == '/' })
+	if len(parts) != 2 {
+		return "", "", "", false
+	}
+
+	endpoint = parts[1]
+	if e := path.Ext(endpoint); audioExtensions[e] != nil {
+		ext = e
+		endpoint = strings.TrimSuffix(endpoint, e)
+	}
+
+	return parts[0], endpoint, ext, true
+}
+
+// StationRouter dispatches /{station}/{endpoint} requests by endpoint name.
+type StationRouter struct {
+	routes         map[string]http.Handler
+	defaultStation string
+	index          http.Handler
+}
+
+func NewStationRouter(routes map[string]http.Handler) *StationRouter {
+	return &StationRouter{routes: routes}
+}
+
+// WithDefaultStation routes bare /{endpoint} paths (e.g. /stream, /meta) to
+// the given station. Without it those paths 404.
+func (rt *StationRouter) WithDefaultStation(id string) *StationRouter {
+	rt.defaultStation = id
+	return rt
+}
+
+// WithIndex serves bare /{station}/ paths (with the trailing slash) with h
+func (rt *StationRouter) WithIndex(h http.Handler) *StationRouter {
+	rt.index = h
+	return rt
+}
+
+func (rt *StationRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if id, ok := stationIndex(r.URL.Path); ok && rt.index != nil {
+		// With a default station, /meta/ still means its endpoint
+		if _, endpoint := rt.routes[id]; rt.defaultStation == "" || !endpoint {
+			rt.index.ServeHTTP(w, r)
+			return
+		}
+	}
+
+	if rt.defaultStation != "" {
+		r = rt.withDefaultStation(r)
+	}
+
+	_, endpoint, ext, ok := splitStationPath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Audio extensions only make sense on the stream itself
+	h, found := rt.routes[endpoint]
+	if !found || (ext != "" && endpoint != "stream") {
+		http.NotFound(w, r)
+		return
+	}
+
+	h.ServeHTTP(w, r)
+}
+
+// stationIndex returns the station ID of a bare /{station}/ path
+func stationIndex(p string) (string, bool) {
+	parts := strings.FieldsFunc(p, func(c rune) bool { return c == '/' })
+	if len(parts) != 1 || !strings.HasSuffix(p, "/") {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// withDefaultStation rewrites a single-segment path to the default station
+func (rt *StationRouter) withDefaultStation(r *http.Request) *http.Request {
+	parts := strings.FieldsFunc(r.URL.Path, func(c rune) bool { return c == '/' })
+	if len(parts) != 1 {
+		return r
+	}
+
+	r2 := new(http.Request)
+	*r2 = *r
+	u := *r.URL
+	u.Path = "/" + rt.defaultStation + "/" + parts[0]
+	u.RawPath = ""
+	r2.URL = &u
+	return r2
+}
+
+// WithCleanPath collapses duplicate slashes and resolves . and .. segments
+// before h sees the path. ServeMux answers such paths with a redirect to the
+// cleaned path it was given, which drops any stripped base path, before the
+// station router could normalize them itself.
+func WithCleanPath(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p := cleanPath(r.URL.Path); p != r.URL.Path {
+			r2 := new(http.Request)
+			*r2 = *r
+			u := *r.URL
+			u.Path = p
+			u.RawPath = ""
+			r2.URL = &u
+			r = r2
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// cleanPath is path.Clean on a rooted path, keeping a trailing slash since
+// it marks a station index
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	np := path.Clean(p)
+	if p[len(p)-1] == '/' && np != "/" {
+		np += "/"
+	}
+	return np
+}
+
+// WithBasePath mounts h under base (e.g. "/radio"), stripping the prefix so
+// handlers and path parsing see root-relative paths. Requests outside the
+// prefix, including ones that merely share its characters such as
+// /radiofoo under /radio, get a 404. Redirects to root-relative paths, such
+// as ServeMux's /admin/stations to /admin/stations/, get the prefix back.
+func WithBasePath(base string, h http.Handler) http.Handler {
+	base = strings.TrimSuffix(base, "/")
+	if base == "" {
+		return h
+	}
+	strip := http.StripPrefix(base, h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, base)
+		if !ok || (rest != "" && rest[0] != '/') {
+			http.NotFound(w, r)
+			return
+		}
+		strip.ServeHTTP(&basePathWriter{ResponseWriter: w, base: base}, r)
+	})
+}
+
+// basePathWriter prefixes root-relative redirect Locations with base
+type basePathWriter struct {
+	http.ResponseWriter
+	base string
+}
+
+func (b *basePathWriter) WriteHeader(status int) {
+	if status >= 300 && status < 400 {
+		h := b.Header()
+		// "//host/..." is protocol-relative, not a path
+		if loc := h.Get("Location"); strings.HasPrefix(loc, "/") && !strings.HasPrefix(loc, "//") {
+			h.Set("Location", b.base+loc)
+		}
+	}
+	b.ResponseWriter.WriteHeader(status)
+}
+
+func (b *basePathWriter) Flush() {
+	if f, ok := b.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (b *basePathWriter) Unwrap() http.ResponseWriter {
+	return b.ResponseWriter
+}
@@ -0,0 +1,199 @@
+// ABOUTME: Tests for per-station path routing
+// ABOUTME: Verifies endpoint dispatch and audio extension aliases
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+)
+
+func TestSplitStationPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		id       string
+		endpoint string
+		ext      string
+		ok       bool
+	}{
+		{"/fip/stream", "fip", "stream", "", true},
+		{"/fip/stream.mp3", "fip", "stream", ".mp3", true},
+		{"/fip/stream.ogg", "fip", "stream", ".ogg", true},
+		{"/fip/7.html", "fip", "7.html", "", true},
+		{"/fip", "", "", "", false},
+		{"/a/b/c", "", "", "", false},
+	}
+
+	for _, tt := range tests {
+		id, endpoint, ext, ok := splitStationPath(tt.path)
+		if id != tt.id || endpoint != tt.endpoint || ext != tt.ext || ok != tt.ok {
+			t.Errorf("%s: got (%q, %q, %q, %v)", tt.path, id, endpoint, ext, ok)
+		}
+	}
+}
+
+func TestStationRouter_StreamExtensions(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+
+	router := NewStationRouter(map[string]http.Handler{
+		"stream": NewStreamHandler(mgr),
+		"meta":   NewMetaHandler(mgr),
+	})
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/test_station/stream.mp3", http.StatusOK},
+		{"/test_station/stream.aac", http.StatusNotFound}, // station is MP3
+		{"/test_station/meta.mp3", http.StatusNotFound},
+		{"/test_station/meta", http.StatusOK},
+		{"/test_station/unknown", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		rec := streamFor(router, req, 50*time.Millisecond)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.want, rec.Code)
+		}
+
+		if tt.want == http.StatusOK && tt.path == "/test_station/stream.mp3" {
+			if ct := rec.Header().Get("Content-Type"); ct != "audio/mpeg" {
+				t.Errorf("expected audio/mpeg, got %s", ct)
+			}
+		}
+	}
+}
+
+func TestStationRouter_AACExtensionCoversAACPlus(t *testing.T) {
+	for _, contentType := range []string{"audio/aac", "audio/aacp"} {
+		cfg := singleStationConfig()
+		cfg.Stations[0].Source.ContentType = contentType
+		mgr, err := manager.NewFromConfig(cfg)
+		if err != nil {
+			t.Fatalf("NewFromConfig: %v", err)
+		}
+		router := NewStationRouter(map[string]http.Handler{"stream": NewStreamHandler(mgr)})
+
+		rec := streamFor(router, httptest.NewRequest("GET", "/test_station/stream.aac", nil), 50*time.Millisecond)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected stream.aac served, got %d", contentType, rec.Code)
+		}
+		rec = streamFor(router, httptest.NewRequest("GET", "/test_station/stream.mp3", nil), 50*time.Millisecond)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected stream.mp3 refused, got %d", contentType, rec.Code)
+		}
+	}
+}
+
+func TestWithBasePath(t *testing.T) {
+	cfg := singleStationConfig()
+	cfg.Server.BasePath = "/radio/"
+
+	mgr, _ := manager.NewFromConfig(cfg)
+
+	mux := http.NewServeMux()
+	mux.Handle("/stations", NewStationsHandler(mgr))
+	mux.Handle("/", NewStationRouter(map[string]http.Handler{
+		"stream": NewStreamHandler(mgr),
+		"meta":   NewMetaHandler(mgr),
+	}))
+	handler := WithBasePath(mgr.Config().Server.BasePath, mux)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/radio/stations", http.StatusOK},
+		{"/radio/test_station/meta", http.StatusOK},
+		{"/radio/test_station/stream", http.StatusOK},
+		{"/stations", http.StatusNotFound},
+		{"/test_station/meta", http.StatusNotFound},
+		{"/radiotest_station/meta", http.StatusNotFound},
+		{"/radiofoo", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		rec := streamFor(handler, req, 50*time.Millisecond)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.want, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/radio/stations", nil))
+
+	var stations []struct {
+		StreamURL string `json:"stream_url"`
+		MetaURL   string `json:"meta_url"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&stations); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if len(stations) != 1 || stations[0].StreamURL != "/radio/test_station/stream" || stations[0].MetaURL != "/radio/test_station/meta" {
+		t.Errorf("expected prefixed URLs, got %+v", stations)
+	}
+}
+
+func TestStationRouter_PathNormalization(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	router := NewStationRouter(map[string]http.Handler{
+		"meta": NewMetaHandler(mgr),
+	})
+
+	for _, p := range []string{
+		"/test_station/meta/",
+		"/test_station//meta",
+		"//test_station/meta",
+		"/Test_Station/meta",
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", p, nil))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", p, rec.Code)
+		}
+	}
+}
+
+func TestStationRouter_DefaultStation(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	routes := map[string]http.Handler{
+		"stream": NewStreamHandler(mgr),
+		"meta":   NewMetaHandler(mgr),
+	}
+
+	tests := []struct {
+		name      string
+		defaultID string
+		path      string
+		want      int
+	}{
+		{"bare meta", "test_station", "/meta", http.StatusOK},
+		{"bare stream alias", "test_station", "/stream.mp3", http.StatusOK},
+		{"explicit station still works", "test_station", "/test_station/meta", http.StatusOK},
+		{"bare path without default", "", "/meta", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewStationRouter(routes).WithDefaultStation(tt.defaultID)
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			rec := streamFor(router, req, 50*time.Millisecond)
+
+			if rec.Code != tt.want {
+				t.Errorf("%s: expected %d, got %d", tt.path, tt.want, rec.Code)
+			}
+		})
+	}
+}
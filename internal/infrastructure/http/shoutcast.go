@@ -0,0 +1,116 @@
+// ABOUTME: Shoutcast-compatible legacy status endpoints
+// ABOUTME: Serves /{station}/7.html CSV and /{station}/admin.cgi?mode=viewxml stats
+package http
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+	"github.com/harper/radio-metadata-proxy/internal/domain/station"
+)
+
+// ShoutcastHandler serves the legacy Shoutcast status formats scraped by
+// directory services and monitoring dashboards.
+type ShoutcastHandler struct {
+	mgr *manager.Manager
+}
+
+func NewShoutcastHandler(mgr *manager.Manager) *ShoutcastHandler {
+	return &ShoutcastHandler{mgr: mgr}
+}
+
+func (h *ShoutcastHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	stationID, endpoint, ext, ok := splitStationPath(r.URL.Path)
+	if !ok || ext != "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	st := h.mgr.Get(stationID)
+	if st == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case endpoint == "7.html":
+		h.serve7(w, st)
+	case endpoint == "admin.cgi" && r.URL.Query().Get("mode") == "viewxml":
+		h.serveXML(w, st)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serve7 writes currentlisteners,streamstatus,peaklisteners,maxlisteners,
+// uniquelisteners,bitrate,songtitle wrapped in the classic HTML body.
+func (h *ShoutcastHandler) serve7(w http.ResponseWriter, st *station.Station) {
+	status := 0
+	if st.SourceHealthy() {
+		status = 1
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprintf(w, "<html><body>%d,%d,%d,%d,%d,%d,%s</body></html>",
+		st.ClientCount(),
+		status,
+		st.PeakClientCount(),
+		h.maxListeners(),
+		st.UniqueClientCount(),
+		st.Bitrate(),
+		html.EscapeString(streamTitle(st)),
+	)
+}
+
+func (h *ShoutcastHandler) serveXML(w http.ResponseWriter, st *station.Station) {
+	type shoutcastServer struct {
+		XMLName           xml.Name `xml:"SHOUTCASTSERVER"`
+		CurrentListeners  int      `xml:"CURRENTLISTENERS"`
+		PeakListeners     int      `xml:"PEAKLISTENERS"`
+		MaxListeners      int      `xml:"MAXLISTENERS"`
+		UniqueListeners   int      `xml:"UNIQUELISTENERS"`
+		ReportedListeners int      `xml:"REPORTEDLISTENERS"`
+		ServerTitle       string   `xml:"SERVERTITLE"`
+		SongTitle         string   `xml:"SONGTITLE"`
+		StreamStatus      int      `xml:"STREAMSTATUS"`
+		Bitrate           int      `xml:"BITRATE"`
+		Content           string   `xml:"CONTENT"`
+	}
+
+	status := 0
+	if st.SourceHealthy() {
+		status = 1
+	}
+
+	resp := shoutcastServer{
+		CurrentListeners:  st.ClientCount(),
+		PeakListeners:     st.PeakClientCount(),
+		MaxListeners:      h.maxListeners(),
+		UniqueListeners:   st.UniqueClientCount(),
+		ReportedListeners: st.ClientCount(),
+		ServerTitle:       st.ICYName(),
+		SongTitle:         streamTitle(st),
+		StreamStatus:      status,
+		Bitrate:           st.Bitrate(),
+		Content:           st.ContentType(),
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprint(w, xml.Header)
+	xml.NewEncoder(w).Encode(resp)
+}
+
+// maxListeners reports server.max_connections, the only listener cap; it is
+// shared by every station, and 0 means unlimited as in Shoutcast
+func (h *ShoutcastHandler) maxListeners() int {
+	_, max := h.mgr.Connections()
+	return max
+}
+
+// streamTitle returns the StreamTitle value of the station's current metadata
+func streamTitle(st *station.Station) string {
+	return extractKV(st.CurrentMetadata(), "StreamTitle")
+}
@@ -0,0 +1,70 @@
+// ABOUTME: Tests for Shoutcast-compatible status endpoints
+// ABOUTME: Verifies 7.html CSV layout and viewxml document
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/harper/radio-metadata-proxy/internal/application/manager"
+	"github.com/harper/radio-metadata-proxy/internal/domain/station"
+)
+
+func TestShoutcastHandler_7HTML(t *testing.T) {
+	mgr, _ := manager.NewFromConfig(singleStationConfig())
+	st := mgr.Get("test_station")
+	st.UpdateMetadata("StreamTitle='Artist - Song';")
+	st.SetSourceHealthy(true)
+	st.Subscribe(&station.Client{ID: "a", Addr: "10.0.0.1:1000"})
+	st.Subscribe(&station.Client{ID: "b", Addr: "10.0.0.1:1001"})
+
+	handler := NewShoutcastHandler(mgr)
+
+	req := httptest.NewRequest("GET", "/test_station/7.html", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	want := "<html><body>2,1,2,0,1,128,Artist - Song</body></html>"
+	if rec.Body.String() != want {
+		t.Errorf("expected %q, got %q", want, rec.Body.String())
+	}
+}
+
+func TestShoutcastHandler_ViewXML(t *testing.T) {
+	cfg := singleStationConfig()
+	cfg.Server.MaxConnections = 500
+	cfg.Stations[0].Source.ContentType = "audio/aac"
+	mgr, _ := manager.NewFromConfig(cfg)
+	mgr.Get("test_station").UpdateMetadata("StreamTitle='Artist - Song';")
+
+	handler := NewShoutcastHandler(mgr)
+
+	req := httptest.NewRequest("GET", "/test_station/admin.cgi?mode=viewxml", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/xml" {
+		t.Errorf("expected Content-Type text/xml, got %s", ct)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{"<SHOUTCASTSERVER>", "<SONGTITLE>Artist - Song</SONGTITLE>", "<BITRATE>128</BITRATE>", "<MAXLISTENERS>500</MAXLISTENERS>", "<CONTENT>audio/aac</CONTENT>"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected body to contain %q, got %s", want, body)
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/test_station/admin.cgi?mode=other", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unsupported mode, got %d", rec.Code)
+	}
+}
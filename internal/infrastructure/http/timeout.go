@@ -0,0 +1,29 @@
+// ABOUTME: Per-route connection deadlines for non-streaming endpoints
+// ABOUTME: JSON and metadata requests get a strict deadline while streams stay unbounded
+package http
+
+import (
+	"net/http"
+	"time"
+)
+
+// WithRequestTimeout bounds how long a non-stream request may spend reading
+// its body and writing its response, so slow clients can't hold connections
+// open. The server runs without a write timeout for the sake of streams, so
+// deadlines are set per request through http.ResponseController; net/http
+// clears them once the response is done, so a stream that follows on the same
+// keep-alive connection stays unbounded.
+func WithRequestTimeout(d time.Duration, next http.Handler) http.Handler {
+	if d <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isStreamPath(r.URL.Path) {
+			deadline := time.Now().Add(d)
+			rc := http.NewResponseController(w)
+			rc.SetReadDeadline(deadline)
+			rc.SetWriteDeadline(deadline)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
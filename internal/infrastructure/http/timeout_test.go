@@ -0,0 +1,92 @@
+// ABOUTME: Tests for per-route request deadlines
+// ABOUTME: Verifies slow non-stream requests time out while streams are unaffected
+package http
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithRequestTimeout_SlowBodyTimesOut(t *testing.T) {
+	readErr := make(chan error, 1)
+	srv := httptest.NewServer(WithRequestTimeout(50*time.Millisecond, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		readErr <- err
+	})))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	// Promise a body and never finish sending it
+	fmt.Fprintf(conn, "POST /admin/config HTTP/1.1\r\nHost: x\r\nContent-Length: 100\r\n\r\npartial")
+
+	select {
+	case err := <-readErr:
+		if err == nil {
+			t.Fatal("expected body read to fail at the deadline")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("slow request was not timed out")
+	}
+}
+
+func TestWithRequestTimeout_StreamUnbounded(t *testing.T) {
+	srv := httptest.NewServer(WithRequestTimeout(50*time.Millisecond, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/stream") {
+			w.(http.Flusher).Flush()
+			time.Sleep(150 * time.Millisecond)
+		}
+		io.WriteString(w, "ok")
+	})))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	// The stream reuses the keep-alive connection of a metadata request, so
+	// the earlier deadline must not carry over
+	for _, p := range []string{"/test/meta", "/test/stream"} {
+		fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: x\r\n\r\n", p)
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", p, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || string(body) != "ok" {
+			t.Fatalf("%s: body %q, err %v", p, body, err)
+		}
+	}
+}
+
+func TestIsStreamPath(t *testing.T) {
+	cases := map[string]bool{
+		"/jazz/stream":     true,
+		"/jazz/stream.mp3": true,
+		"/stream":          true,
+		"/stream.ogg":      true,
+		"/jazz/meta":       false,
+		"/stations":        false,
+		"/metrics":         false,
+	}
+	for p, want := range cases {
+		if got := isStreamPath(p); got != want {
+			t.Errorf("isStreamPath(%q) = %v, want %v", p, got, want)
+		}
+	}
+}
@@ -2,7 +2,10 @@
 // ABOUTME: Handles 16-byte padding and length byte calculation per ICY spec
 package icy
 
-import "bytes"
+import (
+	"bytes"
+	"strings"
+)
 
 // BuildBlock encodes text as ICY metadata block with 16-byte padding.
 // Returns length byte (count of 16-byte chunks) followed by padded payload.
@@ -38,3 +41,39 @@ func BuildBlock(text string) []byte {
 
 	return buf.Bytes()
 }
+
+// StreamTitle formats title as a StreamTitle ICY string. Single quotes are
+// removed since ICY has no escaping and they would terminate the value.
+func StreamTitle(title string) string {
+	return "StreamTitle='" + strings.ReplaceAll(title, "'", "") + "';"
+}
+
+// Field is an extra Name='value'; pair carried after StreamTitle
+type Field struct {
+	Name  string
+	Value string
+}
+
+// WithFields appends fields to meta, which should begin with StreamTitle so
+// players that only look at the first field keep working. Quotes are removed
+// from values, empty values are skipped, and fields that would push the
+// block past its 4080-byte limit are dropped rather than truncated.
+func WithFields(meta string, fields []Field) string {
+	var b strings.Builder
+	b.WriteString(meta)
+	if meta != "" && !strings.HasSuffix(meta, ";") {
+		b.WriteByte(';')
+	}
+	for _, f := range fields {
+		value := strings.ReplaceAll(f.Value, "'", "")
+		if f.Name == "" || value == "" {
+			continue
+		}
+		field := f.Name + "='" + value + "';"
+		if b.Len()+len(field) > 255*16 {
+			break
+		}
+		b.WriteString(field)
+	}
+	return b.String()
+}
@@ -3,6 +3,7 @@
 package icy
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -56,3 +57,40 @@ func TestBuildBlock_Truncation(t *testing.T) {
 		t.Errorf("expected %d bytes, got %d", expected, len(result))
 	}
 }
+
+func TestStreamTitle(t *testing.T) {
+	if got := StreamTitle("Rock 'n' Roll"); got != "StreamTitle='Rock n Roll';" {
+		t.Errorf("unexpected StreamTitle %q", got)
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		meta   string
+		fields []Field
+		want   string
+	}{
+		{"none", "StreamTitle='A';", nil, "StreamTitle='A';"},
+		{"appended", "StreamTitle='A';", []Field{{"StationName", "KEXP"}, {"Studio", "Seattle"}},
+			"StreamTitle='A';StationName='KEXP';Studio='Seattle';"},
+		{"quotes stripped", "StreamTitle='A';", []Field{{"Host", "D'Angelo"}}, "StreamTitle='A';Host='DAngelo';"},
+		{"empty skipped", "StreamTitle='A';", []Field{{"Host", ""}, {"", "x"}}, "StreamTitle='A';"},
+		{"missing semicolon", "StreamTitle='A'", []Field{{"S", "1"}}, "StreamTitle='A';S='1';"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WithFields(tt.meta, tt.fields); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithFields_DropsOversized(t *testing.T) {
+	meta := StreamTitle(strings.Repeat("x", 4000))
+	got := WithFields(meta, []Field{{"StationName", strings.Repeat("y", 100)}})
+	if got != meta {
+		t.Errorf("oversized field should be dropped, got %d bytes", len(got))
+	}
+}
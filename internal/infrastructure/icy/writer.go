@@ -0,0 +1,64 @@
+// ABOUTME: Stream writer that interleaves ICY metadata blocks every metaint bytes
+// ABOUTME: Counts from the first body byte so any pre-roll audio stays aligned
+package icy
+
+import "io"
+
+// Writer injects a metadata block after every metaInt audio bytes written
+// through it. ICY clients count metaint from the first byte of the body, so
+// every audio byte sent to a client (pre-roll or live) must pass through
+// the same Writer. Apart from WriteInitialBlock, a block never precedes the
+// first metaInt bytes.
+type Writer struct {
+	w         io.Writer
+	metaInt   int
+	remaining int
+	block     func() []byte
+}
+
+// NewWriter returns a Writer that calls block for the metadata to inject.
+// A metaInt of 0 disables injection.
+func NewWriter(w io.Writer, metaInt int, block func() []byte) *Writer {
+	return &Writer{w: w, metaInt: metaInt, remaining: metaInt, block: block}
+}
+
+// WriteInitialBlock sends the current metadata before any audio so players
+// show a title at once, then restarts the metaint count. Strict ICY parsers
+// would read the block as audio, but common players accept it.
+func (iw *Writer) WriteInitialBlock() error {
+	if iw.metaInt <= 0 {
+		return nil
+	}
+	if _, err := iw.w.Write(iw.block()); err != nil {
+		return err
+	}
+	iw.remaining = iw.metaInt
+	return nil
+}
+
+// Write writes audio p, reporting only audio bytes in n
+func (iw *Writer) Write(p []byte) (n int, err error) {
+	if iw.metaInt <= 0 {
+		return iw.w.Write(p)
+	}
+
+	for len(p) > 0 {
+		toWrite := min(len(p), iw.remaining)
+		written, err := iw.w.Write(p[:toWrite])
+		n += written
+		iw.remaining -= written
+		if err != nil {
+			return n, err
+		}
+		p = p[written:]
+
+		if iw.remaining == 0 {
+			if _, err := iw.w.Write(iw.block()); err != nil {
+				return n, err
+			}
+			iw.remaining = iw.metaInt
+		}
+	}
+
+	return n, nil
+}
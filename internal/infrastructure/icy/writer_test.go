@@ -0,0 +1,104 @@
+// ABOUTME: Tests for the ICY metadata-interleaving writer
+// ABOUTME: Verifies block alignment across pre-roll bursts and live chunks
+package icy
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestWriter_BurstThenLiveAlignment(t *testing.T) {
+	const metaInt = 16
+	block := BuildBlock("StreamTitle='Song';")
+
+	var out bytes.Buffer
+	w := NewWriter(&out, metaInt, func() []byte { return block })
+
+	// A 40-byte burst followed by uneven live chunks
+	audio := bytes.Repeat([]byte{0xAA}, 40)
+	live := [][]byte{bytes.Repeat([]byte{0xBB}, 5), bytes.Repeat([]byte{0xCC}, 19)}
+
+	if n, err := w.Write(audio); err != nil || n != len(audio) {
+		t.Fatalf("burst write: n=%d err=%v", n, err)
+	}
+	for _, chunk := range live {
+		if _, err := w.Write(chunk); err != nil {
+			t.Fatalf("live write: %v", err)
+		}
+	}
+
+	// Walk the output as a client would: metaInt audio bytes, then a block
+	stream := out.Bytes()
+	var got []byte
+	for blocks := 0; len(stream) > 0; blocks++ {
+		n := min(metaInt, len(stream))
+		got = append(got, stream[:n]...)
+		stream = stream[n:]
+		if len(stream) == 0 {
+			break
+		}
+		if !bytes.HasPrefix(stream, block) {
+			t.Fatalf("expected metadata block after %d audio bytes", len(got))
+		}
+		stream = stream[len(block):]
+	}
+
+	want := append(append(audio, live[0]...), live[1]...)
+	if !bytes.Equal(got, want) {
+		t.Errorf("audio mismatch after stripping metadata")
+	}
+}
+
+func TestWriter_InitialBlock(t *testing.T) {
+	const metaInt = 16
+	block := BuildBlock("StreamTitle='Song';")
+
+	var out bytes.Buffer
+	w := NewWriter(&out, metaInt, func() []byte { return block })
+
+	if err := w.WriteInitialBlock(); err != nil {
+		t.Fatalf("initial block: %v", err)
+	}
+	w.Write(bytes.Repeat([]byte{0xAA}, 20))
+
+	// The block leads, then the count starts over for the audio
+	want := append(append(append([]byte{}, block...), bytes.Repeat([]byte{0xAA}, metaInt)...), block...)
+	want = append(want, bytes.Repeat([]byte{0xAA}, 4)...)
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("unexpected output % x", out.Bytes())
+	}
+}
+
+func TestWriter_NoMetaInt(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, 0, func() []byte { t.Fatal("block requested"); return nil })
+
+	w.Write([]byte("abc"))
+	if out.String() != "abc" {
+		t.Errorf("expected passthrough, got %q", out.String())
+	}
+}
+
+type failingWriter struct{ after int }
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if f.after <= 0 {
+		return 0, errors.New("broken pipe")
+	}
+	n := min(len(p), f.after)
+	f.after -= n
+	return n, nil
+}
+
+func TestWriter_PropagatesErrors(t *testing.T) {
+	w := NewWriter(&failingWriter{after: 10}, 4, func() []byte { return []byte{0} })
+
+	n, err := w.Write(make([]byte, 32))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if n > 10 {
+		t.Errorf("reported %d audio bytes written past the failure", n)
+	}
+}
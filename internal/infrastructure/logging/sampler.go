@@ -0,0 +1,28 @@
+// ABOUTME: Log sampling for high-frequency events such as client connects
+// ABOUTME: Logs one in every N events; errors and health transitions bypass it
+package logging
+
+import "sync/atomic"
+
+// Sampler selects one in every N events for logging
+type Sampler struct {
+	every uint64
+	seen  atomic.Uint64
+}
+
+// NewSampler logs one in every n events; values below 2 log everything
+func NewSampler(n int) *Sampler {
+	if n < 1 {
+		n = 1
+	}
+	return &Sampler{every: uint64(n)}
+}
+
+// Sample records an event and reports whether it should be logged. The
+// first event is always logged so a quiet server still shows activity.
+func (s *Sampler) Sample() bool {
+	if s == nil || s.every == 1 {
+		return true
+	}
+	return (s.seen.Add(1)-1)%s.every == 0
+}
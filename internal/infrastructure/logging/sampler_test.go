@@ -0,0 +1,31 @@
+// ABOUTME: Tests for log sampling
+// ABOUTME: Verifies 1-in-N selection and the log-everything defaults
+package logging
+
+import "testing"
+
+func TestSampler_OneInN(t *testing.T) {
+	s := NewSampler(3)
+
+	var logged []int
+	for i := 0; i < 9; i++ {
+		if s.Sample() {
+			logged = append(logged, i)
+		}
+	}
+
+	if len(logged) != 3 || logged[0] != 0 || logged[1] != 3 || logged[2] != 6 {
+		t.Errorf("expected events 0, 3, 6 logged, got %v", logged)
+	}
+}
+
+func TestSampler_Defaults(t *testing.T) {
+	var nilSampler *Sampler
+	for name, s := range map[string]*Sampler{"nil": nilSampler, "0": NewSampler(0), "1": NewSampler(1), "-5": NewSampler(-5)} {
+		for i := 0; i < 5; i++ {
+			if !s.Sample() {
+				t.Fatalf("sampler %s: expected event %d logged", name, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,415 @@
+// ABOUTME: Metadata builder turning upstream JSON into ICY strings
+// ABOUTME: Extracts placeholder fields and renders them through format templates
+package metadata
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Build modes select how the ICY string is produced
+const (
+	ModeTemplate    = "template"    // render Format with extracted fields (default)
+	ModePassthrough = "passthrough" // use PassthroughField's value verbatim
+)
+
+type BuildConfig struct {
+	Mode                string
+	Format              string
+	PassthroughField    string // JSON path holding a ready-made ICY string
+	ValidatePassthrough bool   // reject passthrough values not starting with StreamTitle=
+	StripSingleQuotes   bool
+	NormalizeWhitespace bool
+	FallbackKeyOrder    []string
+	DedupeSegments      bool        // collapse "A - B - A - B" into "A - B"
+	Transforms          []Transform // applied in order to the title after the flags above
+	MaxTitleBytes       int         // truncate the title beyond this many bytes (0 = no limit)
+
+	Defaults            map[string]string // placeholder values used when the upstream one is empty
+	DropEmptySeparators bool              // drop a separator next to an empty placeholder, and stray copies of the template's separators in the title
+	CleanSeparator      string            // also collapse repeated and dangling copies of this separator in the title (empty = off)
+	Split               Split             // applied to the extracted fields before templating (optional)
+
+	// TitleOverrides replaces sentinel titles such as "" or "adbreak" with a
+	// friendly one. Keys must be normalized with OverrideKey.
+	TitleOverrides map[string]string
+}
+
+// Split divides a combined upstream value such as "Artist - Title" at the
+// first Separator into the First and Rest placeholders, overriding what was
+// extracted for them. Without a separator the whole value goes to Rest.
+type Split struct {
+	Field     string // JSON path of the combined value; empty disables splitting
+	Separator string
+	First     string
+	Rest      string
+}
+
+// MinTitleBytes is the smallest MaxTitleBytes that leaves room for a few
+// characters besides the ellipsis
+const MinTitleBytes = 16
+
+// KnownPlaceholder reports whether name is a template placeholder
+func KnownPlaceholder(name string) bool {
+	return slices.Contains(placeholders, name)
+}
+
+// placeholders lists the template fields in FallbackKeyOrder order
+var placeholders = []string{"artist", "title", "album", "artwork", "year", "label"}
+
+// Fields extracts every placeholder value from an upstream JSON document
+func (b BuildConfig) Fields(data map[string]interface{}) map[string]string {
+	fields := make(map[string]string, len(placeholders))
+	for i, placeholder := range placeholders {
+		fields[placeholder] = b.extractValue(data, i, placeholder)
+	}
+	b.Split.apply(getNestedString(data, b.Split.Field), fields)
+	return fields
+}
+
+func (s Split) apply(combined string, fields map[string]string) {
+	combined = strings.TrimSpace(combined)
+	if s.Field == "" || combined == "" {
+		return
+	}
+
+	first, rest, found := strings.Cut(combined, s.Separator)
+	if !found {
+		fields[s.Rest] = combined
+		return
+	}
+	fields[s.First] = strings.TrimSpace(first)
+	fields[s.Rest] = strings.TrimSpace(rest)
+}
+
+// Build produces the ICY string for an upstream JSON document, along with
+// the fields extracted from it
+func (b BuildConfig) Build(data map[string]interface{}) (string, map[string]string, error) {
+	fields := b.Fields(data)
+
+	switch b.Mode {
+	case "", ModeTemplate:
+		return b.Render(b.Format, fields), fields, nil
+	case ModePassthrough:
+		value := getNestedString(data, b.PassthroughField)
+		if b.ValidatePassthrough && !strings.HasPrefix(value, "StreamTitle=") {
+			return "", nil, fmt.Errorf("passthrough field %q is not an ICY string", b.PassthroughField)
+		}
+		return b.transform(value), fields, nil
+	default:
+		return "", nil, fmt.Errorf("unknown build mode %q", b.Mode)
+	}
+}
+
+// Render expands {placeholder} tokens in format and applies the configured transforms
+func (b BuildConfig) Render(format string, fields map[string]string) string {
+	values := make(map[string]string, len(placeholders))
+	for _, placeholder := range placeholders {
+		value := fields[placeholder]
+		if value == "" {
+			value = b.Defaults[placeholder]
+		}
+		values[placeholder] = value
+	}
+
+	// Values can bring their own gaps, as with an artist of "Artist - ", so
+	// the template's separators are cleaned in the finished title too
+	if b.DropEmptySeparators {
+		return b.transform(expandDroppingSeparators(format, values), templateSeparators(format)...)
+	}
+
+	// Replace all placeholders: {artist}, {title}, {album}, {artwork}, {year}, etc.
+	result := format
+	for _, placeholder := range placeholders {
+		result = strings.ReplaceAll(result, "{"+placeholder+"}", values[placeholder])
+	}
+
+	return b.transform(result)
+}
+
+// templateToken is a literal run or a placeholder in a format template
+type templateToken struct {
+	text        string
+	placeholder bool
+	dropped     bool
+}
+
+// expandDroppingSeparators expands format, removing each empty placeholder
+// together with the separator before it (or after it, at the start), so
+// "{artist} - {title}" with no artist renders as just the title
+func expandDroppingSeparators(format string, values map[string]string) string {
+	tokens := tokenizeTemplate(format)
+
+	for i := range tokens {
+		tok := &tokens[i]
+		if !tok.placeholder {
+			continue
+		}
+		value, known := values[tok.text]
+		if !known {
+			tok.text, tok.placeholder = "{"+tok.text+"}", false
+			continue
+		}
+		if value != "" {
+			tok.text = value
+			continue
+		}
+
+		tok.dropped = true
+		switch {
+		case i > 0 && isSeparator(tokens[i-1]):
+			tokens[i-1].dropped = true
+		case i+1 < len(tokens) && isSeparator(tokens[i+1]):
+			tokens[i+1].dropped = true
+		}
+	}
+
+	var out strings.Builder
+	for _, tok := range tokens {
+		if !tok.dropped {
+			out.WriteString(tok.text)
+		}
+	}
+	return out.String()
+}
+
+// tokenizeTemplate splits format into literals and {name} placeholders
+func tokenizeTemplate(format string) []templateToken {
+	var tokens []templateToken
+	for format != "" {
+		open := strings.IndexByte(format, '{')
+		closing := -1
+		if open >= 0 {
+			closing = strings.IndexByte(format[open:], '}')
+		}
+		if open < 0 || closing < 0 {
+			tokens = append(tokens, templateToken{text: format})
+			break
+		}
+
+		if open > 0 {
+			tokens = append(tokens, templateToken{text: format[:open]})
+		}
+		tokens = append(tokens, templateToken{text: format[open+1 : open+closing], placeholder: true})
+		format = format[open+closing+1:]
+	}
+	return tokens
+}
+
+// templateSeparators returns the distinct separator literals in format
+func templateSeparators(format string) []string {
+	var seps []string
+	for _, tok := range tokenizeTemplate(format) {
+		if isSeparator(tok) && !slices.Contains(seps, tok.text) {
+			seps = append(seps, tok.text)
+		}
+	}
+	return seps
+}
+
+// isSeparator reports whether tok is a live literal made only of
+// whitespace and separator punctuation, such as " - " or ", "
+func isSeparator(tok templateToken) bool {
+	if tok.placeholder || tok.dropped || strings.TrimSpace(tok.text) == "" {
+		return false
+	}
+	return strings.Trim(tok.text, " \t-–—|/,·•:") == ""
+}
+
+// transform applies the configured transformations to a formatted ICY string.
+// Quote stripping and whitespace normalization run last, since decoded
+// entities, replacements and overrides can all bring back a ' that would
+// end StreamTitle='...' early. Separators are cleaned first, CleanSeparator
+// after any passed in from the template.
+func (b BuildConfig) transform(result string, separators ...string) string {
+	if b.CleanSeparator != "" {
+		separators = append(separators, b.CleanSeparator)
+	}
+	for _, sep := range separators {
+		result = applyToTitle(result, func(s string) string { return cleanSeparators(s, sep) })
+	}
+
+	if b.DedupeSegments {
+		result = applyToTitle(result, dedupeSegments)
+	}
+
+	for _, t := range b.Transforms {
+		result = applyToTitle(result, t)
+	}
+
+	if len(b.TitleOverrides) > 0 {
+		result = applyToTitle(result, b.overrideTitle)
+	}
+
+	if b.StripSingleQuotes {
+		result = applyToTitle(result, func(s string) string { return strings.ReplaceAll(s, "'", "") })
+	}
+
+	if b.NormalizeWhitespace {
+		result = applyToTitle(result, func(s string) string { return strings.Join(strings.Fields(s), " ") })
+	}
+
+	if b.MaxTitleBytes > 0 {
+		result = applyToTitle(result, func(s string) string { return truncateUTF8(s, b.MaxTitleBytes) })
+	}
+
+	return result
+}
+
+// cleanSeparators splits s at sep and rejoins the non-blank segments with
+// it, so "Artist - ", " - Title" and "Artist -  - Title" all lose their
+// empty segments. Where sep is padded with whitespace only whitespace-bounded
+// occurrences count, so " - " leaves "Jay-Z" alone; the padding itself may
+// vary, as with doubled spaces.
+func cleanSeparators(s, sep string) string {
+	core := strings.TrimSpace(sep)
+	if core == "" {
+		return s
+	}
+	padLeft := strings.TrimLeftFunc(sep, unicode.IsSpace) != sep
+	padRight := strings.TrimRightFunc(sep, unicode.IsSpace) != sep
+
+	var segments []string
+	start := 0
+	for i := 0; i+len(core) <= len(s); {
+		end := i + len(core)
+		if s[i:end] == core &&
+			(!padLeft || i == 0 || isSpaceByte(s[i-1])) &&
+			(!padRight || end == len(s) || isSpaceByte(s[end])) {
+			segments = append(segments, s[start:i])
+			start, i = end, end
+			continue
+		}
+		i++
+	}
+	segments = append(segments, s[start:])
+
+	kept := segments[:0]
+	for _, seg := range segments {
+		if seg = strings.TrimSpace(seg); seg != "" {
+			kept = append(kept, seg)
+		}
+	}
+	return strings.Join(kept, sep)
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+// OverrideKey normalizes a title for TitleOverrides lookups: lowercased, with
+// surrounding whitespace and separators removed so a "{artist} - {title}"
+// rendered with both fields empty matches the "" key
+func OverrideKey(title string) string {
+	return strings.ToLower(strings.Trim(title, " \t-–—|/:"))
+}
+
+// overrideTitle returns the TitleOverrides replacement for title, if any
+func (b BuildConfig) overrideTitle(title string) string {
+	if replacement, ok := b.TitleOverrides[OverrideKey(title)]; ok {
+		return strings.ReplaceAll(replacement, "'", "")
+	}
+	return title
+}
+
+// dedupeSegments collapses a title whose " - " separated segments repeat as
+// a whole, as produced by feeds that put "Artist - Title" in both fields.
+// Only an exact repeat of two or more segments is collapsed, so titles like
+// "Yeah - Yeah" are left alone.
+func dedupeSegments(s string) string {
+	segments := strings.Split(s, " - ")
+	if len(segments) < 4 || len(segments)%2 != 0 {
+		return s
+	}
+
+	half := len(segments) / 2
+	for i := 0; i < half; i++ {
+		if segments[i] != segments[half+i] {
+			return s
+		}
+	}
+
+	return strings.Join(segments[:half], " - ")
+}
+
+// truncateUTF8 shortens s to at most max bytes on a rune boundary, marking
+// the cut with an ellipsis when a character still fits beside it
+func truncateUTF8(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	if max <= 0 {
+		return ""
+	}
+
+	const ellipsis = "…"
+
+	if cut := runeBoundary(s, max-len(ellipsis)); cut > 0 {
+		return s[:cut] + ellipsis
+	}
+	return s[:runeBoundary(s, max)]
+}
+
+// runeBoundary returns the largest rune boundary in s at or below n
+func runeBoundary(s string, n int) int {
+	if n <= 0 {
+		return 0
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return n
+}
+
+// extractValue tries to extract a value using fallback paths or simple key lookup
+func (b BuildConfig) extractValue(data map[string]interface{}, idx int, placeholder string) string {
+	// If FallbackKeyOrder is configured, the path at the placeholder's index wins
+	if idx < len(b.FallbackKeyOrder) {
+		if val := getNestedString(data, b.FallbackKeyOrder[idx]); val != "" {
+			return val
+		}
+	}
+
+	// Fallback to simple key lookup
+	return getString(data, placeholder)
+}
+
+func getString(data map[string]interface{}, key string) string {
+	if val, ok := data[key].(string); ok {
+		return val
+	}
+	return ""
+}
+
+// getNestedString traverses a nested JSON path using dot notation
+// e.g., "now.secondLine.title" → data["now"]["secondLine"]["title"]
+// Handles strings and numbers (converts numbers to strings)
+func getNestedString(data map[string]interface{}, path string) string {
+	parts := strings.Split(path, ".")
+	var current interface{} = data
+
+	for _, part := range parts {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			current = v[part]
+		default:
+			return ""
+		}
+	}
+
+	// Handle different types
+	switch v := current.(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	case int:
+		return fmt.Sprintf("%d", v)
+	case bool:
+		return fmt.Sprintf("%t", v)
+	}
+	return ""
+}
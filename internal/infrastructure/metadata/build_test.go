@@ -0,0 +1,382 @@
+// ABOUTME: Tests for the metadata builder
+// ABOUTME: Verifies rendering transforms such as length limits
+package metadata
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestBuildConfig_MaxTitleBytes(t *testing.T) {
+	b := BuildConfig{MaxTitleBytes: 16}
+
+	// Only the title counts towards the limit; the ICY wrapper stays intact
+	got := b.Render("StreamTitle='{title}';", map[string]string{"title": "A Very Long Song Title"})
+	want := "StreamTitle='A Very Long S…';"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestTruncateUTF8_TinyLimits(t *testing.T) {
+	tests := []struct {
+		max  int
+		want string
+	}{
+		{-1, ""},
+		{0, ""},
+		{1, ""},
+		{2, "é"},
+		{3, "é"},
+		{4, "éé"},
+		{5, "é…"},
+		{7, "éé…"},
+	}
+	for _, tt := range tests {
+		got := truncateUTF8("éééé", tt.max)
+		if got != tt.want || len(got) > max(tt.max, 0) || !utf8.ValidString(got) {
+			t.Errorf("truncateUTF8(%d) = %q, want %q", tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestBuildConfig_MaxTitleBytesUTF8(t *testing.T) {
+	b := BuildConfig{MaxTitleBytes: 10}
+
+	got := b.Render("{title}", map[string]string{"title": "Ééééééééé"})
+	if !utf8.ValidString(got) {
+		t.Errorf("truncation split a rune: %q", got)
+	}
+
+	if len(got) > 10 {
+		t.Errorf("expected at most 10 bytes, got %d (%q)", len(got), got)
+	}
+}
+
+func TestBuildConfig_MaxTitleBytesUnderLimit(t *testing.T) {
+	b := BuildConfig{MaxTitleBytes: 100}
+
+	got := b.Render("StreamTitle='{title}';", map[string]string{"title": "Short"})
+	if got != "StreamTitle='Short';" {
+		t.Errorf("expected untouched metadata, got %q", got)
+	}
+}
+
+func TestBuildConfig_DedupeSegments(t *testing.T) {
+	b := BuildConfig{DedupeSegments: true}
+	format := "StreamTitle='{artist} - {title}';"
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   string
+	}{
+		{
+			name:   "both fields hold the full title",
+			fields: map[string]string{"artist": "Artist - Title", "title": "Artist - Title"},
+			want:   "StreamTitle='Artist - Title';",
+		},
+		{
+			name:   "normal title untouched",
+			fields: map[string]string{"artist": "Artist", "title": "Title"},
+			want:   "StreamTitle='Artist - Title';",
+		},
+		{
+			name:   "single repeated segment untouched",
+			fields: map[string]string{"artist": "Yeah", "title": "Yeah"},
+			want:   "StreamTitle='Yeah - Yeah';",
+		},
+		{
+			name:   "partial repeat untouched",
+			fields: map[string]string{"artist": "A - B", "title": "A - C"},
+			want:   "StreamTitle='A - B - A - C';",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.Render(format, tt.fields); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildConfig_Passthrough(t *testing.T) {
+	trim, _ := NewTransform("trim", nil)
+	b := BuildConfig{
+		Mode:             ModePassthrough,
+		Format:           "StreamTitle='{artist} - {title}';",
+		PassthroughField: "now.icy",
+		Transforms:       []Transform{trim},
+	}
+
+	data := map[string]interface{}{
+		"now": map[string]interface{}{"icy": "StreamTitle=' Artist - Song ';"},
+	}
+
+	meta, _, err := b.Build(data)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	// Passed through verbatim apart from the configured transforms
+	if meta != "StreamTitle='Artist - Song';" {
+		t.Errorf("expected passthrough metadata, got %q", meta)
+	}
+}
+
+func TestBuildConfig_PassthroughValidation(t *testing.T) {
+	b := BuildConfig{
+		Mode:                ModePassthrough,
+		PassthroughField:    "icy",
+		ValidatePassthrough: true,
+	}
+
+	if _, _, err := b.Build(map[string]interface{}{"icy": "Artist - Song"}); err == nil {
+		t.Error("expected error for a non-ICY passthrough value")
+	}
+
+	meta, _, err := b.Build(map[string]interface{}{"icy": "StreamTitle='Song';"})
+	if err != nil || meta != "StreamTitle='Song';" {
+		t.Errorf("expected valid passthrough, got %q (%v)", meta, err)
+	}
+}
+
+func TestBuildConfig_Defaults(t *testing.T) {
+	b := BuildConfig{Defaults: map[string]string{"album": "Unknown Album"}}
+	format := "StreamTitle='{artist} - {title} ({album})';"
+
+	got := b.Render(format, map[string]string{"artist": "Artist", "title": "Title"})
+	if want := "StreamTitle='Artist - Title (Unknown Album)';"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	got = b.Render(format, map[string]string{"artist": "Artist", "title": "Title", "album": "Real"})
+	if want := "StreamTitle='Artist - Title (Real)';"; got != want {
+		t.Errorf("expected upstream value to win, got %q", got)
+	}
+}
+
+func TestBuildConfig_DropEmptySeparators(t *testing.T) {
+	b := BuildConfig{DropEmptySeparators: true}
+
+	tests := []struct {
+		name   string
+		format string
+		fields map[string]string
+		want   string
+	}{
+		{
+			name:   "missing title drops trailing separator",
+			format: "StreamTitle='{artist} - {title}';",
+			fields: map[string]string{"artist": "Artist"},
+			want:   "StreamTitle='Artist';",
+		},
+		{
+			name:   "missing artist drops leading separator",
+			format: "StreamTitle='{artist} - {title}';",
+			fields: map[string]string{"title": "Title"},
+			want:   "StreamTitle='Title';",
+		},
+		{
+			name:   "missing middle field",
+			format: "{artist} - {album} - {title}",
+			fields: map[string]string{"artist": "Artist", "title": "Title"},
+			want:   "Artist - Title",
+		},
+		{
+			name:   "two missing fields in a row",
+			format: "{artist} - {album} - {title}",
+			fields: map[string]string{"title": "Title"},
+			want:   "Title",
+		},
+		{
+			name:   "all present untouched",
+			format: "{artist}, {year} / {label}",
+			fields: map[string]string{"artist": "A", "year": "1999", "label": "L"},
+			want:   "A, 1999 / L",
+		},
+		{
+			name:   "unknown placeholder kept literally",
+			format: "{artist} {nope}",
+			fields: map[string]string{"artist": "A"},
+			want:   "A {nope}",
+		},
+		{
+			name:   "value with its own dangling separator",
+			format: "StreamTitle='{artist} - {title}';",
+			fields: map[string]string{"artist": "Artist - ", "title": "Title"},
+			want:   "StreamTitle='Artist - Title';",
+		},
+		{
+			name:   "value that is only a separator",
+			format: "StreamTitle='{artist} - {title}';",
+			fields: map[string]string{"artist": " - ", "title": "Title"},
+			want:   "StreamTitle='Title';",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.Render(tt.format, tt.fields); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildConfig_Split(t *testing.T) {
+	b := BuildConfig{
+		Format: "StreamTitle='{artist} - {title}';",
+		Split:  Split{Field: "now.playing", Separator: " - ", First: "artist", Rest: "title"},
+	}
+
+	tests := []struct {
+		combined string
+		artist   string
+		title    string
+	}{
+		{"Daft Punk - One More Time", "Daft Punk", "One More Time"},
+		{"Boards of Canada - Roygbiv - Live", "Boards of Canada", "Roygbiv - Live"},
+		{"  Nina Simone  -  Feeling Good ", "Nina Simone", "Feeling Good"},
+		{"Jay-Z - 99 Problems", "Jay-Z", "99 Problems"},
+		{"Station Ident", "", "Station Ident"},
+		{"Sigur Rós – Hoppípolla", "", "Sigur Rós – Hoppípolla"}, // en dash isn't the separator
+	}
+
+	for _, tt := range tests {
+		data := map[string]interface{}{"now": map[string]interface{}{"playing": tt.combined}}
+		_, fields, err := b.Build(data)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.combined, err)
+		}
+		if fields["artist"] != tt.artist || fields["title"] != tt.title {
+			t.Errorf("%q: expected artist %q title %q, got %q / %q", tt.combined, tt.artist, tt.title, fields["artist"], fields["title"])
+		}
+	}
+
+	// The split wins over a combined value found under the target key itself
+	b.Split.Field = "title"
+	meta, _, _ := b.Build(map[string]interface{}{"title": "Artist - Song"})
+	if want := "StreamTitle='Artist - Song';"; meta != want {
+		t.Errorf("expected %q, got %q", want, meta)
+	}
+	fields := b.Fields(map[string]interface{}{"title": "Artist - Song"})
+	if fields["artist"] != "Artist" || fields["title"] != "Song" {
+		t.Errorf("expected split fields, got %v", fields)
+	}
+}
+
+func TestBuildConfig_TitleOverrides(t *testing.T) {
+	b := BuildConfig{
+		Format: "StreamTitle='{artist} - {title}';",
+		TitleOverrides: map[string]string{
+			"":        "You're listening to X",
+			"adbreak": "Commercial break",
+		},
+	}
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   string
+	}{
+		{"empty fields", map[string]string{}, "StreamTitle='Youre listening to X';"},
+		{"sentinel title", map[string]string{"title": "adbreak"}, "StreamTitle='Commercial break';"},
+		{"sentinel case", map[string]string{"title": "ADBREAK"}, "StreamTitle='Commercial break';"},
+		{"regular title", map[string]string{"artist": "Blondie", "title": "Atomic"}, "StreamTitle='Blondie - Atomic';"},
+		{"sentinel inside title", map[string]string{"artist": "The Adbreak", "title": "Song"}, "StreamTitle='The Adbreak - Song';"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.Render(b.Format, tt.fields); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Passthrough strings are overridden too
+	b = BuildConfig{Mode: ModePassthrough, PassthroughField: "icy", TitleOverrides: map[string]string{"news": "News on the hour"}}
+	got, _, err := b.Build(map[string]interface{}{"icy": "StreamTitle='News';"})
+	if err != nil || got != "StreamTitle='News on the hour';" {
+		t.Errorf("passthrough override = %q, %v", got, err)
+	}
+}
+
+func TestBuildConfig_CleanSeparator(t *testing.T) {
+	b := BuildConfig{CleanSeparator: " - "}
+	format := "StreamTitle='{artist} - {album} - {title}';"
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   string
+	}{
+		{"all fields", map[string]string{"artist": "A", "album": "B", "title": "C"}, "StreamTitle='A - B - C';"},
+		{"missing album", map[string]string{"artist": "A", "title": "C"}, "StreamTitle='A - C';"},
+		{"missing title", map[string]string{"artist": "A", "album": "B"}, "StreamTitle='A - B';"},
+		{"missing artist", map[string]string{"album": "B", "title": "C"}, "StreamTitle='B - C';"},
+		{"only title", map[string]string{"title": "C"}, "StreamTitle='C';"},
+		{"only artist", map[string]string{"artist": "A"}, "StreamTitle='A';"},
+		{"nothing", map[string]string{}, "StreamTitle='';"},
+		{"hyphenated names", map[string]string{"artist": "Jay-Z", "title": "Song-Title"}, "StreamTitle='Jay-Z - Song-Title';"},
+		{"blank field", map[string]string{"artist": "A", "album": "  ", "title": "C"}, "StreamTitle='A - C';"},
+	}
+	for _, tt := range tests {
+		if got := b.Render(format, tt.fields); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+
+	// Values arriving with their own dangling separators are cleaned too
+	for in, want := range map[string]string{
+		"Artist - ":          "Artist",
+		" - Title":           "Title",
+		"Artist -  - Title":  "Artist - Title",
+		"Artist - - Title":   "Artist - Title",
+		"Artist -":           "Artist",
+		"-":                  "",
+		"Artist-Title":       "Artist-Title",
+		"A  -   B":           "A - B",
+		"AC/DC - Back - ":    "AC/DC - Back",
+		"Artist — Title - ":  "Artist — Title",
+		"  - Artist - Title": "Artist - Title",
+	} {
+		if got := cleanSeparators(in, " - "); got != want {
+			t.Errorf("cleanSeparators(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	// An unpadded separator matches anywhere
+	if got := cleanSeparators("|A||B|", "|"); got != "A|B" {
+		t.Errorf("expected unpadded separator cleanup, got %q", got)
+	}
+}
+
+func TestBuildConfig_StripQuotesAfterTransforms(t *testing.T) {
+	stripHTML, _ := NewTransform("strip_html", nil)
+	replace, _ := NewTransform("replace", map[string]string{"from": "`", "to": "'  "})
+	b := BuildConfig{
+		StripSingleQuotes:   true,
+		NormalizeWhitespace: true,
+		Transforms:          []Transform{stripHTML, replace},
+		TitleOverrides:      map[string]string{"": "Off  air"},
+	}
+	format := "StreamTitle='{artist} - {title}';"
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   string
+	}{
+		{"decoded entity", map[string]string{"artist": "Guns N&#39; Roses", "title": "Patience"}, "StreamTitle='Guns N Roses - Patience';"},
+		{"replacement", map[string]string{"artist": "Rock`n Roll", "title": "Song"}, "StreamTitle='Rock n Roll - Song';"},
+		{"override", map[string]string{}, "StreamTitle='Off air';"},
+	}
+	for _, tt := range tests {
+		if got := b.Render(format, tt.fields); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
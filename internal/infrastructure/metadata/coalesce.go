@@ -0,0 +1,82 @@
+// ABOUTME: Shared metadata responses for stations polling the same upstream
+// ABOUTME: Concurrent identical requests share one fetch, reused for a short TTL
+package metadata
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Coalescer shares raw metadata responses between providers whose requests
+// are identical. Polls within TTL of a successful (2xx) response reuse it,
+// and polls while one is in flight wait for it; failures are never reused.
+type Coalescer struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*coalesced
+}
+
+// coalesced is one fetch; its results are set before done is closed
+type coalesced struct {
+	done   chan struct{}
+	status int
+	body   []byte
+	err    error
+	at     time.Time
+}
+
+func NewCoalescer(ttl time.Duration) *Coalescer {
+	return &Coalescer{ttl: ttl, now: time.Now, entries: make(map[string]*coalesced)}
+}
+
+// Do returns the shared response for key, calling fetch when there is none.
+// The fetch runs detached from ctx so one station shutting down doesn't fail
+// the others waiting on it; fetch must bound itself with a timeout.
+func (c *Coalescer) Do(ctx context.Context, key string, fetch func(context.Context) (int, []byte, error)) (int, []byte, error) {
+	c.mu.Lock()
+	e := c.entries[key]
+	if e == nil || !c.fresh(e) {
+		c.prune()
+		e = &coalesced{done: make(chan struct{})}
+		c.entries[key] = e
+
+		go func() {
+			status, body, err := fetch(context.WithoutCancel(ctx))
+			c.mu.Lock()
+			e.status, e.body, e.err, e.at = status, body, err, c.now()
+			c.mu.Unlock()
+			close(e.done)
+		}()
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-e.done:
+		return e.status, e.body, e.err
+	case <-ctx.Done():
+		return 0, nil, ctx.Err()
+	}
+}
+
+// fresh reports whether e is in flight or a success younger than the TTL.
+// Callers hold mu.
+func (c *Coalescer) fresh(e *coalesced) bool {
+	select {
+	case <-e.done:
+		return e.err == nil && e.status/100 == 2 && c.now().Sub(e.at) < c.ttl
+	default:
+		return true
+	}
+}
+
+// prune drops finished entries that can't be reused. Callers hold mu.
+func (c *Coalescer) prune() {
+	for key, e := range c.entries {
+		if !c.fresh(e) {
+			delete(c.entries, key)
+		}
+	}
+}
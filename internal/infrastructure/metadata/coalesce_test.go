@@ -0,0 +1,127 @@
+// ABOUTME: Tests for shared metadata responses
+// ABOUTME: Counts upstream requests for concurrent, repeated and distinct polls
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoalescer_ConcurrentPollsShareOneRequest(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		w.Write([]byte(`{"artist":"A","title":"T"}`))
+	}))
+	defer server.Close()
+
+	shared := NewCoalescer(time.Second)
+	formats := []string{"StreamTitle='{artist} - {title}';", "StreamTitle='{title}';"}
+
+	const n = 10
+	results := make([]string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		provider := NewHTTP(HTTPConfig{
+			URL:     server.URL,
+			Timeout: 5 * time.Second,
+			Build:   BuildConfig{Format: formats[i%2]},
+			Shared:  shared,
+		})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			meta, err := provider.Fetch(context.Background())
+			if err != nil {
+				t.Errorf("Fetch failed: %v", err)
+			}
+			results[i] = meta
+		}()
+	}
+
+	// Let every poll join the in-flight request before it completes
+	deadline := time.Now().Add(2 * time.Second)
+	for requests.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := requests.Load(); got != 1 {
+		t.Errorf("expected 1 upstream request for %d polls, got %d", n, got)
+	}
+	for i, meta := range results {
+		want := []string{"StreamTitle='A - T';", "StreamTitle='T';"}[i%2]
+		if meta != want {
+			t.Errorf("poll %d: expected its own template %q, got %q", i, want, meta)
+		}
+	}
+}
+
+func TestCoalescer_TTLAndKeys(t *testing.T) {
+	var requests atomic.Int32
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"title":"T"}`))
+	}))
+	defer server.Close()
+
+	now := time.Unix(1000, 0)
+	shared := NewCoalescer(time.Second)
+	shared.now = func() time.Time { return now }
+
+	newProvider := func(headers map[string]string) *HTTPProvider {
+		return NewHTTP(HTTPConfig{URL: server.URL, Timeout: 5 * time.Second, Headers: headers, Build: BuildConfig{Format: "StreamTitle='{title}';"}, Shared: shared})
+	}
+	a, b := newProvider(nil), newProvider(nil)
+	keyed := newProvider(map[string]string{"X-Api-Key": "other"})
+
+	fetch := func(p *HTTPProvider) error {
+		_, err := p.Fetch(context.Background())
+		return err
+	}
+
+	fetch(a)
+	fetch(b)
+	if got := requests.Load(); got != 1 {
+		t.Fatalf("expected polls within the TTL to share a response, got %d requests", got)
+	}
+
+	fetch(keyed)
+	if got := requests.Load(); got != 2 {
+		t.Fatalf("expected different headers to fetch separately, got %d requests", got)
+	}
+
+	now = now.Add(time.Second)
+	fetch(a)
+	if got := requests.Load(); got != 3 {
+		t.Fatalf("expected a new request once the TTL passed, got %d requests", got)
+	}
+
+	// Failures are reported to the poll that saw them but never reused
+	now = now.Add(time.Second)
+	fail.Store(true)
+	if fetch(a) == nil {
+		t.Fatal("expected the upstream error")
+	}
+	fail.Store(false)
+	if err := fetch(b); err != nil {
+		t.Fatalf("expected a retry after the failure, got %v", err)
+	}
+	if got := requests.Load(); got != 5 {
+		t.Errorf("expected 5 requests, got %d", got)
+	}
+}
@@ -7,27 +7,34 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
-)
 
-type BuildConfig struct {
-	Format              string
-	StripSingleQuotes   bool
-	NormalizeWhitespace bool
-	FallbackKeyOrder    []string
-}
+	"github.com/harper/radio-metadata-proxy/internal/domain"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/expand"
+)
 
 type HTTPConfig struct {
-	URL     string
-	Timeout time.Duration
-	Build   BuildConfig
+	URL      string // {now_unix} and {station_id} are filled in on every fetch
+	Username string // Basic auth; takes precedence over user:pass@ in the URL
+	Password string // may use {env:NAME}
+	Timeout  time.Duration
+	Headers  map[string]string // values may use {env:NAME}
+	Build    BuildConfig
+	Shared   *Coalescer // shares responses with identical requests from other providers (optional)
+
+	StationID string // substituted for {station_id} in URL
 }
 
 type HTTPProvider struct {
 	cfg    HTTPConfig
 	client *http.Client
+	now    func() time.Time
 }
 
 func NewHTTP(cfg HTTPConfig) *HTTPProvider {
@@ -38,116 +45,148 @@ func NewHTTP(cfg HTTPConfig) *HTTPProvider {
 	return &HTTPProvider{
 		cfg:    cfg,
 		client: client,
+		now:    time.Now,
 	}
 }
 
 func (h *HTTPProvider) Fetch(ctx context.Context) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", h.cfg.URL, nil)
+	meta, _, err := h.FetchFields(ctx)
+	return meta, err
+}
+
+// FetchFields returns the formatted ICY string along with the fields it was built from
+func (h *HTTPProvider) FetchFields(ctx context.Context) (string, map[string]string, error) {
+	_, body, err := h.fetchShared(ctx)
 	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
+		return "", nil, err
 	}
+	return h.build(body)
+}
 
-	req.Header.Set("Cache-Control", "no-store")
-
-	resp, err := h.client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("http request: %w", err)
+// fetchShared performs the request, through the coalescer when configured.
+// Each provider still builds its own title from the shared body.
+func (h *HTTPProvider) fetchShared(ctx context.Context) (int, []byte, error) {
+	u := h.requestURL()
+	if h.cfg.Shared == nil {
+		return h.get(ctx, u)
 	}
-	defer resp.Body.Close()
+	return h.cfg.Shared.Do(ctx, h.requestKey(u), func(ctx context.Context) (int, []byte, error) {
+		return h.get(ctx, u)
+	})
+}
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
-	if err != nil {
-		return "", fmt.Errorf("read body: %w", err)
+// requestURL fills in the per-request tokens, leaving URLs without them,
+// and any other braces, untouched. Unlike {env:NAME} in headers these change
+// on every fetch, which is what cache-busting APIs need.
+func (h *HTTPProvider) requestURL() string {
+	if !strings.Contains(h.cfg.URL, "{") {
+		return h.cfg.URL
 	}
+	return strings.NewReplacer(
+		"{now_unix}", strconv.FormatInt(h.now().Unix(), 10),
+		"{station_id}", url.PathEscape(h.cfg.StationID),
+	).Replace(h.cfg.URL)
+}
 
-	// Parse JSON
-	var data map[string]interface{}
-	if err := json.Unmarshal(body, &data); err != nil {
-		return "", fmt.Errorf("parse json: %w", err)
+// requestKey identifies what get sends, so only requests that would get the
+// same response share one: the URL plus every credential and header
+func (h *HTTPProvider) requestKey(u string) string {
+	var b strings.Builder
+	b.WriteString("GET " + u)
+	if h.cfg.Username != "" {
+		b.WriteString("\x00" + h.cfg.Username + ":" + expand.String(h.cfg.Password, nil))
 	}
+	headers := expand.Headers(h.cfg.Headers, nil)
+	for _, k := range slices.Sorted(maps.Keys(headers)) {
+		b.WriteString("\x00" + http.CanonicalHeaderKey(k) + ": " + headers[k])
+	}
+	return b.String()
+}
 
-	// Build ICY string from format template with all placeholders
-	result := h.cfg.Build.Format
+// FetchDebug fetches once and reports the raw body alongside the result,
+// so operators can see why a template renders what it does
+func (h *HTTPProvider) FetchDebug(ctx context.Context) domain.MetadataDebug {
+	var d domain.MetadataDebug
 
-	// Replace all placeholders: {artist}, {title}, {album}, {artwork}, {year}, etc.
-	placeholders := []string{"artist", "title", "album", "artwork", "year", "label"}
-	for _, placeholder := range placeholders {
-		value := h.extractValue(data, placeholder)
-		result = strings.ReplaceAll(result, "{"+placeholder+"}", value)
+	status, body, err := h.get(ctx, h.requestURL())
+	d.Status = status
+	d.Body = string(body)
+	if len(body) > debugBodyLimit {
+		d.Body, d.BodyTruncated = string(body[:debugBodyLimit]), true
 	}
-
-	// Apply transformations
-	if h.cfg.Build.StripSingleQuotes {
-		result = strings.ReplaceAll(result, "'", "")
+	if err == nil {
+		d.Metadata, d.Fields, err = h.build(body)
 	}
-
-	if h.cfg.Build.NormalizeWhitespace {
-		result = strings.Join(strings.Fields(result), " ")
+	if err != nil {
+		d.Error = err.Error()
 	}
-
-	return result, nil
+	return d
 }
 
-// extractValue tries to extract a value using fallback paths or simple key lookup
-func (h *HTTPProvider) extractValue(data map[string]interface{}, placeholder string) string {
-	// If FallbackKeyOrder is configured, use it
-	if len(h.cfg.Build.FallbackKeyOrder) > 0 {
-		// Map placeholder to fallback path index
-		// Order: artist, title, album, artwork, year, label, ...
-		placeholderMap := map[string]int{
-			"artist":  0,
-			"title":   1,
-			"album":   2,
-			"artwork": 3,
-			"year":    4,
-			"label":   5,
-		}
+// maxBodyBytes caps a metadata response; anything longer is treated as
+// truncated rather than parsed
+const maxBodyBytes = 64 * 1024
+
+// debugBodyLimit caps the raw body returned by FetchDebug
+const debugBodyLimit = 16 * 1024
+
+// get performs the request and returns the status and up to maxBodyBytes of
+// body. The whole exchange, body included, is bounded by Timeout, so an
+// endpoint trickling bytes can't hold a poll open past it.
+func (h *HTTPProvider) get(ctx context.Context, u string) (int, []byte, error) {
+	if h.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.cfg.Timeout)
+		defer cancel()
+	}
 
-		if idx, ok := placeholderMap[placeholder]; ok && idx < len(h.cfg.Build.FallbackKeyOrder) {
-			path := h.cfg.Build.FallbackKeyOrder[idx]
-			if val := getNestedString(data, path); val != "" {
-				return val
-			}
-		}
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("create request: %w", err)
 	}
 
-	// Fallback to simple key lookup
-	return getString(data, placeholder)
-}
+	req.Header.Set("Cache-Control", "no-store")
+	if h.cfg.Username != "" || h.cfg.Password != "" {
+		req.SetBasicAuth(h.cfg.Username, expand.String(h.cfg.Password, nil))
+	}
+	for k, v := range expand.Headers(h.cfg.Headers, nil) {
+		req.Header.Set(k, v)
+	}
 
-func getString(data map[string]interface{}, key string) string {
-	if val, ok := data[key].(string); ok {
-		return val
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("http request: %w", err)
 	}
-	return ""
-}
+	defer resp.Body.Close()
+	// Unblock a read stuck on a silent upstream as soon as ctx ends
+	stop := context.AfterFunc(ctx, func() { resp.Body.Close() })
+	defer stop()
 
-// getNestedString traverses a nested JSON path using dot notation
-// e.g., "now.secondLine.title" → data["now"]["secondLine"]["title"]
-// Handles strings and numbers (converts numbers to strings)
-func getNestedString(data map[string]interface{}, path string) string {
-	parts := strings.Split(path, ".")
-	var current interface{} = data
-
-	for _, part := range parts {
-		switch v := current.(type) {
-		case map[string]interface{}:
-			current = v[part]
-		default:
-			return ""
+	// Read one byte past the cap to tell a full body from a cut-off one
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes+1))
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
 		}
+		return resp.StatusCode, body, fmt.Errorf("read body: %w", err)
 	}
+	if len(body) > maxBodyBytes {
+		return resp.StatusCode, body[:maxBodyBytes], fmt.Errorf("response body exceeds %d bytes", maxBodyBytes)
+	}
+	return resp.StatusCode, body, nil
+}
 
-	// Handle different types
-	switch v := current.(type) {
-	case string:
-		return v
-	case float64:
-		return fmt.Sprintf("%.0f", v)
-	case int:
-		return fmt.Sprintf("%d", v)
-	case bool:
-		return fmt.Sprintf("%t", v)
+// build parses a JSON body and formats it
+func (h *HTTPProvider) build(body []byte) (string, map[string]string, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return "", nil, fmt.Errorf("parse json: %w", err)
 	}
-	return ""
+
+	return h.cfg.Build.Build(data)
+}
+
+// Render formats fields with an alternate template using this provider's transforms
+func (h *HTTPProvider) Render(format string, fields map[string]string) string {
+	return h.cfg.Build.Render(format, fields)
 }
@@ -6,6 +6,7 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -76,3 +77,237 @@ func TestHTTPProvider_Fetch_NestedJSON(t *testing.T) {
 		t.Errorf("expected %q, got %q", expected, result)
 	}
 }
+
+func TestHTTPProvider_FetchFieldsAndRender(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"artist":"Test Artist","title":"Test Song","album":"Test Album"}`))
+	}))
+	defer server.Close()
+
+	provider := NewHTTP(HTTPConfig{
+		URL:     server.URL,
+		Timeout: 5 * time.Second,
+		Build: BuildConfig{
+			Format: "StreamTitle='{artist} - {title}';",
+		},
+	})
+
+	meta, fields, err := provider.FetchFields(context.Background())
+	if err != nil {
+		t.Fatalf("FetchFields failed: %v", err)
+	}
+
+	if meta != "StreamTitle='Test Artist - Test Song';" {
+		t.Errorf("unexpected primary metadata %q", meta)
+	}
+
+	if fields["album"] != "Test Album" {
+		t.Errorf("expected album field 'Test Album', got %q", fields["album"])
+	}
+
+	short := provider.Render("StreamTitle='{title}';", fields)
+	if short != "StreamTitle='Test Song';" {
+		t.Errorf("unexpected rendered metadata %q", short)
+	}
+}
+
+func TestHTTPProvider_HeaderEnvTemplate(t *testing.T) {
+	t.Setenv("META_TEST_KEY", "k-42")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Api-Key"); got != "k-42" {
+			t.Errorf("expected expanded X-Api-Key header, got %q", got)
+		}
+		w.Write([]byte(`{"title":"Song"}`))
+	}))
+	defer server.Close()
+
+	provider := NewHTTP(HTTPConfig{
+		URL:     server.URL,
+		Timeout: 5 * time.Second,
+		Headers: map[string]string{"X-Api-Key": "{env:META_TEST_KEY}"},
+		Build:   BuildConfig{Format: "StreamTitle='{title}';"},
+	})
+
+	if _, err := provider.Fetch(context.Background()); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+}
+
+func TestHTTPProvider_URLTemplate(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Path+"?"+r.URL.RawQuery)
+		w.Write([]byte(`{"title":"Song"}`))
+	}))
+	defer server.Close()
+
+	provider := NewHTTP(HTTPConfig{
+		URL:       server.URL + "/{station_id}/now?t={now_unix}&x={other}",
+		Timeout:   5 * time.Second,
+		Build:     BuildConfig{Format: "StreamTitle='{title}';"},
+		StationID: "fip",
+	})
+	clock := time.Unix(1700000000, 0)
+	provider.now = func() time.Time { return clock }
+
+	for range 2 {
+		if _, err := provider.Fetch(context.Background()); err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		clock = clock.Add(time.Second)
+	}
+
+	want := []string{"/fip/now?t=1700000000&x={other}", "/fip/now?t=1700000001&x={other}"}
+	if len(queries) != 2 || queries[0] != want[0] || queries[1] != want[1] {
+		t.Errorf("expected %q, got %q", want, queries)
+	}
+}
+
+func TestHTTPProvider_BasicAuth(t *testing.T) {
+	t.Setenv("META_TEST_PASSWORD", "hunter2")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "radio" || pass != "hunter2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"title":"Song"}`))
+	}))
+	defer server.Close()
+
+	build := BuildConfig{Format: "StreamTitle='{title}';"}
+	userinfo := strings.Replace(server.URL, "http://", "http://radio:hunter2@", 1)
+
+	for name, cfg := range map[string]HTTPConfig{
+		"fields":   {URL: server.URL, Username: "radio", Password: "{env:META_TEST_PASSWORD}", Build: build},
+		"userinfo": {URL: userinfo, Build: build},
+	} {
+		meta, err := NewHTTP(cfg).Fetch(context.Background())
+		if err != nil || meta != "StreamTitle='Song';" {
+			t.Errorf("%s: expected authenticated fetch, got %q, %v", name, meta, err)
+		}
+	}
+}
+
+func TestHTTPProvider_MalformedPayloads(t *testing.T) {
+	payloads := []string{
+		`[]`,
+		`[{"title": "x"}]`,
+		`42`,
+		`null`,
+		`"just a string"`,
+		`{"now": 7}`,
+		`{"now": [1, 2, 3]}`,
+		`{"now": {"firstLine": null, "secondLine": {"title": {"deep": true}}}}`,
+		`{"title": ["a", "b"], "artist": {"name": 1}}`,
+		`{"now": {"firstLine": {"title": 1e400}}}`,
+		`{"truncated": `,
+		"\xff\xfe{}",
+	}
+
+	builds := map[string]BuildConfig{
+		"template": {
+			Format:           "StreamTitle='{artist} - {title}';",
+			FallbackKeyOrder: []string{"now.secondLine.title", "now.firstLine.title"},
+		},
+		"passthrough": {Mode: ModePassthrough, PassthroughField: "now.firstLine.title"},
+	}
+
+	for _, build := range builds {
+		for _, payload := range payloads {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(payload))
+			}))
+
+			provider := NewHTTP(HTTPConfig{URL: server.URL, Timeout: 5 * time.Second, Build: build})
+			// Errors are fine; panics fail the test
+			provider.Fetch(context.Background())
+			provider.FetchFields(context.Background())
+			server.Close()
+		}
+	}
+}
+
+func TestHTTPProvider_EndlessBody(t *testing.T) {
+	t.Run("slow trickle hits the timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"title":"`))
+			for {
+				select {
+				case <-r.Context().Done():
+					return
+				case <-time.After(10 * time.Millisecond):
+				}
+				w.Write([]byte("a"))
+				w.(http.Flusher).Flush()
+			}
+		}))
+		defer server.Close()
+
+		provider := NewHTTP(HTTPConfig{URL: server.URL, Timeout: 200 * time.Millisecond})
+		start := time.Now()
+		_, err := provider.Fetch(context.Background())
+		if err == nil {
+			t.Fatal("expected an error for a body that never ends")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("fetch took %v, expected it to stop at the 200ms timeout", elapsed)
+		}
+	})
+
+	t.Run("context deadline stops the read", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"title":"`))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}))
+		defer server.Close()
+
+		provider := NewHTTP(HTTPConfig{URL: server.URL, Timeout: time.Minute})
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if _, err := provider.Fetch(ctx); err == nil {
+			t.Fatal("expected an error once the context expired")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("fetch took %v after the context deadline", elapsed)
+		}
+	})
+
+	t.Run("oversized body is rejected", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"title":"`))
+			chunk := []byte(strings.Repeat("a", 4096))
+			for r.Context().Err() == nil {
+				if _, err := w.Write(chunk); err != nil {
+					return
+				}
+			}
+		}))
+		defer server.Close()
+
+		provider := NewHTTP(HTTPConfig{URL: server.URL, Timeout: 5 * time.Second})
+		_, err := provider.Fetch(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "exceeds") {
+			t.Fatalf("expected an oversized body error, got %v", err)
+		}
+	})
+
+	t.Run("body exactly at the cap parses", func(t *testing.T) {
+		prefix, suffix := `{"title":"`, `"}`
+		title := strings.Repeat("a", maxBodyBytes-len(prefix)-len(suffix))
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(prefix + title + suffix))
+		}))
+		defer server.Close()
+
+		provider := NewHTTP(HTTPConfig{URL: server.URL, Timeout: 5 * time.Second, Build: BuildConfig{Format: "StreamTitle='{title}';"}})
+		if _, err := provider.Fetch(context.Background()); err != nil {
+			t.Fatalf("expected a body of exactly %d bytes to parse, got %v", maxBodyBytes, err)
+		}
+	})
+}
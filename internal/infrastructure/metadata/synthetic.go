@@ -0,0 +1,46 @@
+// ABOUTME: Synthetic metadata provider cycling through fake track titles
+// ABOUTME: Pairs with the tone source for end-to-end tests without any upstream
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// defaultFormat is used by providers without an upstream format of their own
+const defaultFormat = "StreamTitle='{artist} - {title}';"
+
+// SyntheticProvider returns a new fake track on every fetch, so the poll
+// interval sets how often the title changes.
+type SyntheticProvider struct {
+	build BuildConfig
+	count atomic.Int64
+}
+
+func NewSynthetic(build BuildConfig) *SyntheticProvider {
+	if build.Format == "" {
+		build.Format = defaultFormat
+	}
+	return &SyntheticProvider{build: build}
+}
+
+func (s *SyntheticProvider) Fetch(ctx context.Context) (string, error) {
+	meta, _, err := s.FetchFields(ctx)
+	return meta, err
+}
+
+// FetchFields builds the next fake track through the same pipeline as an
+// upstream JSON document, so formats and transforms apply as usual
+func (s *SyntheticProvider) FetchFields(ctx context.Context) (string, map[string]string, error) {
+	n := s.count.Add(1)
+	return s.build.Build(map[string]interface{}{
+		"artist": fmt.Sprintf("Test Artist %d", n),
+		"title":  fmt.Sprintf("Test Track %d", n),
+		"album":  "Synthetic",
+	})
+}
+
+func (s *SyntheticProvider) Render(format string, fields map[string]string) string {
+	return s.build.Render(format, fields)
+}
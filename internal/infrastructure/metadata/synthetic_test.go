@@ -0,0 +1,37 @@
+// ABOUTME: Tests for the synthetic metadata provider
+// ABOUTME: Verifies titles advance per fetch and honour the build format
+package metadata
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSyntheticProvider_Cycles(t *testing.T) {
+	p := NewSynthetic(BuildConfig{})
+
+	first, _ := p.Fetch(context.Background())
+	second, _ := p.Fetch(context.Background())
+
+	if first != "StreamTitle='Test Artist 1 - Test Track 1';" {
+		t.Errorf("unexpected first title %q", first)
+	}
+	if second == first {
+		t.Error("expected the title to change on every fetch")
+	}
+}
+
+func TestSyntheticProvider_Format(t *testing.T) {
+	p := NewSynthetic(BuildConfig{Format: "StreamTitle='{title} ({album})';"})
+
+	meta, fields, err := p.FetchFields(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta != "StreamTitle='Test Track 1 (Synthetic)';" {
+		t.Errorf("unexpected title %q", meta)
+	}
+	if fields["artist"] != "Test Artist 1" {
+		t.Errorf("expected artist field, got %q", fields["artist"])
+	}
+}
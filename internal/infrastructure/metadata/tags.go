@@ -0,0 +1,52 @@
+// ABOUTME: Metadata provider reading tags carried inside the station's own audio
+// ABOUTME: Formats in-band Ogg Vorbis comments through the usual build pipeline
+package metadata
+
+import (
+	"context"
+	"errors"
+)
+
+// TagSource exposes the latest in-band tags with lowercased keys
+type TagSource interface {
+	Tags() map[string]string
+}
+
+// ErrNoTags is returned until the stream has carried a comment header
+var ErrNoTags = errors.New("no in-stream tags yet")
+
+// InStreamProvider formats tags parsed from the station's audio, so Ogg
+// stations need no side-channel metadata API
+type InStreamProvider struct {
+	tags  TagSource
+	build BuildConfig
+}
+
+func NewInStream(tags TagSource, build BuildConfig) *InStreamProvider {
+	if build.Format == "" {
+		build.Format = defaultFormat
+	}
+	return &InStreamProvider{tags: tags, build: build}
+}
+
+func (p *InStreamProvider) Fetch(ctx context.Context) (string, error) {
+	meta, _, err := p.FetchFields(ctx)
+	return meta, err
+}
+
+func (p *InStreamProvider) FetchFields(ctx context.Context) (string, map[string]string, error) {
+	tags := p.tags.Tags()
+	if tags == nil {
+		return "", nil, ErrNoTags
+	}
+
+	data := make(map[string]interface{}, len(tags))
+	for k, v := range tags {
+		data[k] = v
+	}
+	return p.build.Build(data)
+}
+
+func (p *InStreamProvider) Render(format string, fields map[string]string) string {
+	return p.build.Render(format, fields)
+}
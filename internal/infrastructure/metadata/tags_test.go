@@ -0,0 +1,32 @@
+// ABOUTME: Tests for the in-stream tag metadata provider
+// ABOUTME: Verifies tags render through formats and missing tags are an error
+package metadata
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type staticTags map[string]string
+
+func (s staticTags) Tags() map[string]string { return s }
+
+func TestInStreamProvider(t *testing.T) {
+	p := NewInStream(staticTags{"artist": "Nina Simone", "title": "Sinnerman", "date": "1965"}, BuildConfig{
+		Format:           "StreamTitle='{artist} - {title} ({year})';",
+		FallbackKeyOrder: []string{"artist", "title", "album", "artwork", "date"},
+	})
+
+	meta, err := p.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta != "StreamTitle='Nina Simone - Sinnerman (1965)';" {
+		t.Errorf("unexpected metadata %q", meta)
+	}
+
+	if _, err := NewInStream(staticTags(nil), BuildConfig{}).Fetch(context.Background()); !errors.Is(err, ErrNoTags) {
+		t.Errorf("expected ErrNoTags before any tags, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+// ABOUTME: Registry of named metadata transforms for configurable pipelines
+// ABOUTME: Transforms apply in order to the StreamTitle value of rendered metadata
+package metadata
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Transform rewrites a metadata value
+type Transform func(string) string
+
+// TransformFactory builds a transform from its config arguments
+type TransformFactory func(args map[string]string) (Transform, error)
+
+var htmlTagRe = regexp.MustCompile(`<[^>]*>`)
+
+// transforms maps pipeline names to their factories
+var transforms = map[string]TransformFactory{
+	"trim":                 simple(strings.TrimSpace),
+	"strip_html":           simple(stripHTML),
+	"title_case":           simple(titleCase),
+	"strip_single_quotes":  simple(func(s string) string { return strings.ReplaceAll(s, "'", "") }),
+	"normalize_whitespace": simple(func(s string) string { return strings.Join(strings.Fields(s), " ") }),
+	"dedupe_segments":      simple(dedupeSegments),
+	"replace":              newReplace,
+}
+
+func simple(fn Transform) TransformFactory {
+	return func(map[string]string) (Transform, error) {
+		return fn, nil
+	}
+}
+
+// NewTransform looks up a transform by name and configures it with args
+func NewTransform(name string, args map[string]string) (Transform, error) {
+	factory, ok := transforms[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown transform %q", name)
+	}
+	return factory(args)
+}
+
+// newReplace substitutes every occurrence of args["from"] with args["to"]
+func newReplace(args map[string]string) (Transform, error) {
+	from := args["from"]
+	if from == "" {
+		return nil, fmt.Errorf("replace: from is required")
+	}
+	to := args["to"]
+
+	return func(s string) string {
+		return strings.ReplaceAll(s, from, to)
+	}, nil
+}
+
+// applyToTitle runs fn over the value of a StreamTitle='...'; string, or the
+// whole string when it is not in that form
+func applyToTitle(s string, fn Transform) string {
+	const prefix, suffix = "StreamTitle='", "';"
+	if strings.HasPrefix(s, prefix) && strings.HasSuffix(s, suffix) && len(s) >= len(prefix)+len(suffix) {
+		return prefix + fn(s[len(prefix):len(s)-len(suffix)]) + suffix
+	}
+	return fn(s)
+}
+
+func stripHTML(s string) string {
+	return html.UnescapeString(htmlTagRe.ReplaceAllString(s, ""))
+}
+
+// titleCase capitalizes the first letter of each word and lowercases the rest
+func titleCase(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	startOfWord := true
+	for _, r := range s {
+		if startOfWord {
+			b.WriteRune(unicode.ToUpper(r))
+		} else {
+			b.WriteRune(unicode.ToLower(r))
+		}
+		startOfWord = unicode.IsSpace(r) || r == '(' || r == '-' || r == '/'
+	}
+
+	return b.String()
+}
@@ -0,0 +1,62 @@
+// ABOUTME: Tests for the metadata transform registry
+// ABOUTME: Verifies individual transforms and ordered pipelines
+package metadata
+
+import "testing"
+
+func TestNewTransform(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  map[string]string
+		input string
+		want  string
+	}{
+		{"trim", nil, "  Song  ", "Song"},
+		{"strip_html", nil, "<b>Rock &amp; Roll</b>", "Rock & Roll"},
+		{"title_case", nil, "THE ARTIST - the song (live)", "The Artist - The Song (Live)"},
+		{"replace", map[string]string{"from": "feat.", "to": "ft."}, "A feat. B", "A ft. B"},
+		{"normalize_whitespace", nil, "A   B", "A B"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transform, err := NewTransform(tt.name, tt.args)
+			if err != nil {
+				t.Fatalf("NewTransform: %v", err)
+			}
+
+			if got := transform(tt.input); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewTransform_Errors(t *testing.T) {
+	if _, err := NewTransform("shout", nil); err == nil {
+		t.Error("expected error for unknown transform")
+	}
+
+	if _, err := NewTransform("replace", nil); err == nil {
+		t.Error("expected error for replace without from")
+	}
+}
+
+func TestBuildConfig_TransformPipeline(t *testing.T) {
+	var pipeline []Transform
+	for _, name := range []string{"strip_html", "trim", "title_case"} {
+		transform, err := NewTransform(name, nil)
+		if err != nil {
+			t.Fatalf("NewTransform(%s): %v", name, err)
+		}
+		pipeline = append(pipeline, transform)
+	}
+
+	b := BuildConfig{Transforms: pipeline}
+
+	// The StreamTitle wrapper is left alone; only the value is transformed
+	got := b.Render("StreamTitle='{title}';", map[string]string{"title": " <i>night DRIVE</i> "})
+	if got != "StreamTitle='Night Drive';" {
+		t.Errorf("expected transformed title, got %q", got)
+	}
+}
@@ -0,0 +1,73 @@
+// ABOUTME: OpenMetrics text exposition writer
+// ABOUTME: Emits metric families, samples, and histograms without external deps
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the OpenMetrics text format media type
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+type Labels map[string]string
+
+type Writer struct {
+	w io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Family writes the TYPE and HELP lines that precede a metric's samples
+func (mw *Writer) Family(name, typ, help string) {
+	fmt.Fprintf(mw.w, "# TYPE %s %s\n# HELP %s %s\n", name, typ, name, help)
+}
+
+func (mw *Writer) Sample(name string, labels Labels, value float64) {
+	fmt.Fprintf(mw.w, "%s%s %s\n", name, formatLabels(labels, "", ""), formatValue(value))
+}
+
+func (mw *Writer) Histogram(name string, labels Labels, snap HistogramSnapshot) {
+	for i, bound := range snap.Bounds {
+		fmt.Fprintf(mw.w, "%s_bucket%s %d\n", name, formatLabels(labels, "le", formatValue(bound)), snap.Counts[i])
+	}
+	fmt.Fprintf(mw.w, "%s_bucket%s %d\n", name, formatLabels(labels, "le", "+Inf"), snap.Count)
+	fmt.Fprintf(mw.w, "%s_sum%s %s\n", name, formatLabels(labels, "", ""), formatValue(snap.Sum))
+	fmt.Fprintf(mw.w, "%s_count%s %d\n", name, formatLabels(labels, "", ""), snap.Count)
+}
+
+// EOF terminates the exposition as OpenMetrics requires
+func (mw *Writer) EOF() {
+	fmt.Fprint(mw.w, "# EOF\n")
+}
+
+func formatLabels(labels Labels, extraKey, extraValue string) string {
+	if len(labels) == 0 && extraKey == "" {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys)+1)
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, strconv.Quote(labels[k])))
+	}
+	if extraKey != "" {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", extraKey, strconv.Quote(extraValue)))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,67 @@
+// ABOUTME: Lock-free histogram for hot-path observations
+// ABOUTME: Cumulative buckets compatible with Prometheus/OpenMetrics exposition
+package metrics
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+type Histogram struct {
+	bounds []float64       // upper bounds, ascending; +Inf is implicit
+	counts []atomic.Uint64 // per bucket, the last one past every bound
+	sum    atomic.Uint64   // float64 bits
+}
+
+// HistogramSnapshot is a point-in-time copy with cumulative bucket counts
+type HistogramSnapshot struct {
+	Bounds []float64
+	Counts []uint64 // cumulative, one per bound
+	Count  uint64
+	Sum    float64
+}
+
+func NewHistogram(bounds []float64) *Histogram {
+	return &Histogram{
+		bounds: bounds,
+		counts: make([]atomic.Uint64, len(bounds)+1),
+	}
+}
+
+func (h *Histogram) Observe(v float64) {
+	i := 0
+	for i < len(h.bounds) && v > h.bounds[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+
+	for {
+		old := h.sum.Load()
+		next := math.Float64bits(math.Float64frombits(old) + v)
+		if h.sum.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+// Snapshot copies the histogram without blocking observers. Count is the
+// total of the same bucket reads as Counts, so the +Inf bucket never falls
+// below the last bound's even while observations race the snapshot.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	snap := HistogramSnapshot{
+		Bounds: h.bounds,
+		Counts: make([]uint64, len(h.bounds)),
+		Sum:    math.Float64frombits(h.sum.Load()),
+	}
+
+	var cumulative uint64
+	for i := range h.counts {
+		cumulative += h.counts[i].Load()
+		if i < len(snap.Counts) {
+			snap.Counts[i] = cumulative
+		}
+	}
+	snap.Count = cumulative
+
+	return snap
+}
@@ -0,0 +1,94 @@
+// ABOUTME: Tests for histogram and OpenMetrics exposition
+// ABOUTME: Verifies cumulative bucket counts and text output format
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHistogram_Observe(t *testing.T) {
+	h := NewHistogram([]float64{1, 10, 100})
+
+	for _, v := range []float64{0, 1, 5, 50, 500} {
+		h.Observe(v)
+	}
+
+	snap := h.Snapshot()
+
+	want := []uint64{2, 3, 4}
+	for i, c := range snap.Counts {
+		if c != want[i] {
+			t.Errorf("bucket %v: expected %d, got %d", snap.Bounds[i], want[i], c)
+		}
+	}
+
+	if snap.Count != 5 {
+		t.Errorf("expected count 5, got %d", snap.Count)
+	}
+
+	if snap.Sum != 556 {
+		t.Errorf("expected sum 556, got %v", snap.Sum)
+	}
+}
+
+func TestHistogram_SnapshotConsistentUnderLoad(t *testing.T) {
+	h := NewHistogram([]float64{1, 10})
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					h.Observe(5)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 1000; i++ {
+		snap := h.Snapshot()
+		if last := snap.Counts[len(snap.Counts)-1]; snap.Count < last {
+			t.Fatalf("+Inf count %d below last bucket %d", snap.Count, last)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestWriter_Histogram(t *testing.T) {
+	h := NewHistogram([]float64{1, 10})
+	h.Observe(5)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Family("fill", "histogram", "Test fill")
+	w.Histogram("fill", Labels{"station": "fip"}, h.Snapshot())
+	w.EOF()
+
+	out := buf.String()
+	for _, want := range []string{
+		"# TYPE fill histogram",
+		`fill_bucket{station="fip",le="1"} 0`,
+		`fill_bucket{station="fip",le="10"} 1`,
+		`fill_bucket{station="fip",le="+Inf"} 1`,
+		`fill_sum{station="fip"} 5`,
+		`fill_count{station="fip"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	if !strings.HasSuffix(out, "# EOF\n") {
+		t.Error("expected output to end with # EOF")
+	}
+}
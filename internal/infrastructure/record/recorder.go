@@ -0,0 +1,342 @@
+// ABOUTME: On-demand recording of a station's live audio to disk
+// ABOUTME: Rotates files by time/size and writes a JSON Lines sidecar of title changes
+package record
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/harper/radio-metadata-proxy/internal/domain/station"
+)
+
+var (
+	ErrDisabled         = errors.New("recording is not configured")
+	ErrAlreadyRecording = errors.New("station is already recording")
+	ErrNotRecording     = errors.New("station is not recording")
+
+	// ErrStationStopped ends a recording whose station was shut down, such
+	// as one removed or rebuilt by a config reload
+	ErrStationStopped = errors.New("station stopped or reloaded")
+)
+
+// Source is the part of a station the recorder needs
+type Source interface {
+	ID() string
+	ContentType() string
+	CurrentMetadata() string
+	Subscribe(c *station.Client) <-chan []byte
+	Unsubscribe(c *station.Client)
+}
+
+type Config struct {
+	Dir         string        // recordings directory; empty disables recording
+	RotateEvery time.Duration // start a new file after this long (0 = never)
+	RotateBytes int64         // start a new file after this many bytes (0 = never)
+}
+
+// Status describes a recording session
+type Status struct {
+	Station   string    `json:"station"`
+	File      string    `json:"file"`
+	StartedAt time.Time `json:"startedAt"`
+	Bytes     int64     `json:"bytes"` // total across all rotated files
+	Recording bool      `json:"recording"`
+	Error     string    `json:"error,omitempty"` // why a recording ended on its own
+}
+
+// Manager runs at most one recording per station
+type Manager struct {
+	cfg      Config
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+func NewManager(cfg Config) *Manager {
+	return &Manager{cfg: cfg, sessions: make(map[string]*session)}
+}
+
+// Start subscribes to src and begins writing its audio to disk
+func (m *Manager) Start(src Source) (Status, error) {
+	if m.cfg.Dir == "" {
+		return Status{}, ErrDisabled
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if s, ok := m.sessions[src.ID()]; ok && s.running() {
+		return s.status(), ErrAlreadyRecording
+	}
+
+	if err := os.MkdirAll(m.cfg.Dir, 0o755); err != nil {
+		return Status{}, err
+	}
+
+	s := &session{
+		cfg:     m.cfg,
+		src:     src,
+		client:  &station.Client{ID: "recorder-" + src.ID(), Addr: "internal", UserAgent: "icyproxy-recorder", ConnectedAt: time.Now(), Internal: true},
+		started: time.Now().UTC().Truncate(time.Second),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := s.rotate(); err != nil {
+		return Status{}, err
+	}
+
+	ch := src.Subscribe(s.client)
+	go s.run(ch)
+
+	m.sessions[src.ID()] = s
+	log.Printf("station %s: recording to %s", src.ID(), s.status().File)
+	return s.status(), nil
+}
+
+// Stop ends the station's recording and returns its final status
+func (m *Manager) Stop(stationID string) (Status, error) {
+	m.mu.Lock()
+	s, ok := m.sessions[stationID]
+	delete(m.sessions, stationID)
+	m.mu.Unlock()
+
+	if !ok {
+		return Status{}, ErrNotRecording
+	}
+
+	s.halt()
+	log.Printf("station %s: recording stopped", stationID)
+	return s.status(), nil
+}
+
+// StopAll ends every recording, used at shutdown
+func (m *Manager) StopAll() {
+	m.mu.Lock()
+	sessions := m.sessions
+	m.sessions = make(map[string]*session)
+	m.mu.Unlock()
+
+	for _, s := range sessions {
+		s.halt()
+	}
+}
+
+// Status reports the station's current or last failed recording
+func (m *Manager) Status(stationID string) (Status, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[stationID]
+	if !ok {
+		return Status{}, false
+	}
+	return s.status(), true
+}
+
+type session struct {
+	cfg     Config
+	src     Source
+	client  *station.Client
+	started time.Time
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+
+	mu        sync.Mutex
+	audio     *os.File
+	cues      *os.File
+	fileStart time.Time
+	fileBytes int64
+	part      int
+	total     int64
+	lastMeta  string
+	err       error
+}
+
+func (s *session) run(ch <-chan []byte) {
+	defer close(s.done)
+	defer s.close()
+	defer s.src.Unsubscribe(s.client)
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case chunk, ok := <-ch:
+			if !ok {
+				// The station closed its clients; a rebuilt station is a new
+				// subscription the operator has to start again
+				s.fail(ErrStationStopped)
+				return
+			}
+			if err := s.write(chunk); err != nil {
+				// Usually a full disk: keep what was written and stop cleanly
+				s.fail(err)
+				return
+			}
+		}
+	}
+}
+
+// fail records why the recording ended on its own
+func (s *session) fail(err error) {
+	log.Printf("station %s: recording ended: %v", s.src.ID(), err)
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+func (s *session) halt() {
+	s.once.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+func (s *session) running() bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (s *session) write(chunk []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.due() {
+		if err := s.rotateLocked(); err != nil {
+			return err
+		}
+	}
+
+	if meta := s.src.CurrentMetadata(); meta != s.lastMeta {
+		if err := s.cueLocked(meta); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.audio.Write(chunk)
+	s.fileBytes += int64(n)
+	s.total += int64(n)
+	return err
+}
+
+func (s *session) due() bool {
+	if s.cfg.RotateBytes > 0 && s.fileBytes >= s.cfg.RotateBytes {
+		return true
+	}
+	return s.cfg.RotateEvery > 0 && time.Since(s.fileStart) >= s.cfg.RotateEvery
+}
+
+func (s *session) rotate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rotateLocked()
+}
+
+// rotateLocked closes the current files and opens the next audio file and
+// its .cues.jsonl sidecar, repeating the current title at offset 0
+func (s *session) rotateLocked() error {
+	s.closeLocked()
+
+	// Files are named {station}-{session start}-{part} so rotations sort
+	// in order. Sessions restarted within the same second would reuse a
+	// name, so existing files are skipped rather than truncated.
+	now := time.Now().UTC()
+	var audio, cues *os.File
+	for {
+		s.part++
+		base := filepath.Join(s.cfg.Dir, fmt.Sprintf("%s-%s-%03d", s.src.ID(), s.started.Format("20060102T150405Z"), s.part))
+
+		var err error
+		if audio, err = createNew(base + extension(s.src.ContentType())); err != nil {
+			if errors.Is(err, fs.ErrExist) && s.part < maxParts {
+				continue
+			}
+			return err
+		}
+		if cues, err = createNew(base + ".cues.jsonl"); err != nil {
+			audio.Close()
+			os.Remove(audio.Name())
+			if errors.Is(err, fs.ErrExist) && s.part < maxParts {
+				continue
+			}
+			return err
+		}
+		break
+	}
+
+	s.audio, s.cues = audio, cues
+	s.fileStart, s.fileBytes = now, 0
+	return s.cueLocked(s.src.CurrentMetadata())
+}
+
+// maxParts bounds the search for an unused file name
+const maxParts = 999
+
+// createNew creates name for writing, failing if it already exists
+func createNew(name string) (*os.File, error) {
+	return os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+}
+
+// cueLocked appends a metadata marker at the current byte offset
+func (s *session) cueLocked(meta string) error {
+	s.lastMeta = meta
+	line, _ := json.Marshal(struct {
+		Offset   int64     `json:"offset"`
+		At       time.Time `json:"at"`
+		Metadata string    `json:"metadata"`
+	}{s.fileBytes, time.Now().UTC(), meta})
+	_, err := s.cues.Write(append(line, '\n'))
+	return err
+}
+
+func (s *session) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked()
+}
+
+func (s *session) closeLocked() {
+	if s.audio != nil {
+		s.audio.Close()
+	}
+	if s.cues != nil {
+		s.cues.Close()
+	}
+}
+
+func (s *session) status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := Status{Station: s.src.ID(), StartedAt: s.started, Bytes: s.total, Recording: s.running()}
+	if s.audio != nil {
+		st.File = s.audio.Name()
+	}
+	if s.err != nil {
+		st.Error = s.err.Error()
+	}
+	return st
+}
+
+// extension picks a file extension for the station's content type
+func extension(contentType string) string {
+	switch contentType {
+	case "audio/aac", "audio/aacp":
+		return ".aac"
+	case "audio/ogg", "application/ogg":
+		return ".ogg"
+	case "audio/webm":
+		return ".webm"
+	default:
+		return ".mp3"
+	}
+}
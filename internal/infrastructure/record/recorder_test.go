@@ -0,0 +1,203 @@
+// ABOUTME: Tests for on-demand station recording
+// ABOUTME: Verifies audio capture, title cues, rotation, and write failures
+package record
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/harper/radio-metadata-proxy/internal/domain/station"
+)
+
+// fakeSource hands chunks pushed by the test to its single subscriber
+type fakeSource struct {
+	mu   sync.Mutex
+	meta string
+	ch   chan []byte
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{meta: "StreamTitle='One';", ch: make(chan []byte)}
+}
+
+func (f *fakeSource) ID() string          { return "fip" }
+func (f *fakeSource) ContentType() string { return "audio/mpeg" }
+
+func (f *fakeSource) CurrentMetadata() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.meta
+}
+
+func (f *fakeSource) setMeta(m string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.meta = m
+}
+
+func (f *fakeSource) Subscribe(c *station.Client) <-chan []byte { return f.ch }
+func (f *fakeSource) Unsubscribe(c *station.Client)             {}
+
+func TestManager_RecordsAudioAndCues(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(Config{Dir: dir})
+	src := newFakeSource()
+
+	if _, err := m.Start(src); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if _, err := m.Start(src); err != ErrAlreadyRecording {
+		t.Errorf("expected ErrAlreadyRecording, got %v", err)
+	}
+
+	src.ch <- []byte("aaaa")
+	// Let the recorder write the first chunk before the metadata changes
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if status, _ := m.Status("fip"); status.Bytes == 4 {
+			break
+		}
+	}
+	src.setMeta("StreamTitle='Two';")
+	src.ch <- []byte("bb")
+
+	status, err := m.Stop("fip")
+	if err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if status.Bytes != 6 {
+		t.Errorf("expected 6 bytes recorded, got %d", status.Bytes)
+	}
+
+	audio, _ := os.ReadFile(status.File)
+	if string(audio) != "aaaabb" {
+		t.Errorf("unexpected audio %q", audio)
+	}
+
+	cues, _ := os.ReadFile(strings.TrimSuffix(status.File, ".mp3") + ".cues.jsonl")
+	lines := strings.Split(strings.TrimSpace(string(cues)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"offset":4`) || !strings.Contains(lines[1], "Two") {
+		t.Errorf("unexpected cues:\n%s", cues)
+	}
+
+	if _, err := m.Stop("fip"); err != ErrNotRecording {
+		t.Errorf("expected ErrNotRecording, got %v", err)
+	}
+}
+
+func TestManager_RotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(Config{Dir: dir, RotateBytes: 4})
+	src := newFakeSource()
+
+	if _, err := m.Start(src); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	src.ch <- []byte("aaaa")
+	src.ch <- []byte("bbbb")
+	m.Stop("fip")
+
+	files, _ := filepath.Glob(filepath.Join(dir, "fip-*.mp3"))
+	if len(files) != 2 || !strings.HasSuffix(files[1], "-002.mp3") {
+		t.Errorf("expected 2 rotated files, got %v", files)
+	}
+}
+
+func TestManager_RestartKeepsEarlierFiles(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(Config{Dir: dir})
+	src := newFakeSource()
+
+	// Files from a session started in the same second as the next one
+	now := time.Now().UTC().Truncate(time.Second)
+	var existing []string
+	for _, at := range []time.Time{now, now.Add(time.Second)} {
+		name := filepath.Join(dir, "fip-"+at.Format("20060102T150405Z")+"-001.mp3")
+		if err := os.WriteFile(name, []byte("earlier"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		existing = append(existing, name)
+	}
+
+	status, err := m.Start(src)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	src.ch <- []byte("later")
+	m.Stop("fip")
+
+	if !strings.HasSuffix(status.File, "-002.mp3") {
+		t.Errorf("expected the next free part, got %s", status.File)
+	}
+	for _, name := range existing {
+		if data, _ := os.ReadFile(name); string(data) != "earlier" {
+			t.Errorf("expected %s left intact, got %q", name, data)
+		}
+	}
+}
+
+func TestManager_WriteFailureStops(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(Config{Dir: dir})
+	src := newFakeSource()
+
+	status, err := m.Start(src)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	// Simulate a full disk by closing the file under the recorder
+	m.mu.Lock()
+	s := m.sessions["fip"]
+	m.mu.Unlock()
+	s.mu.Lock()
+	s.audio.Close()
+	s.mu.Unlock()
+
+	src.ch <- []byte("data")
+	<-s.done
+
+	status, _ = m.Status("fip")
+	if status.Error == "" {
+		t.Error("expected write error in status")
+	}
+
+	// A failed session can be restarted
+	if _, err := m.Start(src); err != nil {
+		t.Errorf("expected restart after failure, got %v", err)
+	}
+	m.StopAll()
+}
+
+func TestManager_StationStopEndsRecording(t *testing.T) {
+	m := NewManager(Config{Dir: t.TempDir()})
+	src := newFakeSource()
+
+	status, err := m.Start(src)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if !status.Recording {
+		t.Error("expected a running recording")
+	}
+
+	// A station shutting down closes its clients' channels
+	close(src.ch)
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if status, _ = m.Status("fip"); !status.Recording {
+			break
+		}
+	}
+	if status.Recording || status.Error != ErrStationStopped.Error() {
+		t.Errorf("expected the stop reported in status, got %+v", status)
+	}
+}
+
+func TestManager_Disabled(t *testing.T) {
+	if _, err := NewManager(Config{}).Start(newFakeSource()); err != ErrDisabled {
+		t.Errorf("expected ErrDisabled, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+// ABOUTME: Masks credentials in URLs before they are shown or logged
+// ABOUTME: Shared by the effective-config view and source status reporting
+package redact
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Mask replaces a URL that can't be parsed, since its secrets can't be found
+const Mask = "[redacted]"
+
+// secret replaces passwords and query values inside a URL
+const secret = "xxxxx"
+
+// URL masks everything in raw that may carry a credential: the password
+// (or, without one, the user name) and every query value. The scheme,
+// host, path and query keys are kept so the URL is still recognizable.
+// A URL that doesn't parse is replaced by Mask.
+func URL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Mask
+	}
+
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), secret)
+		} else {
+			// A lone user name is often a token, as in https://TOKEN@host/
+			u.User = url.User(secret)
+		}
+	}
+
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			for i := range q[k] {
+				q[k][i] = secret
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	if u.Fragment != "" {
+		u.Fragment = secret
+	}
+
+	return u.String()
+}
+
+// Error returns err's message with the URL of any *url.Error in its chain
+// redacted, since the net/http client quotes the full request URL
+func Error(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	msg := err.Error()
+	for e := err; e != nil; {
+		var urlErr *url.Error
+		if !errors.As(e, &urlErr) {
+			break
+		}
+		if urlErr.URL != "" {
+			// url.Error quotes the URL with %q, escaping some characters
+			safe := URL(urlErr.URL)
+			msg = strings.ReplaceAll(msg, strconv.Quote(urlErr.URL), strconv.Quote(safe))
+			msg = strings.ReplaceAll(msg, urlErr.URL, safe)
+		}
+		e = urlErr.Err
+	}
+	return msg
+}
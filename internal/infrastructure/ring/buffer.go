@@ -5,10 +5,11 @@ package ring
 import "sync"
 
 type Buffer struct {
-	buf []byte
-	w   int  // write position
-	n   int  // bytes stored
-	mu  sync.Mutex
+	buf     []byte
+	w       int   // write position
+	n       int   // bytes stored
+	written int64 // bytes ever written; the stored ones end here
+	mu      sync.Mutex
 }
 
 func New(size int) *Buffer {
@@ -19,38 +20,78 @@ func (b *Buffer) Write(p []byte) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	for len(p) > 0 {
-		space := len(b.buf) - b.n
-		if space == 0 {
-			// Drop oldest 25%
-			drop := len(b.buf) / 4
-			b.w = (b.w + drop) % len(b.buf)
-			b.n -= drop
-			if b.n < 0 {
-				b.n = 0
-			}
-			space = len(b.buf) - b.n
-		}
+	size := len(b.buf)
+	if size == 0 {
+		return
+	}
+	b.written += int64(len(p))
 
-		chunk := len(p)
-		if chunk > space {
-			chunk = space
-		}
+	// A write at least as large as the buffer replaces it with its own tail
+	if len(p) >= size {
+		copy(b.buf, p[len(p)-size:])
+		b.w = 0
+		b.n = size
+		return
+	}
 
-		end := (b.w + b.n) % len(b.buf)
-		right := len(b.buf) - end
-		if right > chunk {
-			right = chunk
+	// Drop oldest data in one step: at least 25% so steady writes don't
+	// trim on every call, and at least enough to fit p
+	if overflow := b.n + len(p) - size; overflow > 0 {
+		drop := max(size/4, overflow)
+		if drop > b.n {
+			drop = b.n
 		}
+		b.w = (b.w + drop) % size
+		b.n -= drop
+	}
 
-		copy(b.buf[end:end+right], p[:right])
-		if right < chunk {
-			copy(b.buf[0:chunk-right], p[right:chunk])
-		}
+	end := (b.w + b.n) % size
+	right := copy(b.buf[end:], p)
+	copy(b.buf, p[right:])
+	b.n += len(p)
+}
+
+// Reset discards all buffered data
+func (b *Buffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.w, b.n = 0, 0
+}
 
-		b.n += chunk
-		p = p[chunk:]
+// Written returns the number of bytes ever written, the stream position
+// where the next write will start
+func (b *Buffer) Written() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.written
+}
+
+// Tail copies up to max bytes ending at stream position end, as returned by
+// Written, leaving out any that have since been dropped. Later writes don't
+// affect the result, so callers can take the position under their own lock
+// and copy after releasing it.
+func (b *Buffer) Tail(end int64, max int) []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	oldest := b.written - int64(b.n)
+	lo := end - int64(max)
+	if lo < oldest {
+		lo = oldest
+	}
+	if end > b.written {
+		end = b.written
+	}
+	if end <= lo {
+		return nil
 	}
+
+	out := make([]byte, end-lo)
+	start := (b.w + int(lo-oldest)) % len(b.buf)
+	right := copy(out, b.buf[start:])
+	copy(out[right:], b.buf)
+	return out
 }
 
 func (b *Buffer) Snapshot() []byte {
@@ -57,3 +57,83 @@ func TestWrite_Overflow(t *testing.T) {
 		}
 	}
 }
+
+func TestWrite_LargerThanBuffer(t *testing.T) {
+	buf := New(100)
+	buf.Write([]byte("prefix"))
+
+	data := make([]byte, 8192)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	buf.Write(data)
+
+	snap := buf.Snapshot()
+	if len(snap) != 100 {
+		t.Fatalf("expected 100 bytes, got %d", len(snap))
+	}
+	for i, v := range snap {
+		if expected := data[len(data)-100+i]; v != expected {
+			t.Fatalf("byte %d: expected %d, got %d", i, expected, v)
+		}
+	}
+
+	// Subsequent small writes keep wrapping correctly after the reset
+	buf.Write([]byte("xyz"))
+	snap = buf.Snapshot()
+	if string(snap[len(snap)-3:]) != "xyz" {
+		t.Errorf("expected snapshot to end with xyz, got %q", snap[len(snap)-3:])
+	}
+}
+
+func TestWrite_WrapAround(t *testing.T) {
+	buf := New(10)
+	buf.Write([]byte("abcdefgh"))
+	buf.Write([]byte("ijkl"))
+
+	// 25% drop would free only 2 bytes; the overflow of 2 fits exactly
+	if snap := string(buf.Snapshot()); snap != "cdefghijkl" {
+		t.Errorf("expected cdefghijkl, got %q", snap)
+	}
+}
+
+func TestReset(t *testing.T) {
+	buf := New(16)
+	buf.Write([]byte("old audio"))
+	buf.Reset()
+
+	if got := buf.Snapshot(); len(got) != 0 {
+		t.Errorf("expected empty buffer after reset, got %q", got)
+	}
+
+	buf.Write([]byte("new"))
+	if got := string(buf.Snapshot()); got != "new" {
+		t.Errorf("expected %q, got %q", "new", got)
+	}
+}
+
+func TestTail(t *testing.T) {
+	buf := New(10)
+	buf.Write([]byte("abcdefgh"))
+	end := buf.Written()
+
+	// Writes after the position was taken are left out, wrapped or not
+	buf.Write([]byte("ij"))
+	if got := string(buf.Tail(end, 4)); got != "efgh" {
+		t.Errorf("expected efgh, got %q", got)
+	}
+	buf.Write([]byte("kl"))
+	if got := string(buf.Tail(end, 100)); got != "cdefgh" {
+		t.Errorf("expected what is still buffered, cdefgh, got %q", got)
+	}
+
+	// Wrapped data comes back in order
+	if got := string(buf.Tail(buf.Written(), 5)); got != "hijkl" {
+		t.Errorf("expected hijkl, got %q", got)
+	}
+
+	buf.Reset()
+	if got := buf.Tail(buf.Written(), 4); got != nil {
+		t.Errorf("expected nothing after reset, got %q", got)
+	}
+}
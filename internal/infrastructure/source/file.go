@@ -0,0 +1,94 @@
+// ABOUTME: Local file and FIFO stream source for development and automation pipes
+// ABOUTME: Loops regular files; the station paces reads to the bitrate
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+type FileConfig struct {
+	Path string
+}
+
+// FileSource reads audio from a local path. Regular files loop forever;
+// FIFOs and other special files end on EOF and are reopened by the
+// station's reconnect loop.
+type FileSource struct {
+	cfg FileConfig
+}
+
+func NewFile(cfg FileConfig) *FileSource {
+	return &FileSource{cfg: cfg}
+}
+
+func (f *FileSource) Connect(ctx context.Context) (io.ReadCloser, error) {
+	info, err := os.Stat(f.cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("stat source: %w", err)
+	}
+
+	// Opening a FIFO blocks until a writer appears, so don't hold up
+	// cancellation waiting for it
+	type result struct {
+		file *os.File
+		err  error
+	}
+	opened := make(chan result, 1)
+	go func() {
+		file, err := os.Open(f.cfg.Path)
+		opened <- result{file, err}
+	}()
+
+	var file *os.File
+	select {
+	case <-ctx.Done():
+		go func() {
+			if r := <-opened; r.file != nil {
+				r.file.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	case r := <-opened:
+		if r.err != nil {
+			return nil, fmt.Errorf("open source: %w", r.err)
+		}
+		file = r.file
+	}
+
+	return &fileReader{
+		file: file,
+		loop: info.Mode().IsRegular(),
+	}, nil
+}
+
+type fileReader struct {
+	file *os.File
+	loop bool
+
+	readSinceRewind bool
+}
+
+func (r *fileReader) Read(p []byte) (int, error) {
+	n, err := r.file.Read(p)
+	if n > 0 {
+		r.readSinceRewind = true
+	}
+
+	if err == io.EOF && r.loop && r.readSinceRewind {
+		// Rewind for the next read; an empty file still ends the stream
+		if _, serr := r.file.Seek(0, io.SeekStart); serr != nil {
+			return n, fmt.Errorf("rewind source: %w", serr)
+		}
+		r.readSinceRewind = false
+		err = nil
+	}
+
+	return n, err
+}
+
+func (r *fileReader) Close() error {
+	return r.file.Close()
+}
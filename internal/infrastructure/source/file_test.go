@@ -0,0 +1,58 @@
+// ABOUTME: Tests for the local file stream source
+// ABOUTME: Verifies looping and missing-file errors
+package source
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSource_Loops(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loop.mp3")
+	if err := os.WriteFile(path, []byte("abc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewFile(FileConfig{Path: path}).Connect(context.Background())
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer reader.Close()
+
+	buf := make([]byte, 9)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	if !bytes.Equal(buf, []byte("abcabcabc")) {
+		t.Errorf("expected looped content, got %q", buf)
+	}
+}
+
+func TestFileSource_EmptyFileEnds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.mp3")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewFile(FileConfig{Path: path}).Connect(context.Background())
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer reader.Close()
+
+	if _, err := reader.Read(make([]byte, 16)); err != io.EOF {
+		t.Errorf("expected EOF from empty file, got %v", err)
+	}
+}
+
+func TestFileSource_Missing(t *testing.T) {
+	_, err := NewFile(FileConfig{Path: "/nonexistent/stream.mp3"}).Connect(context.Background())
+	if err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -4,26 +4,96 @@ package source
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"slices"
+	"sync"
 	"time"
+
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/expand"
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/redact"
 )
 
 type HTTPConfig struct {
 	URL            string
-	ConnectTimeout time.Duration
-	ReadTimeout    time.Duration
+	Mirrors        []Mirror      // equivalent upstreams picked by weight on each connect; replaces URL
+	Username       string        // Basic auth; takes precedence over user:pass@ in the URL
+	Password       string        // may use {env:NAME}
+	ConnectTimeout time.Duration // TCP and TLS setup (0 = none)
+	ReadTimeout    time.Duration // wait for the response headers once the request is sent (0 = none)
 	Headers        map[string]string
+
+	Token       TokenSource // optional rotating credential, fetched before each connect
+	TokenHeader string      // header carrying the token, default Authorization
+	TokenPrefix string      // prepended to the token, e.g. "Bearer "
+}
+
+// TokenError reports that the source credential couldn't be refreshed
+type TokenError struct {
+	Err error
+}
+
+func (e *TokenError) Error() string {
+	return fmt.Sprintf("token refresh: %v", e.Err)
+}
+
+func (e *TokenError) Unwrap() error {
+	return e.Err
+}
+
+// Category names the failure without the token endpoint's error text
+func (e *TokenError) Category() string {
+	return "token refresh failed"
+}
+
+// Mirror is one upstream URL. Weight is its relative share of connections;
+// a mirror with weight 0 is never picked.
+type Mirror struct {
+	URL    string
+	Weight int
 }
 
 type HTTPSource struct {
 	cfg    HTTPConfig
 	client *http.Client
+
+	mu         sync.Mutex
+	mirrors    []Mirror
+	current    []int // smooth weighted round-robin state, one per mirror
+	active     int   // index into mirrors, -1 before the first connect
+	lastStatus int
+}
+
+// StatusError reports a non-200 upstream response
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status: %d", e.Code)
+}
+
+// Category names the failure by status alone, e.g. "http 503"
+func (e *StatusError) Category() string {
+	return fmt.Sprintf("http %d", e.Code)
+}
+
+// Permanent reports whether retrying soon is pointless: 4xx responses other
+// than 408 and 429 won't change until the upstream or config does
+func (e *StatusError) Permanent() bool {
+	return e.Code >= 400 && e.Code < 500 &&
+		e.Code != http.StatusRequestTimeout && e.Code != http.StatusTooManyRequests
 }
 
 func NewHTTP(cfg HTTPConfig) *HTTPSource {
+	dialer := &net.Dialer{Timeout: cfg.ConnectTimeout, KeepAlive: 30 * time.Second}
 	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   cfg.ConnectTimeout,
+		ResponseHeaderTimeout: cfg.ReadTimeout,
 		DisableCompression:    true,
 		ExpectContinueTimeout: 1 * time.Second,
 	}
@@ -33,14 +103,145 @@ func NewHTTP(cfg HTTPConfig) *HTTPSource {
 		Timeout:   0, // No total timeout for streaming
 	}
 
+	mirrors := slices.Clone(cfg.Mirrors)
+	if len(mirrors) == 0 {
+		mirrors = []Mirror{{URL: cfg.URL, Weight: 1}}
+	}
+
 	return &HTTPSource{
-		cfg:    cfg,
-		client: client,
+		cfg:     cfg,
+		client:  client,
+		mirrors: mirrors,
+		current: make([]int, len(mirrors)),
+		active:  -1,
 	}
 }
 
+// next picks the mirror for a new connection. Smooth weighted round-robin
+// interleaves picks, so reconnects spread across mirrors in proportion to
+// their weights instead of bunching on the heaviest one. Some mirror must
+// have a positive weight.
+func (h *HTTPSource) next() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	total, best := 0, -1
+	for i, m := range h.mirrors {
+		if m.Weight <= 0 {
+			continue
+		}
+		h.current[i] += m.Weight
+		total += m.Weight
+		if best < 0 || h.current[i] > h.current[best] {
+			best = i
+		}
+	}
+	h.current[best] -= total
+
+	h.active = best
+	return h.mirrors[best].URL
+}
+
+// ActiveMirror returns the index of the mirror used by the latest
+// connection attempt, or -1 before the first attempt
+func (h *HTTPSource) ActiveMirror() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.active
+}
+
+// ActiveURL returns the mirror of the latest connection attempt with its
+// credentials and query values redacted, or "" before the first attempt
+func (h *HTTPSource) ActiveURL() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.active < 0 {
+		return ""
+	}
+	return redact.URL(h.mirrors[h.active].URL)
+}
+
+// LastStatus returns the HTTP status of the latest response, or 0 before
+// any response or after a transport error
+func (h *HTTPSource) LastStatus() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lastStatus
+}
+
+func (h *HTTPSource) setStatus(code int) {
+	h.mu.Lock()
+	h.lastStatus = code
+	h.mu.Unlock()
+}
+
+// Connect opens the stream from the next mirror. A mirror that rejects the
+// request (a permanent 4xx) is skipped for the others in the rotation, so
+// the error only reports a rejection once every mirror has rejected.
 func (h *HTTPSource) Connect(ctx context.Context) (io.ReadCloser, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", h.cfg.URL, nil)
+	total := 0
+	for _, m := range h.mirrors {
+		total += max(m.Weight, 0)
+	}
+	if total == 0 {
+		return nil, errors.New("no mirror has a positive weight")
+	}
+
+	// One full round of weighted picks visits every mirror
+	tried := make(map[string]bool, len(h.mirrors))
+	var rejected error
+	for range total {
+		target := h.next()
+		if tried[target] {
+			continue
+		}
+		tried[target] = true
+
+		body, err := h.connect(ctx, target)
+		var status *StatusError
+		if err == nil || !errors.As(err, &status) || !status.Permanent() || ctx.Err() != nil {
+			return body, err
+		}
+		rejected = err
+	}
+
+	if len(tried) > 1 {
+		return nil, fmt.Errorf("all %d mirrors rejected: %w", len(tried), rejected)
+	}
+	return nil, rejected
+}
+
+// connect opens the stream from one mirror
+func (h *HTTPSource) connect(ctx context.Context, target string) (io.ReadCloser, error) {
+	resp, err := h.do(ctx, target)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && h.cfg.Token != nil {
+		// The token may have been revoked before its refresh interval ran
+		// out, so fetch a fresh one and try once more
+		resp.Body.Close()
+		h.cfg.Token.Invalidate()
+		resp, err = h.do(ctx, target)
+	}
+	if err != nil {
+		var tokenErr *TokenError
+		if !errors.As(err, &tokenErr) {
+			h.setStatus(0)
+		}
+		return nil, err
+	}
+	h.setStatus(resp.StatusCode)
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &StatusError{Code: resp.StatusCode}
+	}
+
+	return resp.Body, nil
+}
+
+// do sends one source request to target
+func (h *HTTPSource) do(ctx context.Context, target string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -48,20 +249,31 @@ func (h *HTTPSource) Connect(ctx context.Context) (io.ReadCloser, error) {
 	// Set ICY headers
 	req.Header.Set("Icy-MetaData", "0")
 
-	// Set custom headers
-	for k, v := range h.cfg.Headers {
+	if h.cfg.Username != "" || h.cfg.Password != "" {
+		req.SetBasicAuth(h.cfg.Username, expand.String(h.cfg.Password, nil))
+	}
+
+	// Set custom headers; the connection is shared by all listeners so only
+	// static and {env:NAME} values can be expanded here
+	for k, v := range expand.Headers(h.cfg.Headers, nil) {
 		req.Header.Set(k, v)
 	}
 
+	if h.cfg.Token != nil {
+		token, err := h.cfg.Token.Token(ctx)
+		if err != nil {
+			return nil, &TokenError{Err: err}
+		}
+		header := h.cfg.TokenHeader
+		if header == "" {
+			header = "Authorization"
+		}
+		req.Header.Set(header, h.cfg.TokenPrefix+token)
+	}
+
 	resp, err := h.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("http request: %w", err)
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		resp.Body.Close()
-		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
-	}
-
-	return resp.Body, nil
+	return resp, nil
 }
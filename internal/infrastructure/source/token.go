@@ -0,0 +1,136 @@
+// ABOUTME: Short-lived credentials for upstreams that rotate their API keys
+// ABOUTME: HTTPToken fetches a token from an endpoint and caches it until refresh
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/expand"
+)
+
+// TokenSource supplies a credential that HTTPSource injects before each connect
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+	// Invalidate drops the cached token, e.g. after the upstream answers 401
+	Invalidate()
+}
+
+// maxTokenBytes bounds a token endpoint response
+const maxTokenBytes = 64 * 1024
+
+type HTTPTokenConfig struct {
+	URL     string
+	Headers map[string]string // sent to the token endpoint; may use {env:NAME}
+	Field   string            // dotted JSON path of the token; empty uses the whole body
+	Refresh time.Duration     // cached tokens older than this are fetched again
+	Timeout time.Duration
+}
+
+// HTTPToken fetches tokens from an HTTP endpoint, refreshing lazily once
+// the cached one is older than Refresh
+type HTTPToken struct {
+	cfg    HTTPTokenConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	fetched time.Time
+	now     func() time.Time
+}
+
+func NewHTTPToken(cfg HTTPTokenConfig) *HTTPToken {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return &HTTPToken{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		now:    time.Now,
+	}
+}
+
+// Token returns the cached token, fetching a new one when it has expired.
+// The mutex is held across the fetch so concurrent callers share it.
+func (t *HTTPToken) Token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && t.now().Sub(t.fetched) < t.cfg.Refresh {
+		return t.token, nil
+	}
+
+	token, err := t.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	t.token, t.fetched = token, t.now()
+	return token, nil
+}
+
+func (t *HTTPToken) Invalidate() {
+	t.mu.Lock()
+	t.token = ""
+	t.mu.Unlock()
+}
+
+func (t *HTTPToken) fetch(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", t.cfg.URL, nil)
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	for k, v := range expand.Headers(t.cfg.Headers, nil) {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTokenBytes))
+	if err != nil {
+		return "", fmt.Errorf("read body: %w", err)
+	}
+
+	token := strings.TrimSpace(string(body))
+	if t.cfg.Field != "" {
+		if token, err = jsonField(body, t.cfg.Field); err != nil {
+			return "", err
+		}
+	}
+	if token == "" {
+		return "", fmt.Errorf("empty token")
+	}
+	return token, nil
+}
+
+// jsonField returns the string at a dotted path such as "data.access_token"
+func jsonField(body []byte, path string) (string, error) {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return "", fmt.Errorf("decode token response: %w", err)
+	}
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("token field %s not found", path)
+		}
+		v = obj[key]
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("token field %s is not a string", path)
+	}
+	return s, nil
+}
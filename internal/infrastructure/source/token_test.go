@@ -0,0 +1,151 @@
+// ABOUTME: Tests for rotating source credentials
+// ABOUTME: Verifies token caching, refresh, JSON extraction and 401 retries
+package source
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPToken_CachesUntilRefresh(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Client") != "proxy" {
+			t.Errorf("token endpoint missing X-Client header")
+		}
+		n := fetches.Add(1)
+		w.Write([]byte(`{"data": {"access_token": "tok` + strconv.Itoa(int(n)) + `"}}`))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	tok := NewHTTPToken(HTTPTokenConfig{
+		URL:     server.URL,
+		Headers: map[string]string{"X-Client": "proxy"},
+		Field:   "data.access_token",
+		Refresh: time.Minute,
+	})
+	tok.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if got, err := tok.Token(context.Background()); err != nil || got != "tok1" {
+			t.Fatalf("Token = %q, %v; want tok1", got, err)
+		}
+	}
+
+	now = now.Add(2 * time.Minute)
+	if got, _ := tok.Token(context.Background()); got != "tok2" {
+		t.Errorf("expected refresh after interval, got %q", got)
+	}
+
+	tok.Invalidate()
+	if got, _ := tok.Token(context.Background()); got != "tok3" {
+		t.Errorf("expected refresh after Invalidate, got %q", got)
+	}
+}
+
+func TestHTTPToken_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		field  string
+	}{
+		{"status", http.StatusInternalServerError, "tok", ""},
+		{"empty", http.StatusOK, "  \n", ""},
+		{"missing field", http.StatusOK, `{"other": "x"}`, "token"},
+		{"not json", http.StatusOK, "tok", "token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			tok := NewHTTPToken(HTTPTokenConfig{URL: server.URL, Field: tt.field, Refresh: time.Minute})
+			if _, err := tok.Token(context.Background()); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+// staticToken hands out numbered tokens, counting how often it was invalidated
+type staticToken struct {
+	gen         atomic.Int32
+	invalidated atomic.Int32
+	err         error
+}
+
+func (s *staticToken) Token(ctx context.Context) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	return "t" + strconv.Itoa(int(s.gen.Load())), nil
+}
+
+func (s *staticToken) Invalidate() {
+	s.invalidated.Add(1)
+	s.gen.Add(1)
+}
+
+func TestHTTPSource_TokenHeaderAndRetryOn401(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "Key t1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("audio"))
+	}))
+	defer server.Close()
+
+	tok := &staticToken{}
+	src := NewHTTP(HTTPConfig{URL: server.URL, Token: tok, TokenHeader: "X-Api-Key", TokenPrefix: "Key "})
+
+	reader, err := src.Connect(context.Background())
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	reader.Close()
+	if tok.invalidated.Load() != 1 {
+		t.Errorf("expected one invalidation, got %d", tok.invalidated.Load())
+	}
+
+	// A token that is still rejected after refreshing surfaces the 401
+	tok.gen.Store(5)
+	_, err = src.Connect(context.Background())
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 StatusError, got %v", err)
+	}
+}
+
+func TestHTTPSource_TokenError(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	src := NewHTTP(HTTPConfig{URL: server.URL, Token: &staticToken{err: errors.New("endpoint down")}})
+	_, err := src.Connect(context.Background())
+
+	var tokenErr *TokenError
+	if !errors.As(err, &tokenErr) {
+		t.Fatalf("expected TokenError, got %v", err)
+	}
+	if err.Error() != "token refresh: endpoint down" {
+		t.Errorf("unexpected message %q", err)
+	}
+	if requests.Load() != 0 {
+		t.Error("source must not be contacted without a token")
+	}
+}
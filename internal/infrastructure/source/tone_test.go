@@ -0,0 +1,60 @@
+// ABOUTME: Tests for the synthetic tone source
+// ABOUTME: Verifies frame validity, real-time pacing and bitrate validation
+package source
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/harper/radio-metadata-proxy/internal/infrastructure/audio"
+)
+
+func TestToneSource_RealTimeFrames(t *testing.T) {
+	src, err := NewTone(ToneConfig{BitrateKbps: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := src.Connect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	// Five 96-byte frames take four frame durations after the first
+	start := time.Now()
+	buf := make([]byte, 5*96)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 4*audio.SilentFrameDuration {
+		t.Errorf("expected real-time pacing, read 5 frames in %s", elapsed)
+	}
+
+	for i := 0; i < len(buf); i += 96 {
+		if h, ok := audio.ParseFrameHeader(buf[i:]); !ok || h.Length != 96 {
+			t.Fatalf("expected a 96-byte frame at offset %d", i)
+		}
+	}
+}
+
+func TestToneSource_CloseUnblocksRead(t *testing.T) {
+	src, _ := NewTone(ToneConfig{BitrateKbps: 32})
+	reader, _ := src.Connect(context.Background())
+
+	buf := make([]byte, 96)
+	reader.Read(buf)
+	reader.Close()
+
+	if _, err := reader.Read(buf); err == nil {
+		t.Error("expected an error reading after close")
+	}
+}
+
+func TestToneSource_InvalidBitrate(t *testing.T) {
+	if _, err := NewTone(ToneConfig{BitrateKbps: 100}); err == nil {
+		t.Error("expected error for a non-MP3 bitrate")
+	}
+}
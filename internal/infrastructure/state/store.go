@@ -0,0 +1,74 @@
+// ABOUTME: On-disk store of each station's last-known metadata
+// ABOUTME: Lets /meta show the previous title right after a restart
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Entry is one station's last fetched metadata
+type Entry struct {
+	Metadata  string            `json:"metadata"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+type file struct {
+	Stations map[string]Entry `json:"stations"`
+}
+
+// Store reads and writes a JSON state file keyed by station ID
+type Store struct {
+	path string
+}
+
+func New(path string) *Store {
+	return &Store{path: path}
+}
+
+// Load returns the saved entries; a missing file is not an error and yields none
+func (s *Store) Load() (map[string]Entry, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", s.path, err)
+	}
+	return f.Stations, nil
+}
+
+// Save replaces the state file with entries. It writes a temporary file and
+// renames it into place, so a crash mid-write never leaves a truncated file.
+func (s *Store) Save(entries map[string]Entry) error {
+	data, err := json.MarshalIndent(file{Stations: entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
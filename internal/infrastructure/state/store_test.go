@@ -0,0 +1,62 @@
+// ABOUTME: Tests for the on-disk metadata state store
+// ABOUTME: Verifies round trips and tolerance of missing or corrupt files
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store := New(path)
+
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	want := map[string]Entry{
+		"fip": {Metadata: "StreamTitle='A - B';", Fields: map[string]string{"artist": "A"}, UpdatedAt: updated},
+	}
+	if err := store.Save(want); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	got, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	e := got["fip"]
+	if e.Metadata != want["fip"].Metadata || e.Fields["artist"] != "A" || !e.UpdatedAt.Equal(updated) {
+		t.Errorf("got %+v", e)
+	}
+
+	// No temporary files are left behind
+	files, _ := os.ReadDir(filepath.Dir(path))
+	if len(files) != 1 {
+		t.Errorf("expected only the state file, got %d files", len(files))
+	}
+}
+
+func TestStore_Missing(t *testing.T) {
+	got, err := New(filepath.Join(t.TempDir(), "none.json")).Load()
+	if err != nil || got != nil {
+		t.Errorf("expected no entries and no error, got %v, %v", got, err)
+	}
+}
+
+func TestStore_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	os.WriteFile(path, []byte("{not json"), 0o644)
+
+	if _, err := New(path).Load(); err == nil {
+		t.Error("expected error for a corrupt file")
+	}
+
+	// Saving over a corrupt file recovers it
+	if err := New(path).Save(map[string]Entry{}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := New(path).Load(); err != nil {
+		t.Errorf("expected a readable file after Save, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+// ABOUTME: ffmpeg-backed transcoder for per-client bitrate reduction
+// ABOUTME: Re-encodes MP3 station audio to a lower MP3 bitrate via an external process
+package transcode
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+)
+
+type FFmpegConfig struct {
+	Path         string        // ffmpeg binary, defaults to "ffmpeg" on $PATH
+	Codec        string        // output audio codec, defaults to "libmp3lame"
+	MaxRestarts  int           // restarts allowed per client after ffmpeg crashes (0 = none)
+	RestartDelay time.Duration // pause before restarting a crashed ffmpeg
+}
+
+type FFmpeg struct {
+	cfg FFmpegConfig
+}
+
+func NewFFmpeg(cfg FFmpegConfig) *FFmpeg {
+	if cfg.Path == "" {
+		cfg.Path = "ffmpeg"
+	}
+	if cfg.Codec == "" {
+		cfg.Codec = "libmp3lame"
+	}
+	if cfg.RestartDelay == 0 {
+		cfg.RestartDelay = 500 * time.Millisecond
+	}
+	return &FFmpeg{cfg: cfg}
+}
+
+// Check verifies the ffmpeg binary can be found so misconfiguration is
+// reported at startup rather than on the first transcoded request. The
+// resolved path is kept, so requests don't search $PATH again.
+func (f *FFmpeg) Check() error {
+	path, err := exec.LookPath(f.cfg.Path)
+	if err != nil {
+		return fmt.Errorf("ffmpeg not found at %q: %w", f.cfg.Path, err)
+	}
+	f.cfg.Path = path
+	return nil
+}
+
+// Transcode starts a supervised ffmpeg pipeline reading MP3 from in and
+// returns its re-encoded output. The pipeline stops when ctx is cancelled,
+// in is exhausted, or the returned reader is closed.
+func (f *FFmpeg) Transcode(ctx context.Context, in io.Reader, bitrateKbps int) (io.ReadCloser, error) {
+	p := NewPipeline(f, bitrateKbps)
+	return p.Start(ctx, in), nil
+}
+
+func (f *FFmpeg) args(bitrateKbps int) []string {
+	return []string{
+		"-hide_banner",
+		"-loglevel", "error",
+		"-f", "mp3",
+		"-i", "pipe:0",
+		"-vn",
+		"-c:a", f.cfg.Codec,
+		"-b:a", fmt.Sprintf("%dk", bitrateKbps),
+		"-f", "mp3",
+		"pipe:1",
+	}
+}
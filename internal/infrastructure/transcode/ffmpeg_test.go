@@ -0,0 +1,32 @@
+// ABOUTME: Tests for ffmpeg transcoder
+// ABOUTME: Verifies command construction and defaults
+package transcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewFFmpeg_Defaults(t *testing.T) {
+	f := NewFFmpeg(FFmpegConfig{})
+
+	if f.cfg.Path != "ffmpeg" {
+		t.Errorf("expected default path ffmpeg, got %q", f.cfg.Path)
+	}
+
+	if f.cfg.Codec != "libmp3lame" {
+		t.Errorf("expected default codec libmp3lame, got %q", f.cfg.Codec)
+	}
+}
+
+func TestFFmpeg_Args(t *testing.T) {
+	f := NewFFmpeg(FFmpegConfig{})
+
+	args := strings.Join(f.args(64), " ")
+
+	for _, want := range []string{"-i pipe:0", "-c:a libmp3lame", "-b:a 64k", "-f mp3 pipe:1"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("expected args to contain %q, got %q", want, args)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// ABOUTME: Supervised ffmpeg process pipeline for a single transcoded client
+// ABOUTME: Feeds station audio to ffmpeg stdin, restarts on crash, stops on disconnect
+package transcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"time"
+)
+
+var errInputDone = errors.New("input exhausted")
+
+type Pipeline struct {
+	ffmpeg  *FFmpeg
+	bitrate int
+	pending []byte // input a crashed ffmpeg didn't take, fed to the next one first
+}
+
+func NewPipeline(f *FFmpeg, bitrateKbps int) *Pipeline {
+	return &Pipeline{ffmpeg: f, bitrate: bitrateKbps}
+}
+
+// Start launches ffmpeg and returns a reader of its encoded output. If ffmpeg
+// exits while input is still flowing it is restarted, up to MaxRestarts times.
+func (p *Pipeline) Start(ctx context.Context, in io.Reader) io.ReadCloser {
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+
+	feed := make(chan []byte, 16)
+	go feedChunks(ctx, in, feed)
+
+	go func() {
+		defer cancel()
+		pw.CloseWithError(p.supervise(ctx, feed, pw))
+	}()
+
+	return &pipelineReader{PipeReader: pr, cancel: cancel}
+}
+
+func (p *Pipeline) supervise(ctx context.Context, feed <-chan []byte, out io.Writer) error {
+	for restarts := 0; ; restarts++ {
+		err := p.runOnce(ctx, feed, out)
+
+		if ctx.Err() != nil || errors.Is(err, errInputDone) {
+			return nil
+		}
+
+		if restarts >= p.ffmpeg.cfg.MaxRestarts {
+			return fmt.Errorf("ffmpeg exited after %d restarts: %w", restarts, err)
+		}
+
+		log.Printf("transcode: ffmpeg exited (%v), restarting (%d/%d)", err, restarts+1, p.ffmpeg.cfg.MaxRestarts)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(p.ffmpeg.cfg.RestartDelay):
+		}
+	}
+}
+
+// runOnce runs a single ffmpeg process until it exits. It returns
+// errInputDone when the process exited because its input was exhausted.
+func (p *Pipeline) runOnce(ctx context.Context, feed <-chan []byte, out io.Writer) error {
+	cmd := exec.CommandContext(ctx, p.ffmpeg.cfg.Path, p.ffmpeg.args(p.bitrate)...)
+	cmd.Stdout = out
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("stdin pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start ffmpeg: %w", err)
+	}
+
+	exited := make(chan struct{})
+	inputDone := make(chan bool, 1)
+
+	go func() {
+		defer stdin.Close()
+		for {
+			chunk := p.pending
+			if chunk == nil {
+				select {
+				case <-exited:
+					inputDone <- false
+					return
+				case next, ok := <-feed:
+					if !ok {
+						inputDone <- true
+						return
+					}
+					chunk = next
+				}
+			}
+			if n, err := stdin.Write(chunk); err != nil {
+				p.pending = chunk[n:]
+				inputDone <- false
+				return
+			}
+			p.pending = nil
+		}
+	}()
+
+	err = cmd.Wait()
+	close(exited)
+
+	if <-inputDone && err == nil {
+		return errInputDone
+	}
+	if err == nil {
+		err = errors.New("exited before input ended")
+	}
+	return err
+}
+
+// feedChunks copies in to feed until in is exhausted or ctx is done
+func feedChunks(ctx context.Context, in io.Reader, feed chan<- []byte) {
+	defer close(feed)
+
+	buf := make([]byte, 8192)
+	for {
+		n, err := in.Read(buf)
+		if n > 0 {
+			chunk := make([]byte, n)
+			copy(chunk, buf[:n])
+			select {
+			case feed <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// pipelineReader stops the pipeline when the consumer closes it
+type pipelineReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (r *pipelineReader) Close() error {
+	r.cancel()
+	return r.PipeReader.Close()
+}
@@ -0,0 +1,155 @@
+// ABOUTME: Tests for supervised ffmpeg pipeline
+// ABOUTME: Stubs the ffmpeg binary with shell scripts to verify piping and restarts
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeStub writes an executable shell script standing in for ffmpeg
+func writeStub(t *testing.T, script string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatalf("write stub: %v", err)
+	}
+	return path
+}
+
+func TestPipeline_EchoesInput(t *testing.T) {
+	stub := writeStub(t, "exec cat")
+	f := NewFFmpeg(FFmpegConfig{Path: stub})
+
+	input := bytes.Repeat([]byte("mp3 frame data "), 2000)
+
+	out, err := f.Transcode(context.Background(), bytes.NewReader(input), 64)
+	if err != nil {
+		t.Fatalf("Transcode failed: %v", err)
+	}
+	defer out.Close()
+
+	got, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+
+	if !bytes.Equal(got, input) {
+		t.Errorf("expected %d echoed bytes, got %d", len(input), len(got))
+	}
+}
+
+func TestPipeline_RestartsOnCrash(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "starts")
+	// Each run records a start, echoes 4 bytes and exits before input ends
+	stub := writeStub(t, "echo x >> "+counter+"\nhead -c 4")
+
+	f := NewFFmpeg(FFmpegConfig{Path: stub, MaxRestarts: 2, RestartDelay: time.Millisecond})
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			if _, err := pw.Write([]byte("abcd")); err != nil {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	out, err := f.Transcode(context.Background(), pr, 64)
+	if err != nil {
+		t.Fatalf("Transcode failed: %v", err)
+	}
+	defer out.Close()
+
+	_, err = io.ReadAll(out)
+	if err == nil || !strings.Contains(err.Error(), "restarts") {
+		t.Errorf("expected restart limit error, got %v", err)
+	}
+
+	data, _ := os.ReadFile(counter)
+	if starts := strings.Count(string(data), "x"); starts != 3 {
+		t.Errorf("expected 3 ffmpeg starts (1 + 2 restarts), got %d", starts)
+	}
+}
+
+func TestPipeline_StopsOnCancel(t *testing.T) {
+	stub := writeStub(t, "exec cat")
+	f := NewFFmpeg(FFmpegConfig{Path: stub})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	out, err := f.Transcode(ctx, pr, 64)
+	if err != nil {
+		t.Fatalf("Transcode failed: %v", err)
+	}
+	defer out.Close()
+
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		io.ReadAll(out)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("pipeline did not stop after context cancel")
+	}
+}
+
+func TestFFmpeg_CheckMissingBinary(t *testing.T) {
+	f := NewFFmpeg(FFmpegConfig{Path: "/nonexistent/ffmpeg"})
+
+	if err := f.Check(); err == nil {
+		t.Error("expected error for missing ffmpeg binary")
+	}
+}
+
+func TestPipeline_RestartKeepsUnsentInput(t *testing.T) {
+	dir := t.TempDir()
+	started, closed := filepath.Join(dir, "started"), filepath.Join(dir, "closed")
+	// The first run closes its input and crashes; the next one echoes
+	stub := writeStub(t, "if [ -e "+started+" ]; then exec cat; fi\ntouch "+started+"\nexec 0<&-\ntouch "+closed+"\nsleep 0.1\nexit 1")
+
+	f := NewFFmpeg(FFmpegConfig{Path: stub, MaxRestarts: 1, RestartDelay: time.Millisecond})
+
+	pr, pw := io.Pipe()
+	out, err := f.Transcode(context.Background(), pr, 64)
+	if err != nil {
+		t.Fatalf("Transcode failed: %v", err)
+	}
+	defer out.Close()
+
+	go func() {
+		// Only write once the first ffmpeg can no longer take input
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			if _, err := os.Stat(closed); err == nil {
+				break
+			}
+		}
+		pw.Write([]byte("abcd"))
+		pw.Close()
+	}()
+
+	got, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if string(got) != "abcd" {
+		t.Errorf("expected the chunk the crashed ffmpeg refused to reach the next one, got %q", got)
+	}
+}